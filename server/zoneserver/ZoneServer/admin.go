@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// registerAdminEndpoints mounts the operator API. Every request must carry
+// "Authorization: Bearer <token>"; with no token configured the API is off.
+func registerAdminEndpoints(mux *http.ServeMux, token string) {
+	mux.HandleFunc("/admin/scheduler", requireAdminToken(token, adminSchedulerHandler))
+	mux.HandleFunc("/admin/worlds", requireAdminToken(token, adminWorldsHandler))
+}
+
+func requireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeAdminJSON(w, http.StatusNotFound, map[string]interface{}{"error": "admin api disabled"})
+			return
+		}
+		got := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeAdminJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminSchedulerHandler lists pending scheduled events (GET) or cancels one
+// by key (DELETE ?key=...).
+func adminSchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		now := worldScheduler.clock.Now()
+		pending := worldScheduler.Pending()
+		events := make([]map[string]interface{}, 0, len(pending))
+		for _, evt := range pending {
+			events = append(events, map[string]interface{}{
+				"key":       evt.Key,
+				"kind":      evt.Kind,
+				"world_id":  evt.WorldID,
+				"subject":   evt.Subject,
+				"due_at":    evt.DueAt.UTC().Format(time.RFC3339Nano),
+				"due_in_ms": evt.DueAt.Sub(now).Milliseconds(),
+			})
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+			"pending": len(events),
+			"events":  events,
+		})
+	case http.MethodDelete:
+		key := strings.TrimSpace(r.URL.Query().Get("key"))
+		if key == "" {
+			writeAdminJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "key is required"})
+			return
+		}
+		if !worldScheduler.Cancel(key) {
+			writeAdminJSON(w, http.StatusNotFound, map[string]interface{}{"error": "no pending event", "key": key})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"cancelled": key})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeAdminJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "method not allowed"})
+	}
+}
+
+// adminWorldsHandler reports tick metrics for every world simulation.
+func adminWorldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeAdminJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "method not allowed"})
+		return
+	}
+	sims := allWorldSims()
+	metrics := make([]WorldTickMetrics, 0, len(sims))
+	for _, sim := range sims {
+		metrics = append(metrics, sim.tickMetrics())
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"tick_rate_ms": durationMS(simTickRate),
+		"worlds":       metrics,
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
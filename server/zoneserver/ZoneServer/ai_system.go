@@ -1,87 +1,209 @@
 package main
 
 import (
-	"math/rand"
 	"math"
 )
 
-// processServerTick is called periodically by the main server loop.
-// It handles entity AI, such as monster wandering and aggro.
+// Mob AI states, exposed as MobEntity.AIState.
+const (
+	MobStateIdle   = "idle"
+	MobStatePatrol = "patrol"
+	MobStateChase  = "chase"
+	MobStateAttack = "attack"
+	MobStateReturn = "return"
+)
+
+const (
+	mobAggroRange   = 60.0  // distance at which an idle mob notices a player
+	mobAttackRange  = 24.0  // melee reach
+	mobLeashRadius  = 120.0 // how far a mob may be pulled from its spawn anchor
+	mobPatrolRadius = 10.0  // patrol points are picked within this distance of the anchor
+	mobPatrolChance = 0.15  // per-tick chance an idle mob starts a patrol leg
+
+	// Movement speeds in units per second; see worldSim.perTick.
+	mobPatrolSpeed = 5.0
+	mobChaseSpeed  = 15.0
+	mobReturnSpeed = 25.0
+)
+
+// processServerTick advances every world once on the calling goroutine. The
+// server runs each world on its own loop (startWorldSims); this drives them
+// synchronously where those loops are not running, as in tests.
 func processServerTick() {
-	if worldMobs == nil {
+	for _, w := range allWorldSims() {
+		w.tick()
+	}
+}
+
+func mobCanTarget(s *ClientSession, worldID WorldID) bool {
+	return s.Authenticated && s.World != nil && s.World.ID == worldID && s.Character != nil && s.Character.HP > 0 && sessionRegion(s).Kind != RegionSafe
+}
+
+// ensureMobAI anchors mobs that were created without AI state (tests and
+// hand-built rosters) at their current position.
+func ensureMobAI(mob *MobEntity) {
+	if mob.AIState == "" {
+		mob.AIState = MobStateIdle
+		mob.Spawn = mob.Position
+	}
+}
+
+// stepMobAI runs one tick of mob's state machine:
+//
+//	idle/patrol -> chase when a player comes within mobAggroRange
+//	chase <-> attack depending on mobAttackRange
+//	chase/attack -> return when pulled past mobLeashRadius, the target is
+//	                lost, or no terrain path reaches the target
+//	return -> idle once back at the spawn anchor
+//
+// While fighting, the mob attacks the top of its threat table (see threat.go).
+// A returning mob evades all attacks, sheds its status effects and is
+// restored to full HP. Movement
+// follows terrain paths (see pathfinding.go); attacks and abilities run on
+// real-time cooldowns (see mob_abilities.go).
+func stepMobAI(w *worldSim, mob *MobEntity, candidates []*ClientSession, outbox *mobOutbox) {
+	ensureMobAI(mob)
+
+	if mob.AIState == MobStateReturn {
+		speed := w.perTick(mobReturnSpeed)
+		arrived, reachable := moveMobTo(w, mob, mob.Spawn, speed)
+		if !reachable {
+			// No path home: rather than walk through walls, put the mob
+			// back on its anchor.
+			placeMobAtSpawn(mob)
+			arrived = true
+		}
+		if arrived {
+			mob.AIState = MobStateIdle
+			mob.path = nil
+		}
 		return
 	}
 
-	sessions := getSessions()
+	if distance2D(mob.Position, mob.Spawn) > mobLeashRadius {
+		startMobReturn(mob, outbox)
+		return
+	}
 
-	mobMu.Lock()
-	defer mobMu.Unlock()
+	target := mobThreatTarget(mob, candidates)
+	if target == nil {
+		target = nearestInRange(mob, candidates, mobAggroRange)
+		if target != nil {
+			addThreatLocked(mob, target.Character.Name, proximityThreat)
+		}
+	}
+	if target == nil {
+		if mob.AIState == MobStateChase || mob.AIState == MobStateAttack {
+			startMobReturn(mob, outbox)
+			return
+		}
+		stepMobIdle(w, mob)
+		return
+	}
+	mob.targetName = target.Character.Name
 
-	for worldID, worldMap := range worldMobs {
-		worldSessions := make([]*ClientSession, 0)
-		for _, s := range sessions {
-			if s.Authenticated && s.World != nil && s.World.ID == worldID && s.Character != nil && s.Character.HP > 0 {
-				worldSessions = append(worldSessions, s)
-			}
+	if distance2D(mob.Position, target.Position) <= mobAttackRange {
+		mob.AIState = MobStateAttack
+		mobAttackSession(w, mob, target, candidates, outbox)
+		return
+	}
+	mob.AIState = MobStateChase
+	if _, reachable := moveMobTo(w, mob, target.Position, mobSpeed(w, mob, mobChaseSpeed)); !reachable {
+		mob.giveUpName = target.Character.Name
+		mob.giveUpCell = terrainCellOf(mob.WorldID, target.Position)
+		startMobReturn(mob, outbox)
+	}
+}
+
+// nearestInRange picks the closest candidate within radius for a fresh
+// aggro, skipping a player the mob already gave up on while they have not
+// moved to another terrain cell.
+func nearestInRange(mob *MobEntity, candidates []*ClientSession, radius float64) *ClientSession {
+	var best *ClientSession
+	bestDist := radius
+	for _, s := range candidates {
+		if s.Character.Name == mob.giveUpName && terrainCellOf(mob.WorldID, s.Position) == mob.giveUpCell {
+			continue
+		}
+		if d := distance2D(mob.Position, s.Position); d <= bestDist {
+			best, bestDist = s, d
+		}
+	}
+	return best
+}
+
+func terrainCellOf(worldID WorldID, p Position) gridCell {
+	if grid := terrainFor(worldID); grid != nil {
+		c, _ := grid.cellAt(p)
+		return c
+	}
+	return gridCell{}
+}
+
+func startMobReturn(mob *MobEntity, outbox *mobOutbox) {
+	mob.AIState = MobStateReturn
+	mob.path = nil
+	mob.effects = nil
+	resetMobThreatLocked(mob)
+	if mob.boss != nil {
+		resetBossLocked(mob)
+	}
+	mob.HP = mob.MaxHP
+	outbox.event(mob, RespMobEvaded, map[string]interface{}{
+		"mob_id": mob.ID,
+		"hp":     mob.HP,
+		"max_hp": mob.MaxHP,
+	}, nil)
+}
+
+func stepMobIdle(w *worldSim, mob *MobEntity) {
+	if mob.AIState == MobStatePatrol {
+		if arrived, reachable := moveMobTo(w, mob, mob.patrolTo, mobSpeed(w, mob, mobPatrolSpeed)); arrived || !reachable {
+			mob.AIState = MobStateIdle
+			mob.path = nil
+		}
+		return
+	}
+	mob.AIState = MobStateIdle
+	if randFloat64() < mobPatrolChance {
+		angle := randFloat64() * 2 * math.Pi
+		radius := randFloat64() * mobPatrolRadius
+		mob.patrolTo = Position{
+			X: mob.Spawn.X + math.Cos(angle)*radius,
+			Y: mob.Spawn.Y,
+			Z: mob.Spawn.Z + math.Sin(angle)*radius,
 		}
+		mob.AIState = MobStatePatrol
+	}
+}
 
-		for _, mob := range worldMap {
-			if mob.HP <= 0 {
-				continue // Dead mobs don't move
-			}
-
-			var target *ClientSession
-			minDistSq := 40000.0 // Aggro range squared (~200 units)
-			
-			for _, s := range worldSessions {
-				dx := s.Position.X - mob.Position.X
-				dz := s.Position.Z - mob.Position.Z
-				distSq := dx*dx + dz*dz
-				if distSq < minDistSq {
-					minDistSq = distSq
-					target = s
-				}
-			}
-
-			if target != nil {
-				// We have a target, either move towards it or attack
-				if minDistSq <= 600.0 { // Attack range (~24 units)
-					// Simple simulated damage logic based on mob level vs player level
-					damage := mob.Level * 2
-					if damage < 1 { damage = 1 }
-					
-					target.Character.HP -= damage
-					
-					if target.Character.HP <= 0 {
-						applyDeathPenalty(target.Character, target.Position)
-						sendMessage(target.Conn, ServerMessage{
-							Command: RespPlayerDied, 
-							Payload: map[string]interface{}{"target": mob.Name, "xp_debt": target.Character.XPDebt, "corpse": target.Character.Corpse, "recovery": "Use RECOVER_CORPSE"},
-						})
-					} else {
-						sendMessage(target.Conn, ServerMessage{
-							Command: RespPVPHit, 
-							Payload: map[string]interface{}{"from": mob.Name, "damage": damage, "target_hp": target.Character.HP, "target_debt": target.Character.XPDebt},
-						})
-					}
-				} else {
-					// Move towards target
-					dirX := target.Position.X - mob.Position.X
-					dirZ := target.Position.Z - mob.Position.Z
-					dist := math.Sqrt(minDistSq)
-					if dist > 0 {
-						// move speed is ~15 units per tick
-						mob.Position.X += (dirX / dist) * 15.0
-						mob.Position.Z += (dirZ / dist) * 15.0
-					}
-				}
-			} else {
-				// No targets, 15% chance to wander
-				if rand.Float64() < 0.15 {
-					mob.Position.X += float64(rand.Intn(21) - 10) // -10 to +10
-					mob.Position.Z += float64(rand.Intn(21) - 10)
-				}
-			}
+// placeMobAtSpawn moves mob straight onto its spawn anchor, on the ground
+// when the anchor is on the world's terrain.
+func placeMobAtSpawn(mob *MobEntity) {
+	mob.Position = mob.Spawn
+	if grid := terrainFor(mob.WorldID); grid != nil {
+		if _, ok := grid.cellAt(mob.Spawn); ok {
+			mob.Position.Y = grid.groundY(mob.Spawn)
 		}
 	}
 }
+
+// moveMobToward steps mob up to speed units toward dest on the X/Z plane and
+// reports whether it arrived.
+func moveMobToward(mob *MobEntity, dest Position, speed float64) bool {
+	dist := distance2D(mob.Position, dest)
+	if dist <= speed {
+		mob.Position.X = dest.X
+		mob.Position.Z = dest.Z
+		return true
+	}
+	mob.Position.X += (dest.X - mob.Position.X) / dist * speed
+	mob.Position.Z += (dest.Z - mob.Position.Z) / dist * speed
+	return false
+}
+
+func distance2D(a, b Position) float64 {
+	dx := a.X - b.X
+	dz := a.Z - b.Z
+	return math.Sqrt(dx*dx + dz*dz)
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func withFixedRandFloat64(v float64, fn func()) {
+	orig := randFloat64
+	randFloat64 = func() float64 { return v }
+	defer func() { randFloat64 = orig }()
+	fn()
+}
+
+func aiTestMob(pos Position) *MobEntity {
+	return &MobEntity{
+		ID: "mob_wolf_01", Name: "Rift Wolf", WorldID: World1, Level: 1, HP: 100, MaxHP: 100,
+		Position: pos, Spawn: pos, AIState: MobStateIdle, RespawnSec: 8,
+	}
+}
+
+func TestMobChasesAttacksAndLeashesHome(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	home := Position{X: 100, Y: 0, Z: 100}
+	player, playerConn := newVisibilityTestSession("Kiter", worlds[World1], Position{X: 135, Y: 0, Z: 100})
+	defer unregisterSession(player)
+
+	withFixedRandFloat64(0.99, func() {
+		withWorldMob(World1, "mob_wolf_01", aiTestMob(home), func() {
+			mob := worldMobsForTests(World1)["mob_wolf_01"]
+
+			processServerTick()
+			if mob.AIState != MobStateChase || mob.Position.X != home.X+mobChaseSpeed {
+				t.Fatalf("expected chase toward the player, got state=%s pos=%#v", mob.AIState, mob.Position)
+			}
+			processServerTick()
+			if mob.AIState != MobStateAttack {
+				t.Fatalf("expected attack once in reach, got %s", mob.AIState)
+			}
+			if !hasMessage(playerConn.DrainMessages(t), RespMobHit, nil) {
+				t.Fatalf("expected the attacked player to be hit")
+			}
+
+			// Kite the mob past its leash radius.
+			mob.HP = 40
+			player.Position = Position{X: home.X + mobLeashRadius + 20, Y: 0, Z: 100}
+			mob.Position = Position{X: home.X + mobLeashRadius + 5, Y: 0, Z: 100}
+			processServerTick()
+			if mob.AIState != MobStateReturn || mob.HP != mob.MaxHP {
+				t.Fatalf("expected leash to return with full HP, got state=%s hp=%d", mob.AIState, mob.HP)
+			}
+			if !hasMessage(playerConn.DrainMessages(t), RespMobEvaded, nil) {
+				t.Fatalf("expected MOB_EVADED for nearby players")
+			}
+			if _, ok, reason := attackMob(player, "mob_wolf_01", ""); ok || reason != "MOB_EVADING" {
+				t.Fatalf("expected attacks to be evaded while returning, got ok=%v reason=%s", ok, reason)
+			}
+
+			for i := 0; i < 10 && mob.AIState == MobStateReturn; i++ {
+				processServerTick()
+			}
+			if mob.AIState != MobStateIdle || mob.Position != home {
+				t.Fatalf("expected mob idle at its anchor, got state=%s pos=%#v", mob.AIState, mob.Position)
+			}
+		})
+	})
+}
+
+func TestMobReturnsWhenTargetLeavesAndReportsState(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	home := Position{X: 100, Y: 0, Z: 100}
+	player, _ := newVisibilityTestSession("Leaver", worlds[World1], Position{X: 130, Y: 0, Z: 100})
+	observer, _ := newVisibilityTestSession("Observer", worlds[World1], Position{X: 300, Y: 0, Z: 300})
+	defer unregisterSession(observer)
+
+	withFixedRandFloat64(0.99, func() {
+		withWorldMob(World1, "mob_wolf_01", aiTestMob(home), func() {
+			mob := worldMobsForTests(World1)["mob_wolf_01"]
+			processServerTick()
+			if mob.AIState != MobStateChase {
+				t.Fatalf("expected chase, got %s", mob.AIState)
+			}
+
+			unregisterSession(player)
+			processServerTick()
+			if mob.AIState != MobStateReturn {
+				t.Fatalf("expected return after losing the target, got %s", mob.AIState)
+			}
+
+			observer.Position = mob.Position
+			entities := listNearbyEntities(observer)
+			mobs, _ := entities["mobs"].([]MobEntity)
+			if len(mobs) != 1 || mobs[0].AIState != MobStateReturn {
+				t.Fatalf("expected LIST_ENTITIES to expose ai_state, got %#v", mobs)
+			}
+		})
+	})
+}
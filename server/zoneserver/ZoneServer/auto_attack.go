@@ -0,0 +1,203 @@
+package main
+
+import "time"
+
+// maxAutoSwingsPerTick bounds catch-up swings when the tick rate is slower
+// than the swing interval, as maxMobSwingsPerTick does for mobs.
+const maxAutoSwingsPerTick = 4
+
+// autoAttack is a running auto-attack loop: plain swings at mobID, one per
+// weapon attack interval, resolved on the world tick.
+type autoAttack struct {
+	mobID       string
+	nextSwingAt time.Time
+	// corpse is the character's corpse when the loop started, so a newer one
+	// means they died since.
+	corpse *Position
+}
+
+// autoAttackInterval is how often c swings; see deriveStats.
+func autoAttackInterval(c *Character) time.Duration {
+	return time.Duration(deriveStats(c).AttackSpeedMS) * time.Millisecond
+}
+
+// selectTarget makes mobID the session's target, or clears the target (and
+// any auto-attack) when mobID is empty. Selecting a new mob while
+// auto-attacking switches the loop to it.
+func selectTarget(s *ClientSession, mobID string) (map[string]interface{}, bool, string) {
+	initWorldEntities()
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return nil, false, "MOB_NOT_FOUND"
+	}
+	var result map[string]interface{}
+	reason := "OK"
+	w.doFor(s, func(w *worldSim, outbox *mobOutbox) {
+		if mobID == "" {
+			delete(w.targets, s)
+			if aa, ok := w.autoAttacks[s]; ok {
+				stopAutoAttackLocked(w, outbox, s, aa, "TARGET_CLEARED")
+			}
+			result = map[string]interface{}{"mob_id": ""}
+			return
+		}
+		var mob *MobEntity
+		if mob, reason = attackableMobLocked(w, s, mobID); mob == nil {
+			return
+		}
+		if w.targets == nil {
+			w.targets = map[*ClientSession]string{}
+		}
+		w.targets[s] = mobID
+		if aa, ok := w.autoAttacks[s]; ok {
+			aa.mobID = mobID
+		}
+		result = targetPayload(mob)
+		result["auto_attack"] = w.autoAttacks[s] != nil
+	})
+	return result, result != nil, reason
+}
+
+func targetPayload(mob *MobEntity) map[string]interface{} {
+	payload := map[string]interface{}{
+		"mob_id": mob.ID,
+		"mob":    mob.Name,
+		"level":  mob.Level,
+		"hp":     mob.HP,
+		"max_hp": mob.MaxHP,
+	}
+	if mob.Training {
+		payload["training"] = true
+	}
+	return payload
+}
+
+// startAutoAttack starts swinging at the selected target, selecting mobID
+// first when given. The first swing lands on the next world tick.
+func startAutoAttack(s *ClientSession, mobID string) (map[string]interface{}, bool, string) {
+	if sessionStunned(s) {
+		return nil, false, "STUNNED"
+	}
+	if mobID != "" {
+		if _, ok, reason := selectTarget(s, mobID); !ok {
+			return nil, false, reason
+		}
+	}
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return nil, false, "NO_TARGET"
+	}
+	var result map[string]interface{}
+	reason := "OK"
+	w.do(func(w *worldSim, _ *mobOutbox) {
+		target, selected := w.targets[s]
+		if !selected {
+			reason = "NO_TARGET"
+			return
+		}
+		var mob *MobEntity
+		if mob, reason = attackableMobLocked(w, s, target); mob == nil {
+			delete(w.targets, s)
+			return
+		}
+		if _, running := w.autoAttacks[s]; running {
+			reason = "ALREADY_AUTO_ATTACKING"
+			return
+		}
+		if w.autoAttacks == nil {
+			w.autoAttacks = map[*ClientSession]*autoAttack{}
+		}
+		w.autoAttacks[s] = &autoAttack{mobID: target, nextSwingAt: simTickClock(), corpse: s.Character.Corpse}
+		result = targetPayload(mob)
+		result["interval_ms"] = autoAttackInterval(s.Character).Milliseconds()
+	})
+	return result, result != nil, reason
+}
+
+// stopAutoAttack ends the session's auto-attack at the client's request.
+// The target stays selected.
+func stopAutoAttack(s *ClientSession) (bool, string) {
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return false, "NOT_AUTO_ATTACKING"
+	}
+	ok := false
+	w.doFor(s, func(w *worldSim, outbox *mobOutbox) {
+		if aa, running := w.autoAttacks[s]; running {
+			stopAutoAttackLocked(w, outbox, s, aa, "STOPPED")
+			ok = true
+		}
+	})
+	if !ok {
+		return false, "NOT_AUTO_ATTACKING"
+	}
+	return true, "OK"
+}
+
+// stopAutoAttackLocked ends s's loop and tells them why with
+// AUTO_ATTACK_STOPPED, from their action path so it follows the result of
+// the swing that ended it. The caller holds the world lock.
+func stopAutoAttackLocked(w *worldSim, outbox *mobOutbox, s *ClientSession, aa *autoAttack, reason string) {
+	delete(w.autoAttacks, s)
+	stopped := ServerMessage{Command: RespAutoAttackStopped, Payload: map[string]interface{}{
+		"mob_id": aa.mobID,
+		"reason": reason,
+	}}
+	outbox.post(s, func() { sendMessage(s.Conn, stopped) })
+}
+
+// resolveAutoAttacksLocked swings for every running auto-attack whose
+// interval has elapsed on the sim clock, streaming AUTO_ATTACK_RESULT. A loop
+// stops when its target can no longer be attacked (gone, out of range,
+// evading or defeated) or the attacker dies; it pauses while they are
+// stunned or casting. Sessions that left the world are dropped silently.
+func resolveAutoAttacksLocked(w *worldSim, outbox *mobOutbox) {
+	for s := range w.targets {
+		if !s.Active || s.World == nil || s.World.ID != w.ID {
+			delete(w.targets, s)
+		}
+	}
+	for s, aa := range w.autoAttacks {
+		if !s.Active || s.World == nil || s.World.ID != w.ID || s.Character == nil {
+			delete(w.autoAttacks, s)
+			continue
+		}
+		if c := s.Character.Corpse; c != nil && c != aa.corpse {
+			stopAutoAttackLocked(w, outbox, s, aa, "PLAYER_DIED")
+			continue
+		}
+		if sessionStunned(s) || sessionCasting(s) {
+			continue
+		}
+		if aa.nextSwingAt.Before(w.now.Add(-w.tickRate)) {
+			aa.nextSwingAt = w.now // resume without bursting through the pause
+		}
+		interval := autoAttackInterval(s.Character)
+		for swings := 0; swings < maxAutoSwingsPerTick && !w.now.Before(aa.nextSwingAt); swings++ {
+			aa.nextSwingAt = aa.nextSwingAt.Add(interval)
+			result, ok, reason := attackMobInWorld(w, outbox, s, aa.mobID, "")
+			if !ok {
+				delete(w.targets, s)
+				stopAutoAttackLocked(w, outbox, s, aa, reason)
+				break
+			}
+			// Sent from s's action path once the swing's XP and loot are theirs.
+			outbox.post(s, func() {
+				sendMessage(s.Conn, ServerMessage{Command: RespAutoAttackResult, Payload: result})
+			})
+			stop := ""
+			if result["status"] == "PLAYER_DIED" {
+				stop = "PLAYER_DIED"
+			} else if result["defeated"] == true {
+				stop = "MOB_DEFEATED"
+				delete(w.targets, s)
+			}
+			if stop == "" {
+				continue
+			}
+			stopAutoAttackLocked(w, outbox, s, aa, stop)
+			outbox.post(s, func() { queuePersistCharacter(s.Character, "an auto-attack") })
+			break
+		}
+	}
+}
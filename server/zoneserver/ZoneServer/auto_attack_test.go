@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAutoAttackSwingsAtWeaponSpeedUntilRangeLoss(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	archer, conn := newVisibilityTestSession("Plinker", worlds[World1], Position{X: 15, Y: 0, Z: 15})
+	defer unregisterSession(archer)
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			dummy := trainingTestDummy(Position{X: 18, Y: 0, Z: 15})
+			withWorldMob(World1, dummy.ID, dummy, func() {
+				w := worldSimFor(World1)
+				w.tickRate = 100 * time.Millisecond
+
+				if _, ok, reason := startAutoAttack(archer, ""); ok || reason != "NO_TARGET" {
+					t.Fatalf("expected NO_TARGET before selecting, got ok=%v reason=%s", ok, reason)
+				}
+				if _, ok, reason := selectTarget(archer, "mob_nobody"); ok || reason != "MOB_NOT_FOUND" {
+					t.Fatalf("expected MOB_NOT_FOUND, got ok=%v reason=%s", ok, reason)
+				}
+				if _, ok, reason := selectTarget(archer, dummy.ID); !ok {
+					t.Fatalf("select failed: %s", reason)
+				}
+				started, ok, reason := startAutoAttack(archer, "")
+				if !ok || started["interval_ms"] != int64(1300) {
+					t.Fatalf("expected the Scout Bow to swing every 1300ms over the archer's 1400ms, got ok=%v reason=%s %#v", ok, reason, started)
+				}
+				if _, ok, reason := startAutoAttack(archer, ""); ok || reason != "ALREADY_AUTO_ATTACKING" {
+					t.Fatalf("expected ALREADY_AUTO_ATTACKING, got ok=%v reason=%s", ok, reason)
+				}
+
+				swings := func(advance time.Duration) int {
+					clock.Advance(advance)
+					processServerTick()
+					return countMessages(conn.DrainMessages(t), RespAutoAttackResult)
+				}
+				if n := swings(0); n != 1 {
+					t.Fatalf("expected the first swing on the next tick, got %d", n)
+				}
+				if n := swings(time.Second); n != 0 {
+					t.Fatalf("expected no swing before the interval, got %d", n)
+				}
+				if n := swings(300 * time.Millisecond); n != 1 {
+					t.Fatalf("expected a swing once 1300ms passed, got %d", n)
+				}
+				if dummy.HP >= dummy.MaxHP {
+					t.Fatalf("expected the swings to land")
+				}
+
+				// Without a weapon speed the class interval applies.
+				archer.Character.Inventory[0].AttackSpeedMS = 0
+				if got := autoAttackInterval(archer.Character); got != 1400*time.Millisecond {
+					t.Fatalf("expected the archer's 1400ms interval, got %v", got)
+				}
+
+				archer.Position = Position{X: 200, Y: 0, Z: 200}
+				clock.Advance(1400 * time.Millisecond)
+				processServerTick()
+				stopped := lastMessage(conn.DrainMessages(t), RespAutoAttackStopped)
+				if toString(toMap(stopped.Payload), "reason") != "MOB_OUT_OF_RANGE" {
+					t.Fatalf("expected range loss to stop the loop, got %#v", stopped)
+				}
+				if ok, reason := stopAutoAttack(archer); ok || reason != "NOT_AUTO_ATTACKING" {
+					t.Fatalf("expected NOT_AUTO_ATTACKING, got ok=%v reason=%s", ok, reason)
+				}
+			})
+		})
+	})
+}
+
+func TestAutoAttackStopsOnKillDeathAndRequest(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	fighter, conn := newVisibilityTestSession("Swinger", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(fighter)
+	fighter.Character.HP, fighter.Character.MaxHP = 100000, 100000
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 103, Y: 0, Z: 100})
+			withWorldMob(World1, mob.ID, mob, func() {
+				w := worldSimFor(World1)
+				w.tickRate = 100 * time.Millisecond
+				stopReason := func() string {
+					msg := lastMessage(conn.DrainMessages(t), RespAutoAttackStopped)
+					return toString(toMap(msg.Payload), "reason")
+				}
+
+				// The client can stop the loop; the target stays selected.
+				if _, ok, reason := startAutoAttack(fighter, mob.ID); !ok {
+					t.Fatalf("start failed: %s", reason)
+				}
+				if ok, reason := stopAutoAttack(fighter); !ok || stopReason() != "STOPPED" {
+					t.Fatalf("expected STOPPED, got ok=%v reason=%s", ok, reason)
+				}
+
+				// Dying ends the loop.
+				if _, ok, reason := startAutoAttack(fighter, ""); !ok {
+					t.Fatalf("restart failed: %s", reason)
+				}
+				applyDeathPenalty(fighter.Character, fighter.Position)
+				processServerTick()
+				if reason := stopReason(); reason != "PLAYER_DIED" {
+					t.Fatalf("expected PLAYER_DIED, got %s", reason)
+				}
+
+				// So does killing the target, which also clears it.
+				mob.HP = 1
+				if _, ok, reason := startAutoAttack(fighter, ""); !ok {
+					t.Fatalf("restart failed: %s", reason)
+				}
+				clock.Advance(100 * time.Millisecond)
+				processServerTick()
+				msgs := conn.DrainMessages(t)
+				result := toMap(lastMessage(msgs, RespAutoAttackResult).Payload)
+				if result["defeated"] != true || toInt(result, "xp_gain") <= 0 {
+					t.Fatalf("expected the swing to kill the mob, got %#v", result)
+				}
+				if toString(toMap(lastMessage(msgs, RespAutoAttackStopped).Payload), "reason") != "MOB_DEFEATED" {
+					t.Fatalf("expected MOB_DEFEATED, got %#v", msgs)
+				}
+				if _, ok, reason := startAutoAttack(fighter, ""); ok || reason != "NO_TARGET" {
+					t.Fatalf("expected the dead target to be cleared, got ok=%v reason=%s", ok, reason)
+				}
+			})
+		})
+	})
+}
+
+func TestAutoAttackKillIsRewardedOnTheAttackersActionPath(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	fighter, conn := newVisibilityTestSession("Reaper", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(fighter)
+	fighter.Character.HP, fighter.Character.MaxHP = 100000, 100000
+	fighter.serving.Store(true) // as if its read loop were running
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 103, Y: 0, Z: 100})
+			mob.HP = 1
+			withWorldMob(World1, mob.ID, mob, func() {
+				if _, ok, reason := startAutoAttack(fighter, mob.ID); !ok {
+					t.Fatalf("start failed: %s", reason)
+				}
+				materials := len(fighter.Character.Materials)
+				xp := fighter.Character.XP
+
+				fighter.actionMu.Lock()
+				processServerTick()
+				msgs := conn.DrainMessages(t)
+				if mob.HP > 0 || fighter.Character.XP != xp || len(fighter.Character.Materials) != materials || countMessages(msgs, RespAutoAttackResult) != 0 {
+					fighter.actionMu.Unlock()
+					t.Fatalf("expected the kill to wait for the action path, got HP=%d %#v", mob.HP, msgs)
+				}
+				fighter.actionMu.Unlock()
+
+				waitForPosted(t, fighter)
+				got := conn.DrainMessages(t)
+				n := len(got)
+				if n < 2 || got[n-2].Command != RespAutoAttackResult || got[n-1].Command != RespAutoAttackStopped {
+					t.Fatalf("expected the swing's result and then AUTO_ATTACK_STOPPED, got %#v", got)
+				}
+				if result := toMap(got[n-2].Payload); result["defeated"] != true {
+					t.Fatalf("expected the kill in the result, got %#v", result)
+				}
+			})
+		})
+	})
+}
@@ -15,6 +15,7 @@ type Character struct {
 	MaxHP          int
 	UnlockedWorlds map[WorldID]bool
 	Trust          map[string]int
+	DialogueFlags  map[string]bool
 	Quests         map[string]*QuestProgress
 	Inventory      []Item
 	Equipped       map[string]string
@@ -35,6 +36,7 @@ type Character struct {
 	PKScore        int
 	Honor          int
 	Corpse         *Position
+	Effects        []ActiveEffect
 }
 
 // Temporary mock character for Epic 2
@@ -100,15 +102,16 @@ func MockCharacter() *Character {
 		Honor:   0,
 		Inventory: []Item{
 			{
-				ID:        "starter_bow",
-				Name:      "Scout Bow",
-				Grade:     2,
-				Rarity:    RarityCommon,
-				Slot:      SlotWeapon,
-				Element:   ElementNone,
-				GearLevel: 1,
-				MinSTR:    8,
-				MinDEX:    12,
+				ID:            "starter_bow",
+				Name:          "Scout Bow",
+				Grade:         2,
+				Rarity:        RarityCommon,
+				Slot:          SlotWeapon,
+				Element:       ElementNone,
+				GearLevel:     1,
+				MinSTR:        8,
+				MinDEX:        12,
+				AttackSpeedMS: 1300,
 			},
 			{
 				ID:        "starter_mail",
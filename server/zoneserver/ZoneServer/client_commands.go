@@ -16,12 +16,22 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 			return true, false
 		}
 		newPos := Position{X: move.X, Y: move.Y, Z: move.Z}
+		if sessionStunned(session) {
+			sendMessage(conn, ServerMessage{Command: RespMoveRejected, Payload: "STUNNED"})
+			return true, false
+		}
 		if !isMoveValid(session.Position, newPos) {
 			sendMessage(conn, ServerMessage{Command: RespMoveRejected, Payload: "INVALID_MOVE"})
 			return true, false
 		}
+		if !slowedMoveValid(session.Character, session.Position, newPos) {
+			sendMessage(conn, ServerMessage{Command: RespMoveRejected, Payload: "SLOWED"})
+			return true, false
+		}
 		session.Position = newPos
 		sendMessage(conn, ServerMessage{Command: RespMoveOK, Payload: session.Position})
+		interruptSkillCast(session)
+		syncSessionRegion(session)
 		updateVisibilityForMove(session, visible)
 		return true, true
 
@@ -33,16 +43,39 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 			sendMessage(conn, ServerMessage{Command: RespError, Payload: "INVALID_WORLD"})
 			return true, false
 		}
-		// In a real game, check if the player has permission or is near a teleporter NPC
-		session.Character.WorldID = worldID
-		session.World = target
-		session.Position = DefaultSpawnPosition(worldID)
-		sendMessage(conn, ServerMessage{Command: RespTeleportOK, Payload: map[string]interface{}{
-			"world": target.Name,
-			"spawn": session.Position,
-		}})
-		// Notify visibility system of a major warp
-		updateVisibilityForMove(session, visible)
+		if !requireNPCService(conn, session, cmd, RespError) {
+			return true, false
+		}
+		if !ownsWorld(worldID) {
+			if ok, reason := handOffToWorldOwner(session, visible, target); !ok {
+				sendMessage(conn, ServerMessage{Command: RespError, Payload: reason})
+			}
+			return true, false
+		}
+		changeSessionWorld(session, visible, target, DefaultSpawnPosition(worldID), RespTeleportOK, nil)
+		return true, true
+
+	case ReqEnterDungeon:
+		payload := toMap(rawPayload)
+		inst, ok, reason := admitToDungeon(session, toString(payload, "dungeon_id"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespDungeonRejected, Payload: reason})
+			return true, false
+		}
+		changeSessionWorld(session, visible, inst.World, inst.Dungeon.Spawn, RespDungeonEntered, dungeonPayload(inst))
+		finishDungeonArrival(inst)
+		return true, true
+
+	case ReqLeaveDungeon:
+		inst := dungeonInstanceFor(session.World.ID)
+		if inst == nil {
+			sendMessage(conn, ServerMessage{Command: RespDungeonRejected, Payload: "NOT_IN_DUNGEON"})
+			return true, false
+		}
+		changeSessionWorld(session, visible, worlds[inst.Dungeon.HostWorld], inst.Dungeon.Entrance, RespDungeonLeft, map[string]interface{}{
+			"dungeon_id": inst.Dungeon.ID,
+			"reason":     "LEFT",
+		})
 		return true, true
 
 	case ReqAuthToken:
@@ -51,6 +84,9 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 	case ReqGetState:
 		sendMessage(conn, ServerMessage{Command: RespState, Payload: statePayload(session)})
 		return true, false
+	case ReqGetStats:
+		sendMessage(conn, ServerMessage{Command: RespStats, Payload: deriveStats(session.Character)})
+		return true, false
 	case ReqGetHistory:
 		sendMessage(conn, ServerMessage{Command: RespHistory, Payload: getUnlockHistoryPayload()})
 		return true, false
@@ -66,6 +102,9 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		}})
 		return true, false
 	case ReqLearnSkill:
+		if !requireNPCService(conn, session, cmd, RespSkillRejected) {
+			return true, false
+		}
 		payload := toMap(rawPayload)
 		result, ok, reason := learnSkill(session.Character, toString(payload, "skill_id"))
 		if !ok {
@@ -83,47 +122,34 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 			sendMessage(conn, ServerMessage{Command: RespEnterDenied, Payload: reason})
 			return true, false
 		}
-		session.Character.WorldID = worldID
-		session.World = target
-		session.Position = DefaultSpawnPosition(worldID)
-		sendMessage(conn, ServerMessage{Command: RespEnterOK, Payload: map[string]interface{}{"world": target.Name, "spawn": session.Position}})
+		if !ownsWorld(worldID) {
+			if ok, reason := handOffToWorldOwner(session, visible, target); !ok {
+				sendMessage(conn, ServerMessage{Command: RespEnterDenied, Payload: reason})
+			}
+			return true, false
+		}
+		changeSessionWorld(session, visible, target, DefaultSpawnPosition(worldID), RespEnterOK, nil)
 		return true, true
 	case ReqTalkNPC:
 		payload := toMap(rawPayload)
-		npc := toString(payload, "npc")
-		if npc == "" {
-			npc = "Elder Rowan"
-		}
-		session.Character.Trust[npc] += trustDelta(toString(payload, "choice"))
-		sendMessage(conn, ServerMessage{Command: RespNPCState, Payload: map[string]interface{}{
-			"npc":                   npc,
-			"trust":                 session.Character.Trust[npc],
-			"hidden_quest_unlocked": npc == quests["npc_oath_hidden"].RequiredNPC && session.Character.Trust[npc] >= quests["npc_oath_hidden"].MinTrust,
-		}})
-		return true, true
-	case ReqAcceptQuest:
-		payload := toMap(rawPayload)
-		questID := toString(payload, "quest_id")
-		q, exists := quests[questID]
-		if !exists {
-			sendMessage(conn, ServerMessage{Command: RespQuestRejected, Payload: "QUEST_NOT_FOUND"})
+		state, ok, reason := talkToNPC(session, toString(payload, "npc"), toString(payload, "choice"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespDialogueRejected, Payload: reason})
 			return true, false
 		}
-		if q.Hidden && session.Character.Trust[q.RequiredNPC] < q.MinTrust {
-			sendMessage(conn, ServerMessage{Command: RespQuestRejected, Payload: "QUEST_HIDDEN"})
-			return true, false
+		sendMessage(conn, ServerMessage{Command: RespDialogueState, Payload: state})
+		if accepted, ok := state["quest_accepted"].(map[string]interface{}); ok {
+			sendMessage(conn, ServerMessage{Command: RespQuestAccepted, Payload: accepted})
 		}
-		if q.MinLevel > session.Character.Level {
-			sendMessage(conn, ServerMessage{Command: RespQuestRejected, Payload: "LEVEL_TOO_LOW"})
+		return true, true
+	case ReqAcceptQuest:
+		payload := toMap(rawPayload)
+		q, ok, reason := acceptQuest(session.Character, toString(payload, "quest_id"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespQuestRejected, Payload: reason})
 			return true, false
 		}
-		cur := session.Character.Quests[questID]
-		if cur == nil {
-			cur = &QuestProgress{}
-		}
-		cur.Accepted = true
-		session.Character.Quests[questID] = cur
-		sendMessage(conn, ServerMessage{Command: RespQuestAccepted, Payload: map[string]interface{}{"quest_id": questID, "quest_name": q.Name}})
+		sendMessage(conn, ServerMessage{Command: RespQuestAccepted, Payload: map[string]interface{}{"quest_id": q.ID, "quest_name": q.Name}})
 		return true, true
 	case ReqCompleteQuest:
 		payload := toMap(rawPayload)
@@ -137,30 +163,67 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 	case ReqAttack:
 		payload := toMap(rawPayload)
 		target := toString(payload, "target")
-		targetLevel := toInt(payload, "target_level")
-		if targetLevel == 0 {
-			targetLevel = session.Character.Level
-		}
-		damage, died := calculateAttack(session.Character, targetLevel)
-		if died {
-			applyDeathPenalty(session.Character, session.Position)
-			sendMessage(conn, ServerMessage{Command: RespPlayerDied, Payload: map[string]interface{}{"target": target, "xp_debt": session.Character.XPDebt, "corpse": session.Character.Corpse, "recovery": "Use RECOVER_CORPSE"}})
-			return true, true
-		}
-		xpGain := 25 + targetLevel*3
-		leveled := gainXP(session.Character, xpGain)
-		drop := maybeLegendaryDrop(session.Character)
-		sendMessage(conn, ServerMessage{Command: RespCombatResult, Payload: map[string]interface{}{"target": target, "damage": damage, "xp_gain": xpGain, "leveled_up": leveled, "legendary": drop}})
+		if _, legacy := payload["target_level"]; legacy {
+			log.Printf("Rejected legacy ATTACK from %s: target=%q target_level=%v", session.Character.Name, target, payload["target_level"])
+			sendMessage(conn, ServerMessage{Command: RespCombatRejected, Payload: "LEGACY_ATTACK_RETIRED"})
+			return true, false
+		}
+		result, ok, reason := attackTrainingDummy(session, target, toString(payload, "skill_id"))
+		if !ok {
+			if reason == "NOT_A_TRAINING_TARGET" {
+				log.Printf("Rejected legacy ATTACK from %s: %q is not a training dummy", session.Character.Name, target)
+			}
+			sendMessage(conn, ServerMessage{Command: RespCombatRejected, Payload: reason})
+			return true, false
+		}
+		sendMessage(conn, ServerMessage{Command: RespCombatResult, Payload: result})
 		return true, true
 	case ReqAttackMob:
-		payload := toMap(rawPayload)
-		result, ok, reason := attackMob(session, toString(payload, "mob_id"), toString(payload, "skill_id"))
+		data, _ := json.Marshal(rawPayload)
+		var attack AttackMobRequest
+		if err := json.Unmarshal(data, &attack); err != nil {
+			sendMessage(conn, ServerMessage{Command: RespMobAttackRejected, Payload: "INVALID_PAYLOAD"})
+			return true, false
+		}
+		result, ok, reason := attackMobAt(session, attack.MobID, attack.SkillID, attack.Position)
 		if !ok {
 			sendMessage(conn, ServerMessage{Command: RespMobAttackRejected, Payload: reason})
 			return true, false
 		}
 		sendMessage(conn, ServerMessage{Command: RespMobAttackResult, Payload: result})
 		return true, true
+	case ReqSelectTarget:
+		payload := toMap(rawPayload)
+		result, ok, reason := selectTarget(session, toString(payload, "mob_id"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespTargetRejected, Payload: reason})
+			return true, false
+		}
+		sendMessage(conn, ServerMessage{Command: RespTargetSelected, Payload: result})
+		return true, false
+	case ReqStartAutoAttack:
+		payload := toMap(rawPayload)
+		result, ok, reason := startAutoAttack(session, toString(payload, "mob_id"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespAutoAttackRejected, Payload: reason})
+			return true, false
+		}
+		sendMessage(conn, ServerMessage{Command: RespAutoAttackStarted, Payload: result})
+		return true, false
+	case ReqStopAutoAttack:
+		if ok, reason := stopAutoAttack(session); !ok {
+			sendMessage(conn, ServerMessage{Command: RespAutoAttackRejected, Payload: reason})
+		}
+		return true, false
+	case ReqCastSupport:
+		payload := toMap(rawPayload)
+		result, ok, reason := castSupport(session, toString(payload, "skill_id"), toString(payload, "target"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespSupportRejected, Payload: reason})
+			return true, false
+		}
+		sendMessage(conn, ServerMessage{Command: RespSupportResult, Payload: result})
+		return true, true
 	case ReqAttackPVP:
 		payload := toMap(rawPayload)
 		target := toString(payload, "target")
@@ -189,7 +252,11 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 			sendMessage(conn, ServerMessage{Command: RespPVPRejected, Payload: "FRIENDLY_FIRE_BLOCKED"})
 			return true, false
 		}
-		result := attackPlayer(session, victimSession, toString(payload, "skill_id"))
+		result, ok, reason := attackPlayer(session, victimSession, toString(payload, "skill_id"))
+		if !ok {
+			sendMessage(conn, ServerMessage{Command: RespPVPRejected, Payload: reason})
+			return true, false
+		}
 		sendMessage(conn, ServerMessage{Command: RespPVPResult, Payload: result})
 		sendMessage(victimSession.Conn, ServerMessage{Command: RespPVPHit, Payload: map[string]interface{}{"from": session.Character.Name, "damage": result["damage"], "target_hp": victimSession.Character.HP, "target_debt": victimSession.Character.XPDebt}})
 		if err := persistCharacter(victimSession.Character); err != nil {
@@ -270,6 +337,9 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespEquipOK, Payload: result})
 		return true, true
 	case ReqUpgradeGear:
+		if !requireNPCService(conn, session, cmd, RespGearUpgradeReject) {
+			return true, false
+		}
 		payload := toMap(rawPayload)
 		result, ok, reason := upgradeGear(session.Character, toString(payload, "item_id"))
 		if !ok {
@@ -282,6 +352,9 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespRecipes, Payload: recipesPayload()})
 		return true, false
 	case ReqCraftItem:
+		if !requireNPCService(conn, session, cmd, RespCraftRejected) {
+			return true, false
+		}
 		payload := toMap(rawPayload)
 		qty := toInt(payload, "qty")
 		if qty == 0 {
@@ -308,15 +381,13 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespPetUpdate, Payload: result})
 		return true, true
 	case ReqStorageView:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: storageViewPayload(session.Character)})
 		return true, false
 	case ReqStorageDepMat:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -328,8 +399,7 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: result})
 		return true, true
 	case ReqStorageWdrMat:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -341,8 +411,7 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: result})
 		return true, true
 	case ReqStorageDepItm:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -354,8 +423,7 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: result})
 		return true, true
 	case ReqStorageWdrItm:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -367,8 +435,7 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: result})
 		return true, true
 	case ReqStorageDepGold:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -380,8 +447,7 @@ func handleClientCommand(conn WSConn, session *ClientSession, visible map[*Clien
 		sendMessage(conn, ServerMessage{Command: RespStorageState, Payload: result})
 		return true, true
 	case ReqStorageWdrGold:
-		if !hasNearbyStorageNPC(session) {
-			sendMessage(conn, ServerMessage{Command: RespStorageRejected, Payload: "STORAGE_NPC_REQUIRED"})
+		if !requireNPCService(conn, session, cmd, RespStorageRejected) {
 			return true, false
 		}
 		payload := toMap(rawPayload)
@@ -960,25 +1026,39 @@ func handleAuthToken(conn WSConn, session *ClientSession, visible map[*ClientSes
 
 	payload := toMap(rawPayload)
 	token := toString(payload, "token")
+	ticket := toString(payload, "ticket")
 	class := toString(payload, "class")
-	if token == "" {
+	if token == "" && ticket == "" {
 		rejectAuthToken(conn, session, "TOKEN_REQUIRED")
 		return true, false
 	}
 
-	claims, err := validateAuthToken(token)
-	if err != nil {
-		rejectAuthToken(conn, session, "TOKEN_INVALID")
-		return true, false
+	var username string
+	var transfer *transferClaims
+	if ticket != "" {
+		claims, err := validateTransferTicket(ticket)
+		if err != nil {
+			rejectAuthToken(conn, session, "TICKET_INVALID")
+			return true, false
+		}
+		username = claims.Username
+		transfer = &claims
+	} else {
+		claims, err := validateAuthToken(token)
+		if err != nil {
+			rejectAuthToken(conn, session, "TOKEN_INVALID")
+			return true, false
+		}
+		username = claims.Username
 	}
 
 	oldName := session.Character.Name
-	loaded, err := loadCharacter(claims.Username, class)
+	loaded, err := loadCharacter(username, class)
 	if err != nil {
 		sendMessage(conn, ServerMessage{Command: RespAuthRejected, Payload: "LOAD_FAILED"})
 		return true, false
 	}
-	account, err := loadAccount(claims.Username)
+	account, err := loadAccount(username)
 	if err != nil {
 		sendMessage(conn, ServerMessage{Command: RespAuthRejected, Payload: "LOAD_FAILED"})
 		return true, false
@@ -992,16 +1072,34 @@ func handleAuthToken(conn WSConn, session *ClientSession, visible map[*ClientSes
 		}
 	}
 	syncCharacterFromAccount(loaded, account)
+	if transfer != nil {
+		loaded.WorldID = transfer.WorldID
+	} else {
+		// A fresh login keeps only long buffs; a handoff carries everything.
+		keepPersistentEffects(loaded, simTickClock())
+	}
 	targetWorld := worlds[loaded.WorldID]
 	if ok, _ := canEnterWorld(loaded, targetWorld); !ok {
 		loaded.WorldID = World1
 		targetWorld = worlds[World1]
 	}
+	if !ownsWorld(targetWorld.ID) {
+		// The character was saved in a world another node runs; send the
+		// client there instead of admitting it here.
+		guest, guestAccount := session.Character, session.Account
+		session.Character, session.Account = loaded, account
+		if ok, reason := handOffToWorldOwner(session, visible, targetWorld); !ok {
+			session.Character, session.Account = guest, guestAccount
+			sendMessage(conn, ServerMessage{Command: RespAuthRejected, Payload: reason})
+		}
+		return true, false
+	}
 
 	session.Character = loaded
 	session.Account = account
-	session.World = targetWorld
+	moveSessionToWorld(session, targetWorld)
 	session.Position = DefaultSpawnPosition(targetWorld.ID)
+	session.RegionID = sessionRegion(session).ID
 	session.Authenticated = true
 	session.AuthFailures = 0
 	resetZoneAuthAttempts(peerKey)
@@ -1017,6 +1115,7 @@ func handleAuthToken(conn WSConn, session *ClientSession, visible map[*ClientSes
 	registerGuildMember(loaded.Guild, loaded.Name, loaded.GuildRole)
 	loaded.GuildRole = guildRoleOfMember(loaded.Name, loaded.Guild)
 	markCharacterOnline(loaded.Name)
+	scheduleCharacterEffectExpiries(loaded, simTickClock())
 
 	sendMessage(conn, ServerMessage{Command: RespAuthOK, Payload: map[string]interface{}{"name": loaded.Name, "class": loaded.Class, "world": targetWorld.Name}})
 	sendMessage(conn, ServerMessage{Command: RespEnterOK, Payload: map[string]interface{}{"character": loaded.Name, "world": targetWorld.Name, "spawn": session.Position}})
@@ -1037,11 +1136,19 @@ func rejectAuthToken(conn WSConn, session *ClientSession, reason string) {
 
 func updateVisibilityForMove(session *ClientSession, visible map[*ClientSession]bool) {
 	forEachSession(func(other *ClientSession) {
-		if other == session || other.World.ID != session.World.ID {
+		if other == session {
 			return
 		}
-		nowVisible := isVisible(other.Position, session.Position)
 		wasVisible := visible[other]
+		if other.World == nil || other.World.ID != session.World.ID {
+			// World transitions already announced the departure; only the
+			// stale entry from before the other session left is dropped here.
+			if wasVisible {
+				delete(visible, other)
+			}
+			return
+		}
+		nowVisible := isVisible(other.Position, session.Position)
 		switch {
 		case nowVisible && !wasVisible:
 			sendMessage(other.Conn, ServerMessage{
@@ -0,0 +1,98 @@
+package main
+
+// Attack outcomes.
+const (
+	AttackHit  = "hit"
+	AttackMiss = "miss"
+	AttackCrit = "crit"
+)
+
+const (
+	baseHitChancePct = 90  // hit chance when accuracy matches evasion
+	minHitChancePct  = 50  // the floor however evasive the target
+	maxCritChancePct = 50  // the ceiling however dexterous the attacker
+	evasionStepScale = 3   // evasion per point of evasion_step bonus
+	deathEvasionBase = 100 // evasion of deathEvasionBase halves death chance
+)
+
+// combatScaling sets how a class turns STR and DEX into combat stats. Pct
+// fields are per 100 points of the stat.
+type combatScaling struct {
+	PowerPct       int // physical power per STR
+	DexPerCrit     int // DEX per point of crit chance
+	CritMultiplier int // crit damage, percent of a normal hit
+	AccuracyPct    int // accuracy per DEX, on top of level
+	EvasionPct     int // evasion per DEX, on top of half the level
+	AttackSpeedMS  int // auto-attack swing interval without a weapon override
+}
+
+var classCombatScaling = map[string]combatScaling{
+	"Warrior":        {PowerPct: 120, DexPerCrit: 4, CritMultiplier: 200, AccuracyPct: 100, EvasionPct: 30, AttackSpeedMS: 1600},
+	"Mage":           {PowerPct: 60, DexPerCrit: 3, CritMultiplier: 160, AccuracyPct: 100, EvasionPct: 40, AttackSpeedMS: 2000},
+	"Healing Knight": {PowerPct: 100, DexPerCrit: 4, CritMultiplier: 150, AccuracyPct: 100, EvasionPct: 30, AttackSpeedMS: 1800},
+	"Archer":         {PowerPct: 80, DexPerCrit: 2, CritMultiplier: 175, AccuracyPct: 120, EvasionPct: 60, AttackSpeedMS: 1400},
+}
+
+var defaultCombatScaling = combatScaling{PowerPct: 100, DexPerCrit: 4, CritMultiplier: 150, AccuracyPct: 100, EvasionPct: 30, AttackSpeedMS: 2000}
+
+func combatScalingFor(class string) combatScaling {
+	if scaling, ok := classCombatScaling[class]; ok {
+		return scaling
+	}
+	return defaultCombatScaling
+}
+
+// CombatStats are the combat stats derived from a character's STR, DEX,
+// level, class and skills; see deriveStats.
+type CombatStats struct {
+	Power          int `json:"power"`
+	CritChance     int `json:"crit_chance"`
+	CritMultiplier int `json:"crit_multiplier"`
+	Accuracy       int `json:"accuracy"`
+	Evasion        int `json:"evasion"`
+}
+
+func characterCombatStats(c *Character) CombatStats {
+	return deriveStats(c).CombatStats
+}
+
+// characterEvasion is c's evasion, raised by evasion_step at all times once
+// learned.
+func characterEvasion(c *Character) int {
+	return deriveStats(c).Evasion
+}
+
+// mobEvasion grows with level, so out-levelled mobs are hard to hit.
+func mobEvasion(mob *MobEntity) int {
+	return mob.Level
+}
+
+// hitChancePct is the chance out of 100 that accuracy lands on evasion.
+func hitChancePct(accuracy, evasion int) int {
+	chance := baseHitChancePct + (accuracy-evasion)/2
+	if chance < minHitChancePct {
+		return minHitChancePct
+	}
+	return minInt(chance, 100)
+}
+
+// AttackResult is one resolved swing: whether it hit or crit, how its raw
+// damage was built before the target's element and defense, and whether the
+// attacker died in a fight above their level.
+type AttackResult struct {
+	Outcome        string `json:"outcome"`
+	HitChance      int    `json:"hit_chance"`
+	CritChance     int    `json:"crit_chance"`
+	Base           int    `json:"base"`
+	Power          int    `json:"power"`
+	Variance       int    `json:"variance"`
+	BonusPct       int    `json:"bonus_pct"`
+	CritMultiplier int    `json:"crit_multiplier"`
+	Damage         int    `json:"damage"`
+	Died           bool   `json:"died"`
+}
+
+// Landed reports whether the swing hit, critically or not.
+func (r AttackResult) Landed() bool {
+	return r.Outcome != AttackMiss
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestCombatStatsScaleWithClassAndEvasionStep(t *testing.T) {
+	c := MockCharacter()
+	stats := characterCombatStats(c)
+	want := CombatStats{Power: 14, CritChance: 11, CritMultiplier: 175, Accuracy: 71, Evasion: 35}
+	if stats != want {
+		t.Fatalf("expected archer stats %#v, got %#v", want, stats)
+	}
+
+	c.Skills["evasion_step"] = 3
+	if got := characterEvasion(c); got != 62 {
+		t.Fatalf("expected evasion_step rank 3 to add 27 evasion, got %d", got)
+	}
+	if hitChancePct(71, 35) != 100 || hitChancePct(71, 62) != 94 || hitChancePct(0, 500) != minHitChancePct {
+		t.Fatalf("unexpected hit chances %d %d %d", hitChancePct(71, 35), hitChancePct(71, 62), hitChancePct(0, 500))
+	}
+
+	c.Class, c.Strength = "Warrior", 22
+	if stats := characterCombatStats(c); stats.Power != 26 || stats.CritMultiplier != 200 {
+		t.Fatalf("expected warriors to hit harder from STR, got %#v", stats)
+	}
+}
+
+func TestCalculateAttackHitsCritsAndMisses(t *testing.T) {
+	c := MockCharacter()
+	withFixedRandFloat64(0.99, func() {
+		withFixedRandIntn(0, func() {
+			hit := calculateAttack(c, c.Level, 0)
+			if hit.Outcome != AttackHit || hit.Damage != hit.Base+hit.Power || hit.CritMultiplier != 100 {
+				t.Fatalf("expected a plain hit, got %#v", hit)
+			}
+		})
+		withFixedRandIntn(99, func() {
+			crit := calculateAttack(c, c.Level, 0)
+			if crit.Outcome != AttackCrit || crit.Variance != 7 || crit.Damage != (crit.Base+crit.Power+7)*175/100 {
+				t.Fatalf("expected a 175%% archer crit, got %#v", crit)
+			}
+			miss := calculateAttack(c, c.Level, 500)
+			if miss.Landed() || miss.Damage != 0 || miss.HitChance != minHitChancePct {
+				t.Fatalf("expected a miss against an evasive target, got %#v", miss)
+			}
+		})
+	})
+}
+
+func TestMissedAttacksDealNoDamage(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	archer, _ := newVisibilityTestSession("Squinter", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(archer)
+	rival, _ := newVisibilityTestSession("Dodger", worlds[World1], Position{X: 110, Y: 0, Z: 100})
+	defer unregisterSession(rival)
+	rival.Character.Skills["evasion_step"] = 3
+
+	withFixedRandFloat64(0.99, func() {
+		withFixedRandIntn(99, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.Level = 300
+			withWorldMob(World1, mob.ID, mob, func() {
+				result, ok, reason := attackMob(archer, mob.ID, "")
+				if !ok {
+					t.Fatalf("attack failed: %s", reason)
+				}
+				if result["outcome"] != AttackMiss || result["damage"] != 0 || mob.HP != mob.MaxHP {
+					t.Fatalf("expected a miss on an out-levelled mob, got %#v (HP %d)", result, mob.HP)
+				}
+			})
+
+			// 94% to hit: the roll of 99 misses the evasive rival.
+			hp := rival.Character.HP
+			result, ok, reason := attackPlayer(archer, rival, "")
+			if !ok {
+				t.Fatalf("pvp attack failed: %s", reason)
+			}
+			attack := result["attack"].(AttackResult)
+			if result["outcome"] != AttackMiss || attack.HitChance != 94 || rival.Character.HP != hp {
+				t.Fatalf("expected evasion_step to dodge the swing, got %#v", result)
+			}
+		})
+	})
+}
@@ -59,6 +59,8 @@ const (
 	ReqPartyKick      = "PARTY_KICK"
 	ReqPartyTransfer  = "PARTY_TRANSFER_LEADER"
 	ReqPartyDisband   = "PARTY_DISBAND"
+	ReqEnterDungeon   = "ENTER_DUNGEON"
+	ReqLeaveDungeon   = "LEAVE_DUNGEON"
 	ReqChatParty      = "CHAT_PARTY"
 	ReqGuildCreate    = "GUILD_CREATE"
 	ReqGuildJoin      = "GUILD_JOIN"
@@ -76,6 +78,12 @@ const (
 	ReqGuildDemote    = "GUILD_DEMOTE"
 	ReqGuildTransfer  = "GUILD_TRANSFER_LEADER"
 	ReqTeleport       = "TELEPORT"
+	ReqCastSupport    = "CAST_SUPPORT"
+	ReqGetStats       = "GET_STATS"
+
+	ReqSelectTarget    = "SELECT_TARGET"
+	ReqStartAutoAttack = "START_AUTO_ATTACK"
+	ReqStopAutoAttack  = "STOP_AUTO_ATTACK"
 )
 
 const (
@@ -92,12 +100,14 @@ const (
 	RespSkillLearned      = "SKILL_LEARNED"
 	RespEnterDenied       = "ENTER_DENIED"
 	RespEnterOK           = "ENTER_OK"
-	RespNPCState          = "NPC_STATE"
+	RespDialogueState     = "DIALOGUE_STATE"
+	RespDialogueRejected  = "DIALOGUE_REJECTED"
 	RespQuestRejected     = "QUEST_REJECTED"
 	RespQuestAccepted     = "QUEST_ACCEPTED"
 	RespQuestCompleted    = "QUEST_COMPLETED"
 	RespPlayerDied        = "PLAYER_DIED"
 	RespCombatResult      = "COMBAT_RESULT"
+	RespCombatRejected    = "COMBAT_REJECTED"
 	RespMobAttackRejected = "MOB_ATTACK_REJECTED"
 	RespMobAttackResult   = "MOB_ATTACK_RESULT"
 	RespPVPRejected       = "PVP_REJECTED"
@@ -148,6 +158,43 @@ const (
 	RespGuildRejected     = "GUILD_REJECTED"
 	RespGuildMembers      = "GUILD_MEMBERS"
 	RespTeleportOK        = "TELEPORT_OK"
+	RespMobSpawned        = "MOB_SPAWNED"
+	RespMobMoved          = "MOB_MOVED"
+	RespMobDamaged        = "MOB_DAMAGED"
+	RespMobDied           = "MOB_DIED"
+	RespMobEvaded         = "MOB_EVADED"
+	RespMobHit            = "MOB_HIT"
+	RespMobAbility        = "MOB_ABILITY"
+	RespDungeonEntered    = "DUNGEON_ENTERED"
+	RespDungeonLeft       = "DUNGEON_LEFT"
+	RespDungeonRejected   = "DUNGEON_REJECTED"
+	RespDungeonComplete   = "DUNGEON_COMPLETE"
+	RespDungeonExpired    = "DUNGEON_EXPIRED"
+	RespWorldHandoff      = "WORLD_HANDOFF"
+	RespRegionChanged     = "REGION_CHANGED"
+
+	RespWorldBossSpawned   = "WORLD_BOSS_SPAWNED"
+	RespWorldBossPhase     = "WORLD_BOSS_PHASE"
+	RespWorldBossDefeated  = "WORLD_BOSS_DEFEATED"
+	RespWorldBossDespawned = "WORLD_BOSS_DESPAWNED"
+	RespWorldBossLoot      = "WORLD_BOSS_LOOT"
+
+	RespEffectApplied = "EFFECT_APPLIED"
+	RespEffectExpired = "EFFECT_EXPIRED"
+
+	RespSupportResult   = "SUPPORT_RESULT"
+	RespSupportRejected = "SUPPORT_REJECTED"
+	RespHealed          = "HEALED"
+	RespResurrected     = "RESURRECTED"
+
+	RespStats = "STATS"
+
+	RespTargetSelected     = "TARGET_SELECTED"
+	RespTargetRejected     = "TARGET_REJECTED"
+	RespAutoAttackStarted  = "AUTO_ATTACK_STARTED"
+	RespAutoAttackStopped  = "AUTO_ATTACK_STOPPED"
+	RespAutoAttackResult   = "AUTO_ATTACK_RESULT"
+	RespAutoAttackRejected = "AUTO_ATTACK_REJECTED"
 )
 
 const (
@@ -246,6 +246,7 @@ func TestCommandFlowAuthLootCraftState(t *testing.T) {
 	withWorldMob(World1, "mob_wolf_01", &MobEntity{
 		ID:         "mob_wolf_01",
 		Name:       "Rift Wolf",
+		LootTable:  "rift_wolf",
 		WorldID:    World1,
 		Level:      42,
 		HP:         1,
@@ -360,6 +361,7 @@ func TestCommandFlowAuthLootCraftState(t *testing.T) {
 		withWorldMob(World1, "mob_bandit_01", &MobEntity{
 			ID:         "mob_bandit_01",
 			Name:       "Dust Bandit",
+			LootTable:  "dust_bandit",
 			WorldID:    World1,
 			Level:      46,
 			HP:         1,
@@ -2,15 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 type ZoneConfig struct {
-	ServerName string `json:"server_name"`
-	TickRateMS int    `json:"tick_rate_ms"`
-	ListenPort int    `json:"listen_port"`
+	ServerName  string `json:"server_name"`
+	TickRateMS  int    `json:"tick_rate_ms"`
+	ListenPort  int    `json:"listen_port"`
+	NodeID      string `json:"node_id"`
+	PublicAddr  string `json:"public_addr"`
+	OwnedWorlds []int  `json:"owned_worlds"`
+	SpawnDir    string `json:"spawn_dir"`
+
+	SchedulerStatePath string `json:"scheduler_state_path"`
+	AdminToken         string `json:"admin_token"`
 }
 
 func loadZoneConfig(path string) ZoneConfig {
@@ -47,6 +56,43 @@ func loadZoneConfig(path string) ZoneConfig {
 			cfg.ListenPort = port
 		}
 	}
+	if raw := strings.TrimSpace(os.Getenv("A3_NODE_ID")); raw != "" {
+		cfg.NodeID = raw
+	}
+	if raw := strings.TrimSpace(os.Getenv("A3_PUBLIC_ADDR")); raw != "" {
+		cfg.PublicAddr = raw
+	}
+	if raw := strings.TrimSpace(os.Getenv("A3_SPAWN_DIR")); raw != "" {
+		cfg.SpawnDir = raw
+	}
+	if raw := strings.TrimSpace(os.Getenv("A3_SCHEDULER_STATE")); raw != "" {
+		cfg.SchedulerStatePath = raw
+	}
+	if raw := strings.TrimSpace(os.Getenv("A3_ADMIN_TOKEN")); raw != "" {
+		cfg.AdminToken = raw
+	}
+	if raw := strings.TrimSpace(os.Getenv("A3_OWNED_WORLDS")); raw != "" {
+		cfg.OwnedWorlds = parseWorldList(raw)
+	}
+	if cfg.NodeID == "" {
+		cfg.NodeID = fmt.Sprintf("zone-%d", cfg.ListenPort)
+	}
+	if cfg.PublicAddr == "" {
+		cfg.PublicAddr = fmt.Sprintf("ws://127.0.0.1:%d/ws", cfg.ListenPort)
+	}
+	if cfg.SchedulerStatePath == "" {
+		cfg.SchedulerStatePath = filepath.Join("data", "scheduler", cfg.NodeID+".json")
+	}
 
 	return cfg
 }
+
+func parseWorldList(raw string) []int {
+	out := make([]int, 0)
+	for _, part := range strings.Split(raw, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && id > 0 {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -53,15 +53,16 @@ var materialCatalog = map[string]MaterialDefinition{
 
 var gearTemplates = map[string]Item{
 	"crafted_wolfhide_bow": {
-		ID:        "crafted_wolfhide_bow",
-		Name:      "Wolfhide Hunter Bow",
-		Grade:     4,
-		Rarity:    RarityRare,
-		Slot:      SlotWeapon,
-		Element:   ElementNone,
-		GearLevel: 1,
-		MinSTR:    20,
-		MinDEX:    24,
+		ID:            "crafted_wolfhide_bow",
+		Name:          "Wolfhide Hunter Bow",
+		Grade:         4,
+		Rarity:        RarityRare,
+		Slot:          SlotWeapon,
+		Element:       ElementNone,
+		GearLevel:     1,
+		MinSTR:        20,
+		MinDEX:        24,
+		AttackSpeedMS: 1200,
 	},
 	"crafted_bandit_mail": {
 		ID:        "crafted_bandit_mail",
@@ -75,15 +76,16 @@ var gearTemplates = map[string]Item{
 		MinDEX:    16,
 	},
 	"crafted_shard_blade": {
-		ID:        "crafted_shard_blade",
-		Name:      "Shardsteel Blade",
-		Grade:     6,
-		Rarity:    RarityEpic,
-		Slot:      SlotWeapon,
-		Element:   ElementLightning,
-		GearLevel: 1,
-		MinSTR:    30,
-		MinDEX:    24,
+		ID:            "crafted_shard_blade",
+		Name:          "Shardsteel Blade",
+		Grade:         6,
+		Rarity:        RarityEpic,
+		Slot:          SlotWeapon,
+		Element:       ElementLightning,
+		GearLevel:     1,
+		MinSTR:        30,
+		MinDEX:        24,
+		AttackSpeedMS: 1400,
 	},
 	"crafted_guardian_shield": {
 		ID:        "crafted_guardian_shield",
@@ -165,25 +167,25 @@ var gearTemplates = map[string]Item{
 }
 
 var lootTables = map[string][]LootEntry{
-	"mob_wolf_01": {
+	"rift_wolf": {
 		{Kind: lootKindMaterial, ItemID: "wolf_pelt", DropRateBPS: 10000, MinQty: 1, MaxQty: 2},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t1", DropRateBPS: 2500, MinQty: 1, MaxQty: 1},
 		{Kind: lootKindMaterial, ItemID: "pet_treat", DropRateBPS: 1800, MinQty: 1, MaxQty: 1},
 	},
-	"mob_bandit_01": {
+	"dust_bandit": {
 		{Kind: lootKindMaterial, ItemID: "bandit_scrap", DropRateBPS: 8500, MinQty: 1, MaxQty: 3},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t1", DropRateBPS: 3500, MinQty: 1, MaxQty: 1},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t2", DropRateBPS: 700, MinQty: 1, MaxQty: 1},
 		{Kind: lootKindMaterial, ItemID: "pet_treat", DropRateBPS: 2400, MinQty: 1, MaxQty: 1},
 	},
-	"mob_shard_01": {
+	"shard_revenant": {
 		{Kind: lootKindMaterial, ItemID: "shard_core", DropRateBPS: 7000, MinQty: 1, MaxQty: 2},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t2", DropRateBPS: 2200, MinQty: 1, MaxQty: 1},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t3", DropRateBPS: 450, MinQty: 1, MaxQty: 1},
 		{Kind: lootKindMaterial, ItemID: "pet_treat", DropRateBPS: 2800, MinQty: 1, MaxQty: 2},
 		{Kind: lootKindGear, ItemID: "crafted_shard_blade", DropRateBPS: 700, MinQty: 1, MaxQty: 1},
 	},
-	"mob_myth_01": {
+	"mythic_devourer": {
 		{Kind: lootKindMaterial, ItemID: "mythic_essence", DropRateBPS: 8000, MinQty: 1, MaxQty: 2},
 		{Kind: lootKindMaterial, ItemID: "enhance_gem_t3", DropRateBPS: 2400, MinQty: 1, MaxQty: 2},
 		{Kind: lootKindMaterial, ItemID: "pet_treat", DropRateBPS: 3200, MinQty: 1, MaxQty: 2},
@@ -376,8 +378,9 @@ func craftItem(c *Character, recipeID string, qty int) (map[string]interface{},
 	}, true, "OK"
 }
 
-func rollLootForMob(c *Character, mobID string) []map[string]interface{} {
-	entries, ok := lootTables[mobID]
+// rollLootForMob rolls the loot table a mob template points at.
+func rollLootForMob(c *Character, lootTable string) []map[string]interface{} {
+	entries, ok := lootTables[lootTable]
 	if !ok {
 		return nil
 	}
@@ -22,7 +22,7 @@ func TestRollLootForMobGuaranteedMaterial(t *testing.T) {
 	c.Materials = map[string]int{}
 
 	withFixedRandIntn(0, func() {
-		drops := rollLootForMob(c, "mob_wolf_01")
+		drops := rollLootForMob(c, "rift_wolf")
 		if len(drops) == 0 {
 			t.Fatalf("expected drops")
 		}
@@ -37,7 +37,7 @@ func TestRollLootForMobMiss(t *testing.T) {
 	c.Materials = map[string]int{}
 
 	withFixedRandIntn(9999, func() {
-		drops := rollLootForMob(c, "mob_bandit_01")
+		drops := rollLootForMob(c, "dust_bandit")
 		if len(drops) != 0 {
 			t.Fatalf("expected no drops on high roll, got %d", len(drops))
 		}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, 3, 14, 10, 17, 30, 0, time.UTC) // a Saturday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"0 */2 * * *", time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2026, 3, 15, 9, 30, 0, 0, time.UTC)},
+		{"0 20 * * 0", time.Date(2026, 3, 15, 20, 0, 0, 0, time.UTC)},
+		{"0 20 * * 7", time.Date(2026, 3, 15, 20, 0, 0, 0, time.UTC)},
+		{"15,45 10-11 * * *", time.Date(2026, 3, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 1", time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)}, // day fields OR together
+	}
+	for _, tc := range cases {
+		c, err := parseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := c.Next(from); !got.Equal(tc.want) {
+			t.Fatalf("%q: expected %v, got %v", tc.expr, tc.want, got)
+		}
+	}
+
+	never, _ := parseCron("0 0 30 2 *")
+	if got := never.Next(from); !got.IsZero() {
+		t.Fatalf("expected February 30th never to fire, got %v", got)
+	}
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
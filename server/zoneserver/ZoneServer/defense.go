@@ -0,0 +1,50 @@
+package main
+
+// defenseScale sets how quickly defense pays off: a target with
+// defenseScale defense takes half damage. Returns diminish, so stacking
+// defense never makes anyone immune.
+const defenseScale = 100
+
+// armorSlots are the gear slots that count toward defense.
+var armorSlots = map[string]bool{
+	SlotArmor:  true,
+	SlotHelmet: true,
+	SlotGloves: true,
+	SlotBoots:  true,
+	SlotPants:  true,
+	SlotShield: true,
+}
+
+// defenseSkills add their learned bonus to defense at all times.
+var defenseSkills = map[string]bool{
+	"iron_wall":       true,
+	"mana_barrier":    true,
+	"guardian_shield": true,
+}
+
+// characterDefense is c's derived defense: equipped armor-slot gear,
+// defensive skills and defense effects.
+func characterDefense(c *Character) int {
+	return deriveStats(c).Defense
+}
+
+// mitigateDamage is the single place incoming damage meets defense. Every
+// hit that lands deals at least 1.
+func mitigateDamage(damage, defense int) int {
+	if defense > 0 {
+		damage = damage * defenseScale / (defenseScale + defense)
+	}
+	if damage < 1 {
+		damage = 1
+	}
+	return damage
+}
+
+// damageMob applies damage to mob after its defense and any damage shield
+// and returns what was dealt.
+func damageMob(mob *MobEntity, damage int) int {
+	defense := mob.Defense + effectTotal(mob.effects, EffectDefense)
+	dealt := absorbDamage(mob.effects, mitigateDamage(damage, defense))
+	mob.HP -= dealt
+	return dealt
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestCharacterDefenseCountsArmorAndDefensiveSkills(t *testing.T) {
+	c := MockCharacter()
+	ensureCharacterDefaults(c)
+	if got := characterDefense(c); got != 0 {
+		t.Fatalf("expected a weapon-only character to have no defense, got %d", got)
+	}
+
+	c.Class = "Warrior"
+	c.Inventory = append(c.Inventory,
+		Item{ID: "plate", Name: "Plate", Grade: 5, Rarity: RarityEpic, Slot: SlotArmor, GearLevel: 3},
+		Item{ID: "kite", Name: "Kite Shield", Grade: 3, Rarity: RarityUnique, Slot: SlotShield, GearLevel: 1},
+		Item{ID: "spare_helm", Name: "Spare Helm", Grade: 9, Rarity: RarityCommon, Slot: SlotHelmet, GearLevel: 1},
+	)
+	c.Equipped[SlotArmor] = "plate"
+	c.Equipped[SlotShield] = "kite"
+	// plate 10+2+2, kite 6+4; the helm is carried, not worn.
+	if got := characterDefense(c); got != 24 {
+		t.Fatalf("expected 24 defense from worn armor, got %d", got)
+	}
+	c.Skills["iron_wall"] = 2
+	if got := characterDefense(c); got != 30 {
+		t.Fatalf("expected iron_wall to add 6 defense, got %d", got)
+	}
+}
+
+func TestMitigationAppliesToPlayersAndMobs(t *testing.T) {
+	if got := mitigateDamage(200, 0); got != 200 {
+		t.Fatalf("expected no mitigation without defense, got %d", got)
+	}
+	if got := mitigateDamage(200, defenseScale); got != 100 {
+		t.Fatalf("expected defenseScale defense to halve damage, got %d", got)
+	}
+	if got := mitigateDamage(1, 1000); got != 1 {
+		t.Fatalf("expected every hit to deal at least 1, got %d", got)
+	}
+
+	tank := MockCharacter()
+	ensureCharacterDefaults(tank)
+	tank.Class = "Mage"
+	tank.Skills["mana_barrier"] = 3 // 9 defense
+	tank.Inventory = append(tank.Inventory, Item{ID: "robe", Name: "Robe", Grade: 10, Rarity: RarityUnique, Slot: SlotArmor, GearLevel: 10})
+	tank.Equipped[SlotArmor] = "robe" // 20+9+4 = 33 defense
+	if dealt, _ := damageCharacter(tank, Position{}, 84); dealt != 84*defenseScale/(defenseScale+42) {
+		t.Fatalf("expected mob and PvP damage to be mitigated by defense, got %d", dealt)
+	}
+
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	session, _ := newVisibilityTestSession("Hunter", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(session)
+	plain := aiTestMob(Position{X: 105, Y: 0, Z: 100})
+	plain.HP, plain.MaxHP = 10000, 10000
+	armored := aiTestMob(Position{X: 105, Y: 0, Z: 100})
+	armored.ID, armored.HP, armored.MaxHP, armored.Defense = "mob_armored", 10000, 10000, defenseScale
+
+	withFixedRandIntn(0, func() {
+		withWorldMob(World1, plain.ID, plain, func() {
+			worldSimFor(World1).mobs[armored.ID] = armored
+			hit, _, _ := attackMob(session, plain.ID, "")
+			blocked, _, _ := attackMob(session, armored.ID, "")
+			if toInt(hit, "damage") < 2 || toInt(blocked, "damage") != toInt(hit, "damage")/2 {
+				t.Fatalf("expected mob defense to halve player damage, got %v vs %v", blocked["damage"], hit["damage"])
+			}
+			if armored.HP != 10000-toInt(blocked, "damage") {
+				t.Fatalf("expected the mob to lose the mitigated amount, hp %d", armored.HP)
+			}
+		})
+	})
+}
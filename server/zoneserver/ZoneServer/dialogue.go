@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const dialoguesFile = "dialogues.json"
+
+// Quest states a dialogue condition can require.
+const (
+	questStateNotAccepted = "not_accepted"
+	questStateAccepted    = "accepted" // accepted, not yet complete
+	questStateComplete    = "complete"
+)
+
+// Dialogue is an NPC's conversation graph. A conversation starts at Start
+// and follows the Next of each choice the player picks; a choice without
+// Next ends it.
+type Dialogue struct {
+	NPC   string                   `json:"npc"` // NPCEntity.ID
+	Start string                   `json:"start"`
+	Nodes map[string]*DialogueNode `json:"nodes"`
+}
+
+type DialogueNode struct {
+	Text    string           `json:"text"`
+	Choices []DialogueChoice `json:"choices"`
+}
+
+// DialogueChoice is offered only while all of its conditions hold, and
+// applies its effects when picked.
+type DialogueChoice struct {
+	ID         string            `json:"id"`
+	Text       string            `json:"text"`
+	Next       string            `json:"next"`
+	Conditions DialogueCondition `json:"conditions"`
+	Effects    DialogueEffects   `json:"effects"`
+}
+
+type DialogueCondition struct {
+	MinTrust int      `json:"min_trust"`
+	MinLevel int      `json:"min_level"`
+	Classes  []string `json:"classes"`
+	// Quest must be in QuestState, one of the questState constants.
+	Quest      string `json:"quest"`
+	QuestState string `json:"quest_state"`
+	// FlagSet / FlagUnset test the character's dialogue flags, so one-off
+	// rewards can't be farmed.
+	FlagSet   string `json:"flag_set"`
+	FlagUnset string `json:"flag_unset"`
+}
+
+type DialogueEffects struct {
+	Trust int `json:"trust"`
+	// OfferQuest accepts the quest, subject to the usual ACCEPT_QUEST rules.
+	OfferQuest string         `json:"offer_quest"`
+	Grant      *DialogueGrant `json:"grant"`
+	SetFlag    string         `json:"set_flag"`
+	// ApplyEffect puts a status effect from effectCatalog on the player.
+	ApplyEffect string `json:"apply_effect"`
+}
+
+// DialogueGrant hands out a material or a gear template, like a loot entry.
+type DialogueGrant struct {
+	Kind   string `json:"kind"` // lootKindMaterial or lootKindGear
+	ItemID string `json:"item_id"`
+	Qty    int    `json:"qty"`
+}
+
+// dialogueCursor is where a session stands in a conversation.
+type dialogueCursor struct {
+	npcID string
+	node  string
+}
+
+func validateDialogues(dialogues []Dialogue, data *SpawnData) []string {
+	problems := make([]string, 0)
+	for i := range dialogues {
+		d := &dialogues[i]
+		npc := findNPC(d.NPC)
+		if npc == nil {
+			problems = append(problems, fmt.Sprintf("dialogue references unknown NPC %q", d.NPC))
+			continue
+		}
+		if _, dup := data.Dialogues[d.NPC]; dup {
+			problems = append(problems, fmt.Sprintf("duplicate dialogue for NPC %q", d.NPC))
+		}
+		if _, ok := d.Nodes[d.Start]; !ok {
+			problems = append(problems, fmt.Sprintf("dialogue %q starts at unknown node %q", d.NPC, d.Start))
+		}
+		for nodeID, node := range d.Nodes {
+			if node == nil {
+				problems = append(problems, fmt.Sprintf("dialogue %q node %q is empty", d.NPC, nodeID))
+				continue
+			}
+			seen := map[string]bool{}
+			for _, choice := range node.Choices {
+				where := fmt.Sprintf("dialogue %q node %q choice %q", d.NPC, nodeID, choice.ID)
+				if choice.ID == "" || seen[choice.ID] {
+					problems = append(problems, where+" needs a unique id")
+				}
+				seen[choice.ID] = true
+				if _, ok := d.Nodes[choice.Next]; choice.Next != "" && !ok {
+					problems = append(problems, fmt.Sprintf("%s leads to unknown node %q", where, choice.Next))
+				}
+				problems = append(problems, validateDialogueChoice(where, choice)...)
+			}
+		}
+		data.Dialogues[d.NPC] = d
+	}
+	return problems
+}
+
+func validateDialogueChoice(where string, choice DialogueChoice) []string {
+	problems := make([]string, 0)
+	cond := choice.Conditions
+	for _, class := range cond.Classes {
+		if canonicalClassName(class) != class {
+			problems = append(problems, fmt.Sprintf("%s requires unknown class %q", where, class))
+		}
+	}
+	if cond.Quest != "" || cond.QuestState != "" {
+		if _, ok := quests[cond.Quest]; !ok {
+			problems = append(problems, fmt.Sprintf("%s references unknown quest %q", where, cond.Quest))
+		}
+		switch cond.QuestState {
+		case questStateNotAccepted, questStateAccepted, questStateComplete:
+		default:
+			problems = append(problems, fmt.Sprintf("%s has unknown quest_state %q", where, cond.QuestState))
+		}
+	}
+	if q := choice.Effects.OfferQuest; q != "" {
+		if _, ok := quests[q]; !ok {
+			problems = append(problems, fmt.Sprintf("%s offers unknown quest %q", where, q))
+		}
+	}
+	if e := choice.Effects.ApplyEffect; e != "" {
+		if _, ok := effectCatalog[e]; !ok {
+			problems = append(problems, fmt.Sprintf("%s applies unknown effect %q", where, e))
+		}
+	}
+	if g := choice.Effects.Grant; g != nil {
+		known := false
+		switch g.Kind {
+		case lootKindMaterial:
+			_, known = materialCatalog[g.ItemID]
+		case lootKindGear:
+			_, known = gearTemplates[g.ItemID]
+		}
+		if !known || g.Qty <= 0 {
+			problems = append(problems, fmt.Sprintf("%s grants unknown or empty %s %q", where, g.Kind, g.ItemID))
+		}
+	}
+	return problems
+}
+
+func findNPC(id string) *NPCEntity {
+	for worldID := range worldNPCs {
+		for i := range worldNPCs[worldID] {
+			if worldNPCs[worldID][i].ID == id {
+				return &worldNPCs[worldID][i]
+			}
+		}
+	}
+	return nil
+}
+
+// npcInWorld resolves an NPC by ID or name in the session's world.
+func npcInWorld(session *ClientSession, ref string) *NPCEntity {
+	if session == nil || session.World == nil {
+		return nil
+	}
+	ref = strings.TrimSpace(ref)
+	npcs := worldNPCs[session.World.ID]
+	for i := range npcs {
+		if npcs[i].ID == ref || strings.EqualFold(npcs[i].Name, ref) {
+			return &npcs[i]
+		}
+	}
+	return nil
+}
+
+func (cond DialogueCondition) holds(c *Character, npcName string) bool {
+	if c.Trust[npcName] < cond.MinTrust || c.Level < cond.MinLevel {
+		return false
+	}
+	if len(cond.Classes) > 0 {
+		match := false
+		for _, class := range cond.Classes {
+			if class == c.Class {
+				match = true
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	if cond.Quest != "" && questState(c, cond.Quest) != cond.QuestState {
+		return false
+	}
+	if cond.FlagSet != "" && !c.DialogueFlags[cond.FlagSet] {
+		return false
+	}
+	if cond.FlagUnset != "" && c.DialogueFlags[cond.FlagUnset] {
+		return false
+	}
+	return true
+}
+
+func questState(c *Character, questID string) string {
+	cur := c.Quests[questID]
+	switch {
+	case cur == nil || !cur.Accepted:
+		return questStateNotAccepted
+	case cur.Complete:
+		return questStateComplete
+	default:
+		return questStateAccepted
+	}
+}
+
+// talkToNPC starts a conversation when choiceID is empty, or picks a choice
+// from the node the session is on. It returns the DIALOGUE_STATE payload.
+func talkToNPC(session *ClientSession, npcRef, choiceID string) (map[string]interface{}, bool, string) {
+	npc := npcInWorld(session, npcRef)
+	if npc == nil {
+		return nil, false, "NPC_NOT_FOUND"
+	}
+	if !isVisible(session.Position, npc.Position) {
+		return nil, false, "NPC_OUT_OF_RANGE"
+	}
+	dialogue := activeSpawnData().Dialogues[npc.ID]
+	if dialogue == nil {
+		return nil, false, "NO_DIALOGUE"
+	}
+	c := session.Character
+
+	if choiceID == "" {
+		session.dialogue = &dialogueCursor{npcID: npc.ID, node: dialogue.Start}
+		return dialogueStatePayload(c, npc, dialogue, dialogue.Start, nil), true, "OK"
+	}
+	cursor := session.dialogue
+	if cursor == nil || cursor.npcID != npc.ID {
+		return nil, false, "NOT_IN_DIALOGUE"
+	}
+	var choice *DialogueChoice
+	for i, candidate := range dialogue.Nodes[cursor.node].Choices {
+		if candidate.ID == choiceID && candidate.Conditions.holds(c, npc.Name) {
+			choice = &dialogue.Nodes[cursor.node].Choices[i]
+			break
+		}
+	}
+	if choice == nil {
+		return nil, false, "CHOICE_UNAVAILABLE"
+	}
+	if q := choice.Effects.OfferQuest; q != "" {
+		// Check the quest before applying anything, so a refused offer
+		// leaves the conversation where it was.
+		if _, ok, reason := canAcceptQuest(c, q); !ok {
+			return nil, false, reason
+		}
+	}
+
+	applied := applyDialogueEffects(session, npc, choice.Effects)
+	if choice.Next == "" {
+		session.dialogue = nil
+	} else {
+		cursor.node = choice.Next
+	}
+	return dialogueStatePayload(c, npc, dialogue, choice.Next, applied), true, "OK"
+}
+
+func applyDialogueEffects(session *ClientSession, npc *NPCEntity, effects DialogueEffects) map[string]interface{} {
+	c := session.Character
+	applied := map[string]interface{}{}
+	if effects.Trust != 0 {
+		c.Trust[npc.Name] += effects.Trust
+		applied["trust_change"] = effects.Trust
+	}
+	if effects.OfferQuest != "" {
+		if q, ok, _ := acceptQuest(c, effects.OfferQuest); ok {
+			applied["quest_accepted"] = map[string]interface{}{"quest_id": q.ID, "quest_name": q.Name}
+		}
+	}
+	if g := effects.Grant; g != nil {
+		switch g.Kind {
+		case lootKindMaterial:
+			c.Materials[g.ItemID] += g.Qty
+		case lootKindGear:
+			for i := 0; i < g.Qty; i++ {
+				if item, ok := buildItemFromTemplate(g.ItemID); ok {
+					c.Inventory = append(c.Inventory, item)
+				}
+			}
+		}
+		applied["granted"] = map[string]interface{}{"kind": g.Kind, "item_id": g.ItemID, "qty": g.Qty}
+	}
+	if effects.ApplyEffect != "" {
+		var outbox mobOutbox
+		def := effectCatalog[effects.ApplyEffect]
+		applySessionEffect(session, def.ID, npc.Name, def.Magnitude, 0, simTickClock(), &outbox)
+		outbox.flush()
+		applied["effect_applied"] = def.ID
+	}
+	if effects.SetFlag != "" {
+		if c.DialogueFlags == nil {
+			c.DialogueFlags = map[string]bool{}
+		}
+		c.DialogueFlags[effects.SetFlag] = true
+	}
+	return applied
+}
+
+// dialogueStatePayload describes nodeID with the choices c may pick; an
+// empty nodeID means the conversation ended.
+func dialogueStatePayload(c *Character, npc *NPCEntity, dialogue *Dialogue, nodeID string, applied map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{
+		"npc_id": npc.ID,
+		"npc":    npc.Name,
+		"trust":  c.Trust[npc.Name],
+		"ended":  nodeID == "",
+	}
+	if node := dialogue.Nodes[nodeID]; node != nil {
+		choices := make([]map[string]interface{}, 0, len(node.Choices))
+		for _, choice := range node.Choices {
+			if choice.Conditions.holds(c, npc.Name) {
+				choices = append(choices, map[string]interface{}{"id": choice.ID, "text": choice.Text})
+			}
+		}
+		out["node"] = nodeID
+		out["text"] = node.Text
+		out["choices"] = choices
+	}
+	for k, v := range applied {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestTalkNPCWalksDialogueTree(t *testing.T) {
+	resetSocialStateForTests()
+	resetPersistenceRuntimeStateForTests()
+	worlds = DefaultWorlds()
+
+	conn := &captureConn{}
+	session := NewSession(conn)
+	session.Character = MockCharacter()
+	ensureCharacterDefaults(session.Character)
+	session.World = worlds[World1]
+	session.Position = Position{X: 200, Y: 0, Z: 200}
+	visible := map[*ClientSession]bool{}
+	bound := ""
+	talk := func(npc, choice string) map[string]interface{} {
+		t.Helper()
+		handleClientCommand(conn, session, visible, "dialogue-peer", &bound, ReqTalkNPC, map[string]interface{}{"npc": npc, "choice": choice})
+		msgs := conn.DrainMessages(t)
+		if len(msgs) == 0 {
+			t.Fatalf("TALK_NPC %s/%s: no response", npc, choice)
+		}
+		if msgs[0].Command == RespDialogueRejected {
+			return map[string]interface{}{"rejected": msgs[0].Payload}
+		}
+		return toMap(msgs[0].Payload)
+	}
+	choiceIDs := func(state map[string]interface{}) map[string]bool {
+		ids := map[string]bool{}
+		choices, _ := state["choices"].([]interface{})
+		for _, choice := range choices {
+			ids[toString(toMap(choice), "id")] = true
+		}
+		return ids
+	}
+
+	if got := talk("Elder Rowan", ""); got["rejected"] != "NPC_OUT_OF_RANGE" {
+		t.Fatalf("expected NPC_OUT_OF_RANGE away from Rowan, got %#v", got)
+	}
+	session.Position = Position{X: -5, Y: 0, Z: -5}
+	if got := talk("", ""); got["rejected"] != "NPC_NOT_FOUND" {
+		t.Fatalf("expected a missing npc to be rejected, got %#v", got)
+	}
+	if got := talk("Elder Rowan", "honor"); got["rejected"] != "NOT_IN_DIALOGUE" {
+		t.Fatalf("expected a choice before greeting to be rejected, got %#v", got)
+	}
+
+	state := talk("npc_elder_rowan", "")
+	if state["node"] != "greeting" || !choiceIDs(state)["honor"] || choiceIDs(state)["oath"] {
+		t.Fatalf("expected the greeting without the oath choice, got %#v", state)
+	}
+	if got := talk("Elder Rowan", "oath"); got["rejected"] != "CHOICE_UNAVAILABLE" {
+		t.Fatalf("expected a gated choice to be rejected, got %#v", got)
+	}
+	state = talk("Elder Rowan", "honor")
+	if state["node"] != "grateful" || toInt(state, "trust") != 15 || toInt(state, "trust_change") != 15 {
+		t.Fatalf("expected honor to raise trust, got %#v", state)
+	}
+	if choiceIDs(state)["warrior_pledge"] {
+		t.Fatalf("expected the warrior-only choice to be hidden from an Archer")
+	}
+	if state = talk("Elder Rowan", "bye"); state["ended"] != true {
+		t.Fatalf("expected bye to end the conversation, got %#v", state)
+	}
+
+	session.Character.Trust["Elder Rowan"] = 60
+	state = talk("Elder Rowan", "")
+	if !choiceIDs(state)["oath"] {
+		t.Fatalf("expected the oath choice at trust 60, got %#v", state)
+	}
+	talk("Elder Rowan", "oath")
+	handleClientCommand(conn, session, visible, "dialogue-peer", &bound, ReqTalkNPC, map[string]interface{}{"npc": "Elder Rowan", "choice": "swear"})
+	msgs := conn.DrainMessages(t)
+	if !hasMessage(msgs, RespQuestAccepted, nil) || questState(session.Character, "npc_oath_hidden") != questStateAccepted {
+		t.Fatalf("expected swearing to accept the hidden quest, got %#v", msgs)
+	}
+
+	// The reward choice is offered once the quest is done, and only once.
+	session.Character.Quests["npc_oath_hidden"].Complete = true
+	talk("Elder Rowan", "")
+	talk("Elder Rowan", "oath_done")
+	state = talk("Elder Rowan", "accept_gift")
+	if state["granted"] == nil || session.Character.Materials["enhance_gem_t2"] != 2 {
+		t.Fatalf("expected the oath reward, got %#v", state)
+	}
+	if state = talk("Elder Rowan", ""); choiceIDs(state)["oath_done"] {
+		t.Fatalf("expected the one-off reward to disappear once claimed")
+	}
+}
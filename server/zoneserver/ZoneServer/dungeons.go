@@ -0,0 +1,245 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// DungeonDefinition describes an instanced dungeon reachable from an entrance
+// in a shared host world. Its mob roster comes from the spawn data (see
+// dungeon_spawns.json), freshly spawned for every instance.
+type DungeonDefinition struct {
+	ID        string
+	Name      string
+	HostWorld WorldID
+	Entrance  Position
+	MinLevel  int
+	TimeLimit time.Duration
+	Spawn     Position
+}
+
+// DungeonInstance is one party's private copy of a dungeon. It lives in its
+// own synthetic world, which is never added to the shared worlds map.
+type DungeonInstance struct {
+	ID        WorldID
+	Dungeon   *DungeonDefinition
+	PartyID   string
+	World     *World
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Completed bool
+
+	// arriving counts members between admission and their world change so
+	// the instance is not torn down underneath them.
+	arriving int
+}
+
+const (
+	dungeonInstanceBase   WorldID = 1000
+	dungeonEntranceRadius         = 20.0
+)
+
+var dungeonDefinitions = map[string]*DungeonDefinition{
+	"wolf_den": {
+		ID:        "wolf_den",
+		Name:      "Wolf Den",
+		HostWorld: World1,
+		Entrance:  Position{X: 130, Y: 0, Z: 120},
+		MinLevel:  10,
+		TimeLimit: 30 * time.Minute,
+		Spawn:     Position{X: 130, Y: 0, Z: 130},
+	},
+	"shard_vault": {
+		ID:        "shard_vault",
+		Name:      "Shard Vault",
+		HostWorld: World2,
+		Entrance:  Position{X: 520, Y: 0, Z: 515},
+		MinLevel:  60,
+		TimeLimit: 30 * time.Minute,
+		Spawn:     Position{X: 520, Y: 0, Z: 525},
+	},
+}
+
+var (
+	dungeonMu        sync.Mutex
+	dungeonInstances = map[WorldID]*DungeonInstance{}
+	dungeonByParty   = map[string]WorldID{}
+	dungeonSeq       WorldID
+)
+
+// admitToDungeon resolves which instance session may enter: its party's open
+// instance of dungeonID, or a fresh one when the leader enters with everyone
+// ready. The caller must follow up with finishDungeonArrival.
+func admitToDungeon(session *ClientSession, dungeonID string) (*DungeonInstance, bool, string) {
+	def, ok := dungeonDefinitions[dungeonID]
+	if !ok {
+		return nil, false, "DUNGEON_NOT_FOUND"
+	}
+	if session.World.InstanceOf != 0 {
+		return nil, false, "ALREADY_IN_DUNGEON"
+	}
+	if session.World.ID != def.HostWorld || distance(session.Position, def.Entrance) > dungeonEntranceRadius {
+		return nil, false, "NOT_AT_ENTRANCE"
+	}
+	if session.Character.Level < def.MinLevel {
+		return nil, false, "LEVEL_TOO_LOW"
+	}
+	partyID, leader, allReady, ok := partyRosterForMember(session.Character.Name)
+	if !ok {
+		return nil, false, "NOT_IN_PARTY"
+	}
+
+	dungeonMu.Lock()
+	defer dungeonMu.Unlock()
+	if id, open := dungeonByParty[partyID]; open {
+		inst := dungeonInstances[id]
+		if inst.Dungeon.ID != def.ID {
+			return nil, false, "PARTY_IN_OTHER_DUNGEON"
+		}
+		// A cleared or timed-out instance only lets its occupants out; the
+		// party opens a fresh one once the last of them has left.
+		if inst.Completed {
+			return nil, false, "DUNGEON_CLEARED"
+		}
+		if !time.Now().Before(inst.ExpiresAt) {
+			return nil, false, "DUNGEON_EXPIRED"
+		}
+		inst.arriving++
+		return inst, true, "OK"
+	}
+	if session.Character.Name != leader {
+		return nil, false, "LEADER_MUST_OPEN"
+	}
+	if !allReady {
+		return nil, false, "PARTY_NOT_READY"
+	}
+
+	dungeonSeq++
+	now := time.Now()
+	inst := &DungeonInstance{
+		ID:      dungeonInstanceBase + dungeonSeq,
+		Dungeon: def,
+		PartyID: partyID,
+		World: &World{
+			ID:         dungeonInstanceBase + dungeonSeq,
+			Name:       def.Name,
+			MinLevel:   def.MinLevel,
+			MaxLevel:   session.World.MaxLevel,
+			Unlocked:   true,
+			InstanceOf: def.HostWorld,
+		},
+		CreatedAt: now,
+		ExpiresAt: now.Add(def.TimeLimit),
+		arriving:  1,
+	}
+	dungeonInstances[inst.ID] = inst
+	dungeonByParty[partyID] = inst.ID
+
+	roster := spawnDungeonMobs(activeSpawnData(), def.ID, inst.ID)
+	initWorldEntities()
+	addWorldSim(inst.ID, roster)
+
+	log.Printf("dungeon %s opened as instance %d for party %s", def.ID, inst.ID, partyID)
+	return inst, true, "OK"
+}
+
+func finishDungeonArrival(inst *DungeonInstance) {
+	dungeonMu.Lock()
+	inst.arriving--
+	dungeonMu.Unlock()
+	releaseDungeonIfEmpty(inst.ID)
+}
+
+func dungeonInstanceFor(worldID WorldID) *DungeonInstance {
+	dungeonMu.Lock()
+	defer dungeonMu.Unlock()
+	return dungeonInstances[worldID]
+}
+
+func dungeonPayload(inst *DungeonInstance) map[string]interface{} {
+	return map[string]interface{}{
+		"dungeon_id":     inst.Dungeon.ID,
+		"instance_id":    inst.ID,
+		"party_id":       inst.PartyID,
+		"expires_in_sec": int(time.Until(inst.ExpiresAt).Seconds()),
+	}
+}
+
+// releaseDungeonIfEmpty destroys the instance behind worldID once nobody is
+// inside or on the way in. Shared worlds are ignored.
+func releaseDungeonIfEmpty(worldID WorldID) {
+	if worldID < dungeonInstanceBase {
+		return
+	}
+	dungeonMu.Lock()
+	inst, ok := dungeonInstances[worldID]
+	if !ok || inst.arriving > 0 || countSessionsInWorld(worldID) > 0 {
+		dungeonMu.Unlock()
+		return
+	}
+	delete(dungeonInstances, worldID)
+	if dungeonByParty[inst.PartyID] == worldID {
+		delete(dungeonByParty, inst.PartyID)
+	}
+	dungeonMu.Unlock()
+
+	removeWorldSim(worldID)
+	worldScheduler.CancelWorld(worldID)
+	log.Printf("dungeon instance %d (%s) destroyed", worldID, inst.Dungeon.ID)
+}
+
+// completeDungeon marks the instance cleared and tells everyone inside.
+func completeDungeon(worldID WorldID) {
+	dungeonMu.Lock()
+	inst, ok := dungeonInstances[worldID]
+	if !ok || inst.Completed {
+		dungeonMu.Unlock()
+		return
+	}
+	inst.Completed = true
+	payload := map[string]interface{}{
+		"dungeon_id":     inst.Dungeon.ID,
+		"name":           inst.Dungeon.Name,
+		"instance_id":    inst.ID,
+		"party_id":       inst.PartyID,
+		"cleared_in_sec": int(time.Since(inst.CreatedAt).Seconds()),
+	}
+	dungeonMu.Unlock()
+
+	forEachSessionInWorld(worldID, func(s *ClientSession) {
+		sendMessage(s.Conn, ServerMessage{Command: RespDungeonComplete, Payload: payload})
+	})
+}
+
+// expireDungeonInstances evicts everyone from instances past their time
+// limit back to the dungeon entrance; the last eviction destroys each one.
+func expireDungeonInstances(now time.Time) {
+	dungeonMu.Lock()
+	expired := make([]*DungeonInstance, 0)
+	for _, inst := range dungeonInstances {
+		if !now.Before(inst.ExpiresAt) {
+			expired = append(expired, inst)
+		}
+	}
+	dungeonMu.Unlock()
+
+	for _, inst := range expired {
+		host := worlds[inst.Dungeon.HostWorld]
+		occupants := make([]*ClientSession, 0)
+		forEachSessionInWorld(inst.ID, func(s *ClientSession) {
+			occupants = append(occupants, s)
+		})
+		for _, s := range occupants {
+			sendMessage(s.Conn, ServerMessage{Command: RespDungeonExpired, Payload: map[string]interface{}{
+				"dungeon_id":  inst.Dungeon.ID,
+				"instance_id": inst.ID,
+			}})
+			forceSessionWorld(s, inst.ID, host, inst.Dungeon.Entrance, RespDungeonLeft, map[string]interface{}{
+				"dungeon_id": inst.Dungeon.ID,
+				"reason":     "EXPIRED",
+			})
+		}
+		releaseDungeonIfEmpty(inst.ID)
+	}
+}
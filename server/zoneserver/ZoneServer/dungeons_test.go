@@ -0,0 +1,223 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func resetDungeonStateForTests() {
+	dungeonMu.Lock()
+	dungeonInstances = map[WorldID]*DungeonInstance{}
+	dungeonByParty = map[string]WorldID{}
+	dungeonMu.Unlock()
+}
+
+func formReadyPartyForTests(t *testing.T, leader, member string) {
+	t.Helper()
+	if _, ok, reason := partyInvite(leader, member); !ok {
+		t.Fatalf("party invite failed: %s", reason)
+	}
+	if _, ok, reason := partyAccept(member, leader); !ok {
+		t.Fatalf("party accept failed: %s", reason)
+	}
+	for _, name := range []string{leader, member} {
+		if _, ok, reason := setPartyReady(name, true); !ok {
+			t.Fatalf("party ready failed: %s", reason)
+		}
+	}
+}
+
+func dungeonCommand(t *testing.T, s *ClientSession, visible map[*ClientSession]bool, command string, payload interface{}) {
+	t.Helper()
+	bound := s.Character.Name
+	if handled, _ := handleClientCommand(s.Conn, s, visible, "dungeon-peer", &bound, command, payload); !handled {
+		t.Fatalf("expected %s to be handled", command)
+	}
+}
+
+func TestEnterDungeonAdmitsOnlyTheReadyParty(t *testing.T) {
+	resetSocialStateForTests()
+	resetDungeonStateForTests()
+	worlds = DefaultWorlds()
+
+	entrance := dungeonDefinitions["wolf_den"].Entrance
+	leader, leaderConn := newVisibilityTestSession("DenLead", worlds[World1], entrance)
+	member, memberConn := newVisibilityTestSession("DenMate", worlds[World1], entrance)
+	outsider, outsiderConn := newVisibilityTestSession("DenStranger", worlds[World1], entrance)
+	defer unregisterSession(leader)
+	defer unregisterSession(member)
+	defer unregisterSession(outsider)
+
+	if _, ok, reason := partyInvite("DenLead", "DenMate"); !ok {
+		t.Fatalf("party invite failed: %s", reason)
+	}
+	if _, ok, reason := partyAccept("DenMate", "DenLead"); !ok {
+		t.Fatalf("party accept failed: %s", reason)
+	}
+	leaderConn.DrainMessages(t)
+	memberConn.DrainMessages(t)
+
+	req := map[string]interface{}{"dungeon_id": "wolf_den"}
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(leaderConn.DrainMessages(t), RespDungeonRejected, "PARTY_NOT_READY") {
+		t.Fatalf("expected PARTY_NOT_READY before the ready check passes")
+	}
+	setPartyReady("DenLead", true)
+	setPartyReady("DenMate", true)
+
+	dungeonCommand(t, member, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(memberConn.DrainMessages(t), RespDungeonRejected, "LEADER_MUST_OPEN") {
+		t.Fatalf("expected LEADER_MUST_OPEN for a member opening the instance")
+	}
+
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(leaderConn.DrainMessages(t), RespDungeonEntered, nil) {
+		t.Fatalf("expected leader to enter the dungeon")
+	}
+	if leader.World.InstanceOf != World1 || leader.Character.WorldID != World1 {
+		t.Fatalf("expected instance world hosted by world 1, got world=%#v saved=%d", leader.World, leader.Character.WorldID)
+	}
+
+	dungeonCommand(t, member, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(memberConn.DrainMessages(t), RespDungeonEntered, nil) || member.World.ID != leader.World.ID {
+		t.Fatalf("expected member to join the party instance")
+	}
+
+	dungeonCommand(t, outsider, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(outsiderConn.DrainMessages(t), RespDungeonRejected, "NOT_IN_PARTY") || outsider.World.ID != World1 {
+		t.Fatalf("expected outsider to be kept out of the instance")
+	}
+
+	instanceWolf := worldMobsForTests(leader.World.ID)["mob_den_wolf_01"]
+	if instanceWolf == nil || instanceWolf.WorldID != leader.World.ID || instanceWolf.RespawnSec != 0 {
+		t.Fatalf("expected a private non-respawning mob roster, got %#v", instanceWolf)
+	}
+	// Instance mobs fight like their template, as world mobs do.
+	tmpl := activeSpawnData().Templates["den_wolf"]
+	if instanceWolf.AttackIntervalMS != tmpl.AttackIntervalMS || instanceWolf.Element != tmpl.Element || len(instanceWolf.Abilities) != len(tmpl.Abilities) || instanceWolf.Defense != tmpl.Defense {
+		t.Fatalf("expected the den wolf's combat profile from its template, got %#v", instanceWolf)
+	}
+
+	unregisterSession(leader)
+	unregisterSession(member)
+	if dungeonInstanceFor(instanceWolf.WorldID) != nil {
+		t.Fatalf("expected empty instance to be destroyed")
+	}
+	if worldSimFor(instanceWolf.WorldID) != nil {
+		t.Fatalf("expected instance mob roster to be removed")
+	}
+}
+
+func TestClearingDungeonReportsCompletion(t *testing.T) {
+	resetSocialStateForTests()
+	resetDungeonStateForTests()
+	worlds = DefaultWorlds()
+
+	// Killing a mob shares party XP, which persists the party members.
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	entrance := dungeonDefinitions["wolf_den"].Entrance
+	leader, leaderConn := newVisibilityTestSession("ClearLead", worlds[World1], entrance)
+	member, memberConn := newVisibilityTestSession("ClearMate", worlds[World1], entrance)
+	defer unregisterSession(leader)
+	defer unregisterSession(member)
+	formReadyPartyForTests(t, "ClearLead", "ClearMate")
+
+	req := map[string]interface{}{"dungeon_id": "wolf_den"}
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	dungeonCommand(t, member, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	instanceID := leader.World.ID
+
+	for _, mob := range worldMobsForTests(instanceID) {
+		mob.HP = 1
+		mob.Position = leader.Position
+	}
+	leaderConn.DrainMessages(t)
+	memberConn.DrainMessages(t)
+
+	withFixedRandIntn(9999, func() {
+		if _, ok, reason := attackMob(leader, "mob_den_wolf_01", ""); !ok {
+			t.Fatalf("attackMob failed: %s", reason)
+		}
+		if hasMessage(memberConn.DrainMessages(t), RespDungeonComplete, nil) {
+			t.Fatalf("expected no completion while mobs remain")
+		}
+		if _, ok, reason := attackMob(leader, "mob_den_warden_01", ""); !ok {
+			t.Fatalf("attackMob failed: %s", reason)
+		}
+	})
+	if !hasMessage(leaderConn.DrainMessages(t), RespDungeonComplete, nil) || !hasMessage(memberConn.DrainMessages(t), RespDungeonComplete, nil) {
+		t.Fatalf("expected DUNGEON_COMPLETE for every member inside")
+	}
+
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqLeaveDungeon, nil)
+	if leader.World.ID != World1 || !hasMessage(leaderConn.DrainMessages(t), RespDungeonLeft, nil) {
+		t.Fatalf("expected leader back in the host world")
+	}
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqEnterDungeon, req)
+	if !hasMessage(leaderConn.DrainMessages(t), RespDungeonRejected, "DUNGEON_CLEARED") || leader.World.ID != World1 {
+		t.Fatalf("expected a cleared instance to refuse re-entry")
+	}
+	if dungeonInstanceFor(instanceID) == nil {
+		t.Fatalf("expected instance to survive while a member remains")
+	}
+	dungeonCommand(t, member, map[*ClientSession]bool{}, ReqLeaveDungeon, nil)
+	if dungeonInstanceFor(instanceID) != nil {
+		t.Fatalf("expected instance destroyed once the last member left")
+	}
+}
+
+func TestExpiredDungeonEvictsMembers(t *testing.T) {
+	resetSocialStateForTests()
+	resetDungeonStateForTests()
+	worlds = DefaultWorlds()
+
+	entrance := dungeonDefinitions["wolf_den"].Entrance
+	leader, leaderConn := newVisibilityTestSession("LateLead", worlds[World1], entrance)
+	member, memberConn := newVisibilityTestSession("LateMate", worlds[World1], entrance)
+	defer unregisterSession(leader)
+	defer unregisterSession(member)
+	formReadyPartyForTests(t, "LateLead", "LateMate")
+
+	dungeonCommand(t, leader, map[*ClientSession]bool{}, ReqEnterDungeon, map[string]interface{}{"dungeon_id": "wolf_den"})
+	instanceID := leader.World.ID
+	leaderConn.DrainMessages(t)
+
+	expireDungeonInstances(time.Now())
+	if leader.World.ID != instanceID {
+		t.Fatalf("expected instance to stay open before its time limit")
+	}
+
+	// Between the deadline and the next sweep nobody else may join.
+	inst := dungeonInstanceFor(instanceID)
+	dungeonMu.Lock()
+	inst.ExpiresAt = time.Now().Add(-time.Second)
+	dungeonMu.Unlock()
+	dungeonCommand(t, member, map[*ClientSession]bool{}, ReqEnterDungeon, map[string]interface{}{"dungeon_id": "wolf_den"})
+	if !hasMessage(memberConn.DrainMessages(t), RespDungeonRejected, "DUNGEON_EXPIRED") || member.World.ID != World1 {
+		t.Fatalf("expected an expired instance to refuse a late arrival")
+	}
+
+	expireDungeonInstances(time.Now())
+	msgs := leaderConn.DrainMessages(t)
+	if !hasMessage(msgs, RespDungeonExpired, nil) || !hasMessage(msgs, RespDungeonLeft, nil) {
+		t.Fatalf("expected DUNGEON_EXPIRED and DUNGEON_LEFT, got %#v", msgs)
+	}
+	if leader.World.ID != World1 || leader.Position != entrance {
+		t.Fatalf("expected leader evicted to the entrance, got world=%d pos=%#v", leader.World.ID, leader.Position)
+	}
+	if dungeonInstanceFor(instanceID) != nil {
+		t.Fatalf("expected expired instance to be destroyed")
+	}
+}
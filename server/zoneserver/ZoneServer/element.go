@@ -0,0 +1,83 @@
+package main
+
+// Element effectiveness labels reported with attack results.
+const (
+	EffectivenessStrong  = "strong"
+	EffectivenessNeutral = "neutral"
+	EffectivenessWeak    = "weak"
+)
+
+const (
+	affinityStrongPct = 150
+	affinityWeakPct   = 75
+)
+
+// elementAffinity[attacker][defender] is the damage percentage an attack of
+// one element deals to a target of another; missing pairs are 100. The four
+// natural elements form a cycle (Fire > Ice > Earth > Lightning > Fire) and
+// Light and Dark are each strong against the other.
+var elementAffinity = map[Element]map[Element]int{
+	ElementFire:      {ElementIce: affinityStrongPct, ElementLightning: affinityWeakPct},
+	ElementIce:       {ElementEarth: affinityStrongPct, ElementFire: affinityWeakPct},
+	ElementEarth:     {ElementLightning: affinityStrongPct, ElementIce: affinityWeakPct},
+	ElementLightning: {ElementFire: affinityStrongPct, ElementEarth: affinityWeakPct},
+	ElementLight:     {ElementDark: affinityStrongPct},
+	ElementDark:      {ElementLight: affinityStrongPct},
+}
+
+// elementalPct is the damage percentage for an attack element against a
+// defender's element and resistances (percent, negative for a weakness).
+func elementalPct(attack, defense Element, resist map[Element]int) int {
+	if attack == "" || attack == ElementNone {
+		return 100
+	}
+	pct := 100
+	if v, ok := elementAffinity[attack][defense]; ok {
+		pct = v
+	}
+	return pct * (100 - resist[attack]) / 100
+}
+
+func effectivenessLabel(pct int) string {
+	switch {
+	case pct > 100:
+		return EffectivenessStrong
+	case pct < 100:
+		return EffectivenessWeak
+	default:
+		return EffectivenessNeutral
+	}
+}
+
+// applyElement scales damage by pct, keeping hits at least 1.
+func applyElement(damage, pct int) int {
+	return maxInt(damage*pct/100, 1)
+}
+
+// attackElement is the element a character strikes with: the weapon
+// attunement from SET_ELEMENT, else the equipped weapon's own element.
+func attackElement(c *Character) Element {
+	return gearElement(c, "weapon", SlotWeapon)
+}
+
+// armorElement is the element a character defends with, chosen the same
+// way from the armor attunement and equipped armor.
+func armorElement(c *Character) Element {
+	return gearElement(c, "armor", SlotArmor)
+}
+
+func gearElement(c *Character, attunement, slot string) Element {
+	if e := c.Elemental[attunement]; e != "" && e != ElementNone {
+		return e
+	}
+	for _, item := range c.Inventory {
+		if item.Slot == slot && c.Equipped[slot] == item.ID && item.Element != "" {
+			return item.Element
+		}
+	}
+	return ElementNone
+}
+
+func validElement(e Element) bool {
+	return e == "" || canonicalElement(string(e)) == e
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestElementAffinityMatrix(t *testing.T) {
+	cases := []struct {
+		attack, defense Element
+		resist          map[Element]int
+		want            int
+	}{
+		{ElementFire, ElementIce, nil, 150},
+		{ElementIce, ElementFire, nil, 75},
+		{ElementLight, ElementDark, nil, 150},
+		{ElementDark, ElementLight, nil, 150},
+		{ElementFire, ElementFire, nil, 100},
+		{ElementNone, ElementIce, map[Element]int{ElementNone: 50}, 100},
+		{ElementFire, ElementIce, map[Element]int{ElementFire: -20}, 180},
+		{ElementDark, ElementDark, map[Element]int{ElementDark: 75}, 25},
+	}
+	for _, tc := range cases {
+		if got := elementalPct(tc.attack, tc.defense, tc.resist); got != tc.want {
+			t.Fatalf("%s vs %s (%v): expected %d%%, got %d%%", tc.attack, tc.defense, tc.resist, tc.want, got)
+		}
+	}
+	if effectivenessLabel(150) != EffectivenessStrong || effectivenessLabel(100) != EffectivenessNeutral || effectivenessLabel(25) != EffectivenessWeak {
+		t.Fatalf("unexpected effectiveness labels")
+	}
+
+	c := MockCharacter()
+	ensureCharacterDefaults(c)
+	c.Inventory[0].Element = ElementEarth
+	if got := attackElement(c); got != ElementEarth {
+		t.Fatalf("expected the weapon's own element, got %s", got)
+	}
+	c.Elemental["weapon"] = ElementLight
+	if got := attackElement(c); got != ElementLight {
+		t.Fatalf("expected the SET_ELEMENT attunement to take precedence, got %s", got)
+	}
+}
+
+func TestElementsScaleMobAndPlayerDamage(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	player, playerConn := newVisibilityTestSession("Pyro", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(player)
+	mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+	mob.HP, mob.MaxHP = 10000, 10000
+	mob.Element = ElementIce
+	mob.Resistances = map[Element]int{ElementIce: 50}
+
+	withFixedRandIntn(0, func() {
+		withWorldMob(World1, mob.ID, mob, func() {
+			neutral, _, _ := attackMob(player, mob.ID, "")
+			player.Character.Elemental["weapon"] = ElementFire
+			strong, _, _ := attackMob(player, mob.ID, "")
+			if strong["effectiveness"] != EffectivenessStrong || toInt(strong, "damage") != toInt(neutral, "damage")*150/100 {
+				t.Fatalf("expected Fire to hit Ice for 150%%, got %#v vs %#v", strong, neutral)
+			}
+			player.Character.Elemental["weapon"] = ElementIce
+			if resisted, _, _ := attackMob(player, mob.ID, ""); resisted["effectiveness"] != EffectivenessWeak {
+				t.Fatalf("expected the mob to resist its own element, got %#v", resisted)
+			}
+
+			// Fire-attuned armor shrugs off the Ice mob's swings.
+			player.Character.HP, player.Character.MaxHP = 1000, 1000
+			player.Character.Elemental["armor"] = ElementFire
+			playerConn.DrainMessages(t)
+			var outbox mobOutbox
+			mobHitSession(worldSimFor(World1), mob, player, 100, "", 0, nil, &outbox)
+			outbox.flush()
+			if player.Character.HP != 1000-75 {
+				t.Fatalf("expected Ice to deal 75%% to Fire armor, HP is %d", player.Character.HP)
+			}
+		})
+	})
+}
@@ -1,28 +1,21 @@
 package main
 
-import (
-	"log"
-	"time"
-)
+import "time"
 
+// initWorldEntities spawns the mobs of every world this node owns, each
+// under its own worldSim. Later calls are no-ops.
 func initWorldEntities() {
-	mobMu.Lock()
-	defer mobMu.Unlock()
-	if worldMobs != nil {
+	simsMu.Lock()
+	defer simsMu.Unlock()
+	if worldSims != nil {
 		return
 	}
 
-	worldMobs = map[WorldID]map[string]*MobEntity{
-		World1: {
-			"mob_wolf_01":   {ID: "mob_wolf_01", Name: "Rift Wolf", WorldID: World1, Level: 42, HP: 210, MaxHP: 210, Position: Position{X: 112, Y: 0, Z: 108}, RespawnSec: 8},
-			"mob_bandit_01": {ID: "mob_bandit_01", Name: "Dust Bandit", WorldID: World1, Level: 46, HP: 245, MaxHP: 245, Position: Position{X: 118, Y: 0, Z: 110}, RespawnSec: 9},
-		},
-		World2: {
-			"mob_shard_01": {ID: "mob_shard_01", Name: "Shard Revenant", WorldID: World2, Level: 62, HP: 360, MaxHP: 360, Position: Position{X: 510, Y: 0, Z: 507}, RespawnSec: 10},
-		},
-		World3: {
-			"mob_myth_01": {ID: "mob_myth_01", Name: "Mythic Devourer", WorldID: World3, Level: 112, HP: 680, MaxHP: 680, Position: Position{X: 1012, Y: 0, Z: 1009}, RespawnSec: 12},
-		},
+	worldSims = map[WorldID]*worldSim{}
+	for worldID, mobs := range spawnWorldMobs(activeSpawnData()) {
+		if ownsWorld(worldID) {
+			worldSims[worldID] = newWorldSim(worldID, mobs)
+		}
 	}
 }
 
@@ -36,16 +29,22 @@ func listNearbyEntities(s *ClientSession) map[string]interface{} {
 		}
 	}
 
-	mobMu.RLock()
-	defer mobMu.RUnlock()
 	mobs := make([]MobEntity, 0)
-	for _, mob := range worldMobs[s.World.ID] {
-		if mob.HP <= 0 {
-			continue
-		}
-		if isVisible(s.Position, mob.Position) {
-			mobs = append(mobs, *mob)
-		}
+	if w := worldSimFor(s.World.ID); w != nil {
+		w.do(func(w *worldSim, _ *mobOutbox) {
+			for _, mob := range w.mobs {
+				if mob.HP <= 0 {
+					continue
+				}
+				if isVisible(s.Position, mob.Position) {
+					snapshot := *mob
+					if mob.boss != nil {
+						snapshot.Boss = mob.boss.status()
+					}
+					mobs = append(mobs, snapshot)
+				}
+			}
+		})
 	}
 
 	return map[string]interface{}{
@@ -55,49 +54,199 @@ func listNearbyEntities(s *ClientSession) map[string]interface{} {
 	}
 }
 
+// attackMob resolves one player attack on the world simulation that owns
+// the mob.
 func attackMob(s *ClientSession, mobID, skillID string) (map[string]interface{}, bool, string) {
+	return attackMobAt(s, mobID, skillID, nil)
+}
+
+// attackMobAt is attackMob with the position a ground-targeted skill is
+// aimed at.
+func attackMobAt(s *ClientSession, mobID, skillID string, ground *Position) (map[string]interface{}, bool, string) {
 	initWorldEntities()
+	if sessionStunned(s) {
+		return nil, false, "STUNNED"
+	}
 
-	mobMu.Lock()
-	defer mobMu.Unlock()
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return nil, false, "MOB_NOT_FOUND"
+	}
+	var result map[string]interface{}
+	ok, reason := false, "MOB_NOT_FOUND"
+	w.doFor(s, func(w *worldSim, outbox *mobOutbox) {
+		def, skilled := learnedSkill(s, skillID)
+		if _, area := areaSkill(s, skillID); area {
+			_, reason = areaTargetsLocked(w, s, def, mobID, ground)
+		} else {
+			_, reason = attackableMobLocked(w, s, mobID)
+		}
+		if reason != "OK" {
+			return
+		}
+		if sessionCasting(s) {
+			reason = "ALREADY_CASTING"
+			return
+		}
+		if skilled && def.CastMS > 0 {
+			result, ok, reason = startSkillCast(w, s, mobID, ground, def, simTickClock())
+			return
+		}
+		if skilled {
+			if ok, reason = useSkill(s, def, simTickClock()); !ok {
+				return
+			}
+		}
+		result, ok, reason = landSkillLocked(w, outbox, s, mobID, skillID, ground)
+		if ok && skilled {
+			result["resource"] = skillResourcePayload(s)
+		}
+	})
+	return result, ok, reason
+}
 
-	worldMap := worldMobs[s.World.ID]
-	mob, ok := worldMap[mobID]
+// attackableMobLocked returns the mob s may attack, or why it can't.
+func attackableMobLocked(w *worldSim, s *ClientSession, mobID string) (*MobEntity, string) {
+	mob, ok := w.mobs[mobID]
 	if !ok {
-		return nil, false, "MOB_NOT_FOUND"
+		return nil, "MOB_NOT_FOUND"
 	}
 	if !isVisible(s.Position, mob.Position) {
-		return nil, false, "MOB_OUT_OF_RANGE"
+		return nil, "MOB_OUT_OF_RANGE"
 	}
 	if mob.HP <= 0 {
-		return nil, false, "MOB_ALREADY_DEFEATED"
+		return nil, "MOB_ALREADY_DEFEATED"
+	}
+	if mob.AIState == MobStateReturn {
+		return nil, "MOB_EVADING"
+	}
+	return mob, "OK"
+}
+
+// attackMobInWorld resolves a hit on mobID; any skill has already been paid
+// for. The mob side lands at once; the attacker's death penalty, heal, XP
+// and loot are posted to their action path (see mobOutbox.post) and fill in
+// the returned result when they run.
+func attackMobInWorld(w *worldSim, outbox *mobOutbox, s *ClientSession, mobID, skillID string) (map[string]interface{}, bool, string) {
+	worldMap := w.mobs
+	mob, reason := attackableMobLocked(w, s, mobID)
+	if mob == nil {
+		return nil, false, reason
+	}
+
+	riskLevel := mob.Level
+	if mob.Training {
+		riskLevel = minInt(riskLevel, s.Character.Level) // practice is never deadly
+	}
+	attack := calculateAttack(s.Character, riskLevel, mobEvasion(mob))
+	bonus := skillBonus(s.Character, skillID)
+	element := attackElement(s.Character)
+	elementPct := elementalPct(element, mob.Element, mob.Resistances)
+	damage := 0
+	if attack.Landed() {
+		damage = damageMob(mob, applyElement(attack.Damage+bonus, elementPct))
 	}
 
-	damage, died := calculateAttack(s.Character, mob.Level)
-	damage += skillBonus(s.Character, skillID)
-	mob.HP -= damage
+	if attack.Died {
+		clearPlayerThreatLocked(w, s.Character.Name)
+		result := map[string]interface{}{
+			"mob":         mob.Name,
+			"status":      "PLAYER_DIED",
+			"skill_id":    skillID,
+			"outcome":     attack.Outcome,
+			"attack":      attack,
+			"threat_rank": 0,
+		}
+		at := s.Position
+		outbox.post(s, func() {
+			applyDeathPenalty(s.Character, at)
+			result["xp_debt"] = s.Character.XPDebt
+			result["corpse"] = s.Character.Corpse
+		})
+		return result, true, "OK"
+	}
 
-	if died {
-		applyDeathPenalty(s.Character, s.Position)
-		return map[string]interface{}{
-			"mob":      mob.Name,
-			"status":   "PLAYER_DIED",
-			"xp_debt":  s.Character.XPDebt,
-			"corpse":   s.Character.Corpse,
-			"skill_id": skillID,
-		}, true, "OK"
+	if mob.Training {
+		if mob.HP <= 0 {
+			mob.HP = mob.MaxHP // training dummies never fall
+		}
+	} else {
+		addThreatLocked(mob, s.Character.Name, damage)
+	}
+	if mob.boss != nil {
+		recordBossDamageLocked(mob, s.Character.Name, damage, outbox)
+	}
+	landed := attack.Landed() && bonus > 0
+	if landed && tauntSkills[skillID] && !mob.Training {
+		tauntMobLocked(mob, s.Character.Name)
+	}
+	if mob.targetName == "" && !mob.Training {
+		mob.targetName = s.Character.Name
+	}
+	healed := 0
+	if landed && healingSkills[skillID] {
+		healed = minInt(bonus, maxInt(s.Character.MaxHP-s.Character.HP, 0))
+		outbox.post(s, func() {
+			s.Character.HP = minInt(s.Character.HP+healed, s.Character.MaxHP)
+		})
+		recordHealingThreatLocked(w, s.Character.Name, s.Character.Name, healed)
+	}
+	if _, skilled := learnedSkill(s, skillID); skilled && attack.Landed() {
+		target := mob
+		if mob.HP <= 0 {
+			target = nil
+		}
+		applySkillEffect(s, skillID, target, nil, simTickClock(), outbox)
 	}
 
 	result := map[string]interface{}{
-		"mob_id":    mob.ID,
-		"mob":       mob.Name,
-		"mob_hp":    maxInt(mob.HP, 0),
-		"damage":    damage,
-		"skill_id":  skillID,
-		"defeated":  false,
-		"xp_gain":   0,
-		"legendary": nil,
-		"drops":     []map[string]interface{}{},
+		"mob_id":        mob.ID,
+		"mob":           mob.Name,
+		"mob_hp":        maxInt(mob.HP, 0),
+		"damage":        damage,
+		"element":       element,
+		"effectiveness": effectivenessLabel(elementPct),
+		"skill_id":      skillID,
+		"outcome":       attack.Outcome,
+		"attack":        attack,
+		"defeated":      false,
+		"xp_gain":       0,
+		"legendary":     nil,
+		"drops":         []map[string]interface{}{},
+		"healed":        healed,
+		"threat_rank":   threatRankLocked(mob, s.Character.Name),
+	}
+	if mob.Training {
+		result["training"] = true
+	}
+
+	if mob.HP > 0 && attack.Landed() {
+		outbox.event(mob, RespMobDamaged, map[string]interface{}{
+			"mob_id":   mob.ID,
+			"hp":       mob.HP,
+			"max_hp":   mob.MaxHP,
+			"damage":   damage,
+			"attacker": s.Character.Name,
+		}, s)
+	}
+
+	if mob.HP <= 0 && mob.boss != nil {
+		award := awardWorldBossLocked(w, mob, s, outbox)
+		mob.HP = 0
+		result["defeated"] = true
+		result["xp_gain"] = award["xp_gain"]
+		result["boss"] = award
+		outbox.post(s, func() { // after the award itself lands
+			result["leveled_up"] = award["leveled_up"]
+			result["drops"] = award["drops"]
+		})
+		outbox.event(mob, RespMobDied, map[string]interface{}{
+			"mob_id":      mob.ID,
+			"name":        mob.Name,
+			"killer":      s.Character.Name,
+			"respawn_sec": 0,
+		}, s)
+		return result, true, "OK"
 	}
 
 	if mob.HP <= 0 {
@@ -107,53 +256,90 @@ func attackMob(s *ClientSession, mobID, skillID string) (map[string]interface{},
 		if partyBonusPct > 0 {
 			xpGain += (xpGain * partyBonusPct) / 100
 		}
-		leveled := gainXP(s.Character, xpGain)
-		drops := rollLootForMob(s.Character, mob.ID)
-		if len(nearbyParty) > 0 {
-			drops = applyPartyLootBonus(s.Character, drops)
-		}
-		drop := maybeLegendaryDrop(s.Character)
-		if drop != nil {
-			drops = append(drops, map[string]interface{}{
-				"kind":    lootKindGear,
-				"item_id": drop.ID,
-				"qty":     1,
-				"item":    *drop,
-			})
-		}
 		result["defeated"] = true
 		result["xp_gain"] = xpGain
-		result["leveled_up"] = leveled
-		result["drops"] = drops
-		result["legendary"] = drop
 		result["party_bonus_pct"] = partyBonusPct
+		lootTable := mob.LootTable
+		outbox.post(s, func() {
+			result["leveled_up"] = gainXP(s.Character, xpGain)
+			drops := rollLootForMob(s.Character, lootTable)
+			if len(nearbyParty) > 0 {
+				drops = applyPartyLootBonus(s.Character, drops)
+			}
+			drop := maybeLegendaryDrop(s.Character)
+			if drop != nil {
+				drops = append(drops, map[string]interface{}{
+					"kind":    lootKindGear,
+					"item_id": drop.ID,
+					"qty":     1,
+					"item":    *drop,
+				})
+			}
+			result["drops"] = drops
+			result["legendary"] = drop
+		})
 		if len(nearbyParty) > 0 {
-			result["party_xp_shared"] = sharePartyXP(s, nearbyParty, xpGain)
+			result["party_xp_shared"] = sharePartyXP(outbox, nearbyParty, xpGain)
 		}
 
 		respawnAfter := time.Duration(mob.RespawnSec) * time.Second
 		mob.HP = 0
-		go respawnMob(s.World.ID, mob.ID, respawnAfter)
+		resetMobThreatLocked(mob)
+		outbox.event(mob, RespMobDied, map[string]interface{}{
+			"mob_id":      mob.ID,
+			"name":        mob.Name,
+			"killer":      s.Character.Name,
+			"respawn_sec": mob.RespawnSec,
+		}, s)
+		if mob.RespawnSec > 0 {
+			scheduleMobRespawn(s.World.ID, mob.ID, respawnAfter)
+		}
+		if s.World.InstanceOf != 0 && allMobsDefeatedLocked(worldMap) {
+			instanceID := s.World.ID
+			outbox.then(func() { completeDungeon(instanceID) })
+		}
 	}
 
 	return result, true, "OK"
 }
 
-func respawnMob(worldID WorldID, mobID string, wait time.Duration) {
-	time.Sleep(wait)
-	mobMu.Lock()
-	defer mobMu.Unlock()
-	worldMap, ok := worldMobs[worldID]
-	if !ok {
-		return
+func allMobsDefeatedLocked(worldMap map[string]*MobEntity) bool {
+	for _, mob := range worldMap {
+		if mob.HP > 0 {
+			return false
+		}
 	}
-	mob, ok := worldMap[mobID]
-	if !ok {
+	return true
+}
+
+// respawnMob brings a dead mob back at its anchor. It runs as the
+// eventMobRespawn handler on the world scheduler.
+func respawnMob(worldID WorldID, mobID string) {
+	w := worldSimFor(worldID)
+	if w == nil {
 		return
 	}
+	w.do(func(w *worldSim, outbox *mobOutbox) {
+		if mob, ok := w.mobs[mobID]; ok {
+			respawnMobInWorld(mob, outbox)
+		}
+	})
+}
+
+func respawnMobInWorld(mob *MobEntity, outbox *mobOutbox) {
+	ensureMobAI(mob)
 	mob.HP = mob.MaxHP
+	mob.Position = mob.Spawn
 	mob.Position.X += float64(randIntn(5) - 2)
 	mob.Position.Z += float64(randIntn(5) - 2)
+	mob.AIState = MobStateIdle
+	mob.path = nil
+	mob.nextAttackAt = time.Time{}
+	mob.abilityReadyAt = nil
+	mob.effects = nil
+	resetMobThreatLocked(mob)
+	mob.lastSentPos = mob.Position
+	outbox.event(mob, RespMobSpawned, mobSnapshotPayload(mob), nil)
 }
 
 func applyPartyLootBonus(c *Character, drops []map[string]interface{}) []map[string]interface{} {
@@ -174,21 +360,22 @@ func applyPartyLootBonus(c *Character, drops []map[string]interface{}) []map[str
 	return drops
 }
 
-func sharePartyXP(attacker *ClientSession, nearby []*ClientSession, killerXP int) []map[string]interface{} {
+// sharePartyXP gives each nearby party member half the killer's XP, on the
+// member's own action path.
+func sharePartyXP(outbox *mobOutbox, nearby []*ClientSession, killerXP int) []map[string]interface{} {
 	shared := make([]map[string]interface{}, 0, len(nearby))
 	shareXP := killerXP / 2
 	if shareXP < 1 {
 		shareXP = 1
 	}
 	for _, member := range nearby {
-		leveled := gainXP(member.Character, shareXP)
-		if err := persistCharacter(member.Character); err != nil {
-			log.Printf("Failed to persist party member %s after shared XP: %v", member.Character.Name, err)
-		}
+		outbox.post(member, func() {
+			gainXP(member.Character, shareXP)
+			queuePersistCharacter(member.Character, "shared XP")
+		})
 		shared = append(shared, map[string]interface{}{
-			"name":       member.Character.Name,
-			"xp_gain":    shareXP,
-			"leveled_up": leveled,
+			"name":    member.Character.Name,
+			"xp_gain": shareXP,
 		})
 	}
 	return shared
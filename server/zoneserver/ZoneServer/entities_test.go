@@ -3,22 +3,32 @@ package main
 import "testing"
 
 func withWorldMob(worldID WorldID, mobID string, mob *MobEntity, fn func()) {
-	mobMu.Lock()
-	orig := worldMobs
-	worldMobs = map[WorldID]map[string]*MobEntity{
-		worldID: {
+	simsMu.Lock()
+	orig := worldSims
+	worldSims = map[WorldID]*worldSim{
+		worldID: newWorldSim(worldID, map[string]*MobEntity{
 			mobID: mob,
-		},
+		}),
 	}
-	mobMu.Unlock()
+	simsMu.Unlock()
 	defer func() {
-		mobMu.Lock()
-		worldMobs = orig
-		mobMu.Unlock()
+		simsMu.Lock()
+		worldSims = orig
+		simsMu.Unlock()
 	}()
 	fn()
 }
 
+// worldMobsForTests returns the live mob map of worldID, or nil when the
+// world has no simulation. The world loops are not running in tests, so
+// callers may touch the mobs directly.
+func worldMobsForTests(worldID WorldID) map[string]*MobEntity {
+	if w := worldSimFor(worldID); w != nil {
+		return w.mobs
+	}
+	return nil
+}
+
 func TestAttackMobDefeatReturnsDropsAndLegendary(t *testing.T) {
 	resetSocialStateForTests()
 
@@ -34,6 +44,7 @@ func TestAttackMobDefeatReturnsDropsAndLegendary(t *testing.T) {
 	withWorldMob(World1, "mob_wolf_01", &MobEntity{
 		ID:         "mob_wolf_01",
 		Name:       "Rift Wolf",
+		LootTable:  "rift_wolf",
 		WorldID:    World1,
 		Level:      42,
 		HP:         1,
@@ -88,6 +99,7 @@ func TestAttackMobNonDefeatKeepsAdditiveDropFields(t *testing.T) {
 	withWorldMob(World1, "mob_wolf_01", &MobEntity{
 		ID:         "mob_wolf_01",
 		Name:       "Rift Wolf",
+		LootTable:  "rift_wolf",
 		WorldID:    World1,
 		Level:      42,
 		HP:         9999,
@@ -63,17 +63,29 @@ func main() {
 	log.Println("=================================")
 
 	worlds = DefaultWorlds()
+	configureSharding(cfg)
+	if err := configureSpawnData(cfg.SpawnDir); err != nil {
+		log.Fatalf("Invalid spawn data: %v", err)
+	}
 	initWorldEntities()
+	if n, err := restoreSchedulerState(cfg.SchedulerStatePath); err != nil {
+		log.Printf("Could not restore scheduled events: %v", err)
+	} else if n > 0 {
+		log.Printf("Restored %d scheduled events from %s", n, cfg.SchedulerStatePath)
+	}
+	scheduleWorldBosses()
 
+	log.Printf("Node %s (%s)", localNode.ID, localNode.PublicAddr)
 	log.Println("World status:")
 	for _, w := range worlds {
-		log.Printf(" - %s (Lv %d-%d) | Unlocked=%v | AuraRequired=%v", w.Name, w.MinLevel, w.MaxLevel, w.Unlocked, w.RequiresAura)
+		log.Printf(" - %s (Lv %d-%d) | Unlocked=%v | AuraRequired=%v | Owned=%v", w.Name, w.MinLevel, w.MaxLevel, w.Unlocked, w.RequiresAura, ownsWorld(w.ID))
 	}
 
 	listenAddr := fmt.Sprintf(":%d", cfg.ListenPort)
 
 	// Init Redis Pub/Sub bus (graceful fallback if unavailable)
 	InitRedis()
+	advertiseWorldOwnership()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	sigChan := make(chan os.Signal, 1)
@@ -81,6 +93,7 @@ func main() {
 
 	mux := http.NewServeMux()
 	registerHealthEndpoints(mux)
+	registerAdminEndpoints(mux, cfg.AdminToken)
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -100,15 +113,20 @@ func main() {
 	}()
 
 	tickRate := time.Duration(cfg.TickRateMS) * time.Millisecond
+	startCharacterPersister()
+	startWorldSims(tickRate)
 	ticker := time.NewTicker(tickRate)
 	presenceTicker := time.NewTicker(30 * time.Second)
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
-				processServerTick()
+				expireDungeonInstances(time.Now())
+				runScheduledEvents()
 			case <-presenceTicker.C:
 				refreshRedisPresence()
+				advertiseWorldOwnership()
+				pruneSkillStates(simTickClock())
 			case <-ctx.Done():
 				return
 			}
@@ -119,6 +137,12 @@ func main() {
 	cancel()
 	ticker.Stop()
 	presenceTicker.Stop()
+	stopWorldSims()
+	stopCharacterPersister()
+	if err := saveSchedulerState(cfg.SchedulerStatePath); err != nil {
+		log.Printf("Could not save scheduled events: %v", err)
+	}
+	withdrawWorldOwnership()
 	server.Shutdown(context.Background())
 	log.Println("ZoneServer shut down cleanly")
 }
@@ -138,7 +162,7 @@ func handleClient(conn *websocket.Conn) {
 	ensureCharacterDefaults(character)
 
 	session.Character = character
-	session.World = worlds[World1]
+	moveSessionToWorld(session, worlds[World1])
 	session.Position = DefaultSpawnPosition(World1)
 	boundName := ""
 	defer func() {
@@ -150,6 +174,8 @@ func handleClient(conn *websocket.Conn) {
 		if !session.Authenticated {
 			return
 		}
+		session.actionMu.Lock()
+		defer session.actionMu.Unlock()
 		if err := persistSessionState(session); err != nil {
 			log.Printf("Failed to persist character %s: %v", session.Character.Name, err)
 		}
@@ -158,6 +184,8 @@ func handleClient(conn *websocket.Conn) {
 	sendMessage(conn, ServerMessage{Command: RespAuthRequired, Payload: MsgLoginRequired})
 
 	visible := make(map[*ClientSession]bool)
+	session.visible = visible
+	session.serving.Store(true)
 
 	for session.Active {
 		if !session.Authenticated {
@@ -192,22 +220,22 @@ func handleClient(conn *websocket.Conn) {
 			}
 		}
 
+		// The save happens under actionMu too, so character work posted by
+		// world loops can't change the character while it is encoded.
+		session.actionMu.Lock()
 		handled, modified := handleClientCommand(conn, session, visible, peerKey, &boundName, cmd, msg.Payload)
-		if !handled {
-			sendMessage(conn, ServerMessage{Command: RespError, Payload: MsgUnknownCommand})
-			continue
-		}
-
-		if modified {
+		if handled && modified {
 			if err := persistSessionState(session); err != nil {
 				log.Printf("Failed to persist character %s: %v", session.Character.Name, err)
 			}
 		}
+		session.actionMu.Unlock()
+		if !handled {
+			sendMessage(conn, ServerMessage{Command: RespError, Payload: MsgUnknownCommand})
+		}
 	}
 
-	for other := range visible {
-		sendMessage(other.Conn, ServerMessage{Command: RespPlayerLeft, Payload: session.Character.Name})
-	}
+	leaveOnDisconnect(session, visible)
 
 	log.Printf("Client disconnected: %s", remoteAddrStr)
 }
@@ -267,8 +295,8 @@ func toInt(m map[string]interface{}, key string) int {
 }
 
 func syncInitialVisibility(session *ClientSession, visible map[*ClientSession]bool) {
-	forEachSession(func(other *ClientSession) {
-		if other == session || other.World.ID != session.World.ID {
+	forEachSessionInWorld(session.World.ID, func(other *ClientSession) {
+		if other == session {
 			return
 		}
 		if isVisible(other.Position, session.Position) {
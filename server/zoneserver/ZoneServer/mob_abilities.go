@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Mob ability kinds.
+const (
+	MobAbilityAoE      = "aoe"       // damages every player within Radius of the mob
+	MobAbilityStun     = "stun"      // damages and stuns the mob's target for StunMS
+	MobAbilityHealSelf = "heal_self" // restores HealPct of max HP once below BelowHPPct
+)
+
+// Defaults for mobs built without a template combat profile (dungeon rosters
+// and tests): one swing a second for Level..3*Level damage.
+const (
+	defaultMobAttackInterval = time.Second
+	defaultMobHealBelowPct   = 50
+
+	// maxMobSwingsPerTick bounds catch-up swings when the tick rate is slower
+	// than a mob's attack interval.
+	maxMobSwingsPerTick = 4
+)
+
+// MobAbility is a special attack a mob uses in place of a basic swing when
+// its cooldown, measured in real time, has elapsed.
+type MobAbility struct {
+	ID         string  `json:"id"`
+	Kind       string  `json:"kind"`
+	CooldownMS int     `json:"cooldown_ms"`
+	Radius     float64 `json:"radius,omitempty"`
+	DamageMin  int     `json:"damage_min,omitempty"`
+	DamageMax  int     `json:"damage_max,omitempty"`
+	StunMS     int     `json:"stun_ms,omitempty"`
+	HealPct    int     `json:"heal_pct,omitempty"`
+	BelowHPPct int     `json:"below_hp_pct,omitempty"`
+}
+
+func validateMobAbilities(t MobTemplate) []string {
+	problems := make([]string, 0)
+	seen := map[string]bool{}
+	for _, a := range t.Abilities {
+		if a.ID == "" {
+			problems = append(problems, fmt.Sprintf("mob template %q has an ability without id", t.ID))
+			continue
+		}
+		if seen[a.ID] {
+			problems = append(problems, fmt.Sprintf("mob template %q has duplicate ability %q", t.ID, a.ID))
+		}
+		seen[a.ID] = true
+		if a.CooldownMS <= 0 {
+			problems = append(problems, fmt.Sprintf("mob ability %q needs a positive cooldown_ms", a.ID))
+		}
+		switch a.Kind {
+		case MobAbilityAoE, MobAbilityStun:
+			if a.DamageMin <= 0 || a.DamageMax < a.DamageMin {
+				problems = append(problems, fmt.Sprintf("mob ability %q needs 0 < damage_min <= damage_max", a.ID))
+			}
+			if a.Kind == MobAbilityAoE && a.Radius <= 0 {
+				problems = append(problems, fmt.Sprintf("mob ability %q needs a positive radius", a.ID))
+			}
+			if a.Kind == MobAbilityStun && a.StunMS <= 0 {
+				problems = append(problems, fmt.Sprintf("mob ability %q needs a positive stun_ms", a.ID))
+			}
+		case MobAbilityHealSelf:
+			if a.HealPct <= 0 || a.HealPct > 100 {
+				problems = append(problems, fmt.Sprintf("mob ability %q needs heal_pct in 1..100", a.ID))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("mob ability %q has unknown kind %q", a.ID, a.Kind))
+		}
+	}
+	return problems
+}
+
+func mobAttackInterval(mob *MobEntity) time.Duration {
+	if mob.AttackIntervalMS > 0 {
+		return time.Duration(mob.AttackIntervalMS) * time.Millisecond
+	}
+	return defaultMobAttackInterval
+}
+
+func mobSwingDamage(mob *MobEntity) int {
+	lo, hi := mob.DamageMin, mob.DamageMax
+	if lo <= 0 {
+		lo, hi = mob.Level, mob.Level*3
+	}
+	return rollDamage(lo, hi)
+}
+
+func rollDamage(lo, hi int) int {
+	if hi > lo {
+		lo += randIntn(hi - lo + 1)
+	}
+	if lo < 1 {
+		lo = 1
+	}
+	return lo
+}
+
+// mobAttackSession swings at target for every attack interval that has
+// elapsed on the sim clock since the last swing, so mob DPS does not depend
+// on the tick rate. A mob that was not swinging (chasing, idle) starts again
+// immediately rather than bursting through the time it missed.
+func mobAttackSession(w *worldSim, mob *MobEntity, target *ClientSession, candidates []*ClientSession, outbox *mobOutbox) {
+	interval := mobAttackInterval(mob)
+	if mob.nextAttackAt.Before(w.now.Add(-w.tickRate)) {
+		mob.nextAttackAt = w.now
+	}
+	killed := new(bool)
+	for swings := 0; swings < maxMobSwingsPerTick && !w.now.Before(mob.nextAttackAt); swings++ {
+		mob.nextAttackAt = mob.nextAttackAt.Add(interval)
+		if !mobUseAbility(w, mob, target, candidates, killed, outbox) {
+			mobHitSession(w, mob, target, mobSwingDamage(mob), "", 0, killed, outbox)
+		}
+	}
+}
+
+// mobUseAbility fires the first ready ability whose conditions hold and
+// reports whether one was used. killed is as for mobHitSession.
+func mobUseAbility(w *worldSim, mob *MobEntity, target *ClientSession, candidates []*ClientSession, killed *bool, outbox *mobOutbox) bool {
+	for _, a := range mob.Abilities {
+		if w.now.Before(mob.abilityReadyAt[a.ID]) {
+			continue
+		}
+		switch a.Kind {
+		case MobAbilityHealSelf:
+			below := a.BelowHPPct
+			if below <= 0 {
+				below = defaultMobHealBelowPct
+			}
+			if mob.HP*100 >= mob.MaxHP*below {
+				continue
+			}
+			mob.HP += mob.MaxHP * a.HealPct / 100
+			if mob.HP > mob.MaxHP {
+				mob.HP = mob.MaxHP
+			}
+		case MobAbilityAoE:
+			for _, s := range candidates {
+				if distance2D(mob.Position, s.Position) <= a.Radius {
+					hitKilled := killed
+					if s != target {
+						hitKilled = nil
+					}
+					mobHitSession(w, mob, s, rollDamage(a.DamageMin, a.DamageMax), a.ID, 0, hitKilled, outbox)
+				}
+			}
+		case MobAbilityStun:
+			stun := time.Duration(a.StunMS) * time.Millisecond
+			mobHitSession(w, mob, target, rollDamage(a.DamageMin, a.DamageMax), a.ID, stun, killed, outbox)
+		default:
+			continue
+		}
+		if mob.abilityReadyAt == nil {
+			mob.abilityReadyAt = map[string]time.Time{}
+		}
+		mob.abilityReadyAt[a.ID] = w.now.Add(time.Duration(a.CooldownMS) * time.Millisecond)
+		outbox.event(mob, RespMobAbility, map[string]interface{}{
+			"mob_id":  mob.ID,
+			"ability": a.ID,
+			"kind":    a.Kind,
+			"hp":      mob.HP,
+			"max_hp":  mob.MaxHP,
+		}, nil)
+		return true
+	}
+	return false
+}
+
+// mobHitSession deals damage to target and tells them with MOB_HIT, or
+// PLAYER_DIED on a killing blow. The hit lands through damageCharacter on
+// target's own action path; a death there also drops them from this world's
+// threat tables. A non-nil killed is shared by one mob's hits on target in a
+// tick, so once one of them kills target the rest miss.
+func mobHitSession(w *worldSim, mob *MobEntity, target *ClientSession, damage int, abilityID string, stun time.Duration, killed *bool, outbox *mobOutbox) {
+	worldID, now := w.ID, w.now
+	mobID, from, element := mob.ID, mob.Name, mob.Element
+	outbox.post(target, func() {
+		if killed != nil && *killed {
+			return
+		}
+		c := target.Character
+		elementPct := elementalPct(element, armorElement(c), nil)
+		dealt, died := damageCharacter(c, target.Position, applyElement(damage, elementPct))
+		if died {
+			if killed != nil {
+				*killed = true
+			}
+			clearPlayerThreat(worldID, c.Name)
+			sendMessage(target.Conn, ServerMessage{
+				Command: RespPlayerDied,
+				Payload: map[string]interface{}{"target": from, "mob_id": mobID, "xp_debt": c.XPDebt, "corpse": c.Corpse, "recovery": "Use RECOVER_CORPSE"},
+			})
+			return
+		}
+		if stun > 0 {
+			var effects mobOutbox
+			applySessionEffect(target, "stunned", from, 0, stun, now, &effects)
+			effects.flush()
+		}
+		sendMessage(target.Conn, ServerMessage{
+			Command: RespMobHit,
+			Payload: map[string]interface{}{
+				"mob_id":        mobID,
+				"from":          from,
+				"ability":       abilityID,
+				"damage":        dealt,
+				"element":       element,
+				"effectiveness": effectivenessLabel(elementPct),
+				"stunned_ms":    stun.Milliseconds(),
+				"target_hp":     c.HP,
+				"target_debt":   c.XPDebt,
+			},
+		})
+	})
+}
+
+// stunSession keeps s from moving or attacking until the sim clock passes
+// until. A longer stun already in effect is kept.
+func stunSession(s *ClientSession, until time.Time) {
+	if until.UnixNano() > s.stunnedUntil.Load() {
+		s.stunnedUntil.Store(until.UnixNano())
+	}
+}
+
+func sessionStunned(s *ClientSession) bool {
+	return simTickClock().UnixNano() < s.stunnedUntil.Load()
+}
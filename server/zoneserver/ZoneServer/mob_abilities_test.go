@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countMessages(msgs []ServerMessage, command string) int {
+	n := 0
+	for _, msg := range msgs {
+		if msg.Command == command {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMobDamageDoesNotDependOnTickRate(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	player, playerConn := newVisibilityTestSession("Anvil", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(player)
+
+	for _, tickRate := range []time.Duration{250 * time.Millisecond, time.Second, 2 * time.Second} {
+		withSimClock(func(clock *fakeClock) {
+			withFixedRandIntn(0, func() {
+				mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+				mob.AttackIntervalMS, mob.DamageMin, mob.DamageMax = 1000, 10, 10
+				mob.threat = map[string]int{"Anvil": 10}
+				withWorldMob(World1, "mob_wolf_01", mob, func() {
+					worldSimFor(World1).tickRate = tickRate
+					player.Character.HP, player.Character.MaxHP = 10000, 10000
+					playerConn.DrainMessages(t)
+
+					for elapsed := time.Duration(0); elapsed <= 4*time.Second; elapsed += tickRate {
+						processServerTick()
+						clock.Advance(tickRate)
+					}
+					if hits := countMessages(playerConn.DrainMessages(t), RespMobHit); hits != 5 {
+						t.Fatalf("tick rate %v: expected 5 swings in 0..4s, got %d", tickRate, hits)
+					}
+					if player.Character.HP != 10000-50 {
+						t.Fatalf("tick rate %v: expected 50 damage, HP is %d", tickRate, player.Character.HP)
+					}
+				})
+			})
+		})
+	}
+}
+
+func TestMobKillLandsOnTheVictimsActionPath(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	victim, conn := newVisibilityTestSession("Doomed", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(victim)
+	victim.serving.Store(true) // as if its read loop were running
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.AttackIntervalMS, mob.DamageMin, mob.DamageMax = 1000, 500, 500
+			mob.threat = map[string]int{"Doomed": 10}
+			withWorldMob(World1, mob.ID, mob, func() {
+				victim.Character.HP, victim.Character.MaxHP = 10, 100
+				conn.DrainMessages(t)
+
+				// A command in progress holds actionMu; the killing blow
+				// waits for it to finish.
+				victim.actionMu.Lock()
+				processServerTick()
+				if victim.Character.Corpse != nil || countMessages(conn.DrainMessages(t), RespPlayerDied) != 0 {
+					victim.actionMu.Unlock()
+					t.Fatalf("expected the hit to wait for the command, got corpse=%v", victim.Character.Corpse)
+				}
+				victim.actionMu.Unlock()
+				waitForPosted(t, victim)
+				died := lastMessage(conn.DrainMessages(t), RespPlayerDied)
+				if victim.Character.Corpse == nil || toString(toMap(died.Payload), "mob_id") != mob.ID {
+					t.Fatalf("expected PLAYER_DIED once posted, got %#v", died)
+				}
+				if _, ok := mob.threat["Doomed"]; ok {
+					t.Fatalf("expected the death to clear the victim's threat, got %#v", mob.threat)
+				}
+
+				// Corpse recovery racing the mob's swings; run with -race.
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for i := 0; i < 50; i++ {
+						victim.actionMu.Lock()
+						recoverCorpse(victim.Character)
+						victim.actionMu.Unlock()
+					}
+				}()
+				for i := 0; i < 20; i++ {
+					clock.Advance(time.Second)
+					processServerTick()
+				}
+				<-done
+				waitForPosted(t, victim)
+			})
+		})
+	})
+}
+
+func TestMobAbilitiesStunAoEAndHealSelf(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	tank, tankConn := newVisibilityTestSession("Stunned", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(tank)
+	bystander, bystanderConn := newVisibilityTestSession("Nearby", worlds[World1], Position{X: 120, Y: 0, Z: 100})
+	defer unregisterSession(bystander)
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.HP, mob.MaxHP = 400, 1000
+			mob.AttackIntervalMS, mob.DamageMin, mob.DamageMax = 1000, 10, 10
+			mob.Abilities = []MobAbility{
+				{ID: "mend", Kind: MobAbilityHealSelf, CooldownMS: 60000, HealPct: 20},
+				{ID: "grip", Kind: MobAbilityStun, CooldownMS: 60000, DamageMin: 5, DamageMax: 5, StunMS: 1500},
+				{ID: "quake", Kind: MobAbilityAoE, CooldownMS: 60000, Radius: 30, DamageMin: 7, DamageMax: 7},
+			}
+			mob.threat = map[string]int{"Stunned": 10}
+			withWorldMob(World1, "mob_wolf_01", mob, func() {
+				tankConn.DrainMessages(t)
+				bystanderConn.DrainMessages(t)
+
+				// Below half HP the mob heals itself first.
+				processServerTick()
+				if mob.HP != 600 || !hasMessage(tankConn.DrainMessages(t), RespMobAbility, nil) {
+					t.Fatalf("expected heal_self to restore 20%% of max HP, got %d", mob.HP)
+				}
+
+				clock.Advance(time.Second)
+				processServerTick()
+				msgs := tankConn.DrainMessages(t)
+				hit := msgs[len(msgs)-1]
+				payload := toMap(hit.Payload)
+				if hit.Command != RespMobHit || toString(payload, "ability") != "grip" || toInt(payload, "stunned_ms") != 1500 {
+					t.Fatalf("expected a stunning MOB_HIT, got %#v", msgs)
+				}
+				if !sessionStunned(tank) {
+					t.Fatalf("expected the target to be stunned")
+				}
+				if _, ok, reason := attackMob(tank, "mob_wolf_01", ""); ok || reason != "STUNNED" {
+					t.Fatalf("expected attacks rejected while stunned, got ok=%v reason=%s", ok, reason)
+				}
+
+				clock.Advance(time.Second)
+				processServerTick()
+				if toString(toMap(lastMessage(tankConn.DrainMessages(t), RespMobHit).Payload), "ability") != "quake" {
+					t.Fatalf("expected the AoE to hit the target")
+				}
+				if toString(toMap(lastMessage(bystanderConn.DrainMessages(t), RespMobHit).Payload), "ability") != "quake" {
+					t.Fatalf("expected the AoE to hit the bystander in radius")
+				}
+
+				// Every ability is on cooldown: a basic swing follows.
+				clock.Advance(time.Second)
+				processServerTick()
+				if msg := lastMessage(tankConn.DrainMessages(t), RespMobHit); toString(toMap(msg.Payload), "ability") != "" || toInt(toMap(msg.Payload), "damage") != 10 {
+					t.Fatalf("expected a basic swing while abilities cool down, got %#v", msg)
+				}
+				if sessionStunned(tank) {
+					t.Fatalf("expected the stun to wear off after 1.5s")
+				}
+			})
+		})
+	})
+}
+
+func lastMessage(msgs []ServerMessage, command string) ServerMessage {
+	var last ServerMessage
+	for _, msg := range msgs {
+		if msg.Command == command {
+			last = msg
+		}
+	}
+	return last
+}
+
+func TestLoadSpawnDataValidatesMobCombatProfile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		mobTemplatesFile: `[{"id": "wolf", "name": "Rift Wolf", "level": 42, "hp": 210, "loot_table": "rift_wolf", "respawn_sec": 8,
+			"attack_interval_ms": 0, "damage_min": 20, "damage_max": 10,
+			"abilities": [{"id": "howl", "kind": "scream", "cooldown_ms": 1000}, {"id": "bite", "kind": "stun", "cooldown_ms": 1000, "damage_min": 5, "damage_max": 5}]}]`,
+		spawnGroupsFile:   `[]`,
+		worldSpawnsFile:   `[]`,
+		dungeonSpawnsFile: `[]`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	_, err := loadSpawnData(dir)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{"positive attack_interval_ms", "0 < damage_min <= damage_max", `unknown kind "scream"`, `"bite" needs a positive stun_ms`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+// mobOutbox collects mob notifications produced while a worldSim is
+// stepping so they can be delivered after its lock is released.
+type mobOutbox struct {
+	events []mobBroadcast
+	direct []mobDirect
+	moves  map[WorldID][]MobEntity
+	after  []func()
+	posts  []mobPost
+	// owner is the session whose action path flushes the outbox, if any;
+	// see worldSim.doFor.
+	owner *ClientSession
+}
+
+type mobPost struct {
+	to *ClientSession
+	fn func()
+}
+
+type mobDirect struct {
+	to  *ClientSession
+	msg ServerMessage
+}
+
+type mobBroadcast struct {
+	worldID WorldID
+	pos     Position
+	msg     ServerMessage
+	exclude *ClientSession
+}
+
+func (o *mobOutbox) event(mob *MobEntity, command string, payload map[string]interface{}, exclude *ClientSession) {
+	o.events = append(o.events, mobBroadcast{
+		worldID: mob.WorldID,
+		pos:     mob.Position,
+		msg:     ServerMessage{Command: command, Payload: payload},
+		exclude: exclude,
+	})
+}
+
+// send queues msg for a single session.
+func (o *mobOutbox) send(to *ClientSession, msg ServerMessage) {
+	o.direct = append(o.direct, mobDirect{to: to, msg: msg})
+}
+
+// moved records mob for this tick's MOB_MOVED batch if its position changed
+// since the last batch that carried it.
+func (o *mobOutbox) moved(mob *MobEntity) {
+	if mob.Position == mob.lastSentPos {
+		return
+	}
+	mob.lastSentPos = mob.Position
+	if o.moves == nil {
+		o.moves = map[WorldID][]MobEntity{}
+	}
+	o.moves[mob.WorldID] = append(o.moves[mob.WorldID], *mob)
+}
+
+// announce queues msg for every player in worldID, regardless of range.
+func (o *mobOutbox) announce(worldID WorldID, msg ServerMessage) {
+	o.then(func() {
+		forEachSessionInWorld(worldID, func(s *ClientSession) {
+			if s.Authenticated && s.Character != nil {
+				sendMessage(s.Conn, msg)
+			}
+		})
+	})
+}
+
+// then queues fn to run once the outbox is flushed, outside the world lock.
+func (o *mobOutbox) then(fn func()) {
+	o.after = append(o.after, fn)
+}
+
+// post queues fn, a change to to's character, to run on to's own action
+// path once the outbox is flushed and the world lock released. World code
+// never writes a character directly. Work posted to the outbox's owner runs
+// inline, since the flushing goroutine is already their action path; work
+// for anyone else goes through ClientSession.post. Either way one session's
+// work runs in the order it was posted, after the outbox's messages.
+func (o *mobOutbox) post(to *ClientSession, fn func()) {
+	o.posts = append(o.posts, mobPost{to: to, fn: fn})
+}
+
+func (o *mobOutbox) flush() {
+	for _, evt := range o.events {
+		for _, viewer := range mobViewers(evt.worldID, evt.pos) {
+			if viewer == evt.exclude {
+				continue
+			}
+			sendMessage(viewer.Conn, evt.msg)
+		}
+	}
+	for _, d := range o.direct {
+		sendMessage(d.to.Conn, d.msg)
+	}
+	for worldID, mobs := range o.moves {
+		broadcastMobMoves(worldID, mobs)
+	}
+	after, posts := o.after, o.posts
+	o.events = nil
+	o.direct = nil
+	o.moves = nil
+	o.after = nil
+	o.posts = nil
+	for _, fn := range after {
+		fn()
+	}
+	for _, p := range posts {
+		if p.to == o.owner {
+			p.fn()
+		} else {
+			p.to.post(p.fn)
+		}
+	}
+}
+
+// mobViewers returns the authenticated sessions in worldID whose visibility
+// radius covers pos.
+func mobViewers(worldID WorldID, pos Position) []*ClientSession {
+	viewers := make([]*ClientSession, 0)
+	forEachSessionInWorld(worldID, func(s *ClientSession) {
+		if !s.Authenticated || s.Character == nil {
+			return
+		}
+		if isVisible(s.Position, pos) {
+			viewers = append(viewers, s)
+		}
+	})
+	return viewers
+}
+
+// broadcastMobMoves sends each viewer a single MOB_MOVED message listing only
+// the mobs it can currently see.
+func broadcastMobMoves(worldID WorldID, mobs []MobEntity) {
+	forEachSessionInWorld(worldID, func(s *ClientSession) {
+		if !s.Authenticated || s.Character == nil {
+			return
+		}
+		batch := make([]map[string]interface{}, 0)
+		for i := range mobs {
+			if isVisible(s.Position, mobs[i].Position) {
+				batch = append(batch, map[string]interface{}{"id": mobs[i].ID, "pos": mobs[i].Position})
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		sendMessage(s.Conn, ServerMessage{Command: RespMobMoved, Payload: map[string]interface{}{"mobs": batch}})
+	})
+}
+
+func mobSnapshotPayload(mob *MobEntity) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     mob.ID,
+		"name":   mob.Name,
+		"level":  mob.Level,
+		"hp":     maxInt(mob.HP, 0),
+		"max_hp": mob.MaxHP,
+		"pos":    mob.Position,
+	}
+}
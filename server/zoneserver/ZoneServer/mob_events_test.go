@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestAttackMobBroadcastsDamageAndDeathToNearbyViewers(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	attacker, attackerConn := newVisibilityTestSession("Striker", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	viewer, viewerConn := newVisibilityTestSession("Onlooker", worlds[World1], Position{X: 110, Y: 0, Z: 100})
+	distant, distantConn := newVisibilityTestSession("Faraway", worlds[World1], Position{X: 400, Y: 0, Z: 400})
+	defer unregisterSession(attacker)
+	defer unregisterSession(viewer)
+	defer unregisterSession(distant)
+
+	withWorldMob(World1, "mob_wolf_01", &MobEntity{
+		ID:         "mob_wolf_01",
+		Name:       "Rift Wolf",
+		LootTable:  "rift_wolf",
+		WorldID:    World1,
+		Level:      42,
+		HP:         9999,
+		MaxHP:      9999,
+		Position:   Position{X: 100, Y: 0, Z: 100},
+		RespawnSec: 99,
+	}, func() {
+		withFixedRandIntn(0, func() {
+			if _, ok, reason := attackMob(attacker, "mob_wolf_01", ""); !ok {
+				t.Fatalf("attackMob failed: %s", reason)
+			}
+		})
+		if !hasMessage(viewerConn.DrainMessages(t), RespMobDamaged, nil) {
+			t.Fatalf("expected nearby viewer to receive MOB_DAMAGED")
+		}
+		if msgs := attackerConn.DrainMessages(t); len(msgs) != 0 {
+			t.Fatalf("expected attacker to rely on MOB_ATTACK_RESULT only, got %#v", msgs)
+		}
+		if msgs := distantConn.DrainMessages(t); len(msgs) != 0 {
+			t.Fatalf("expected out-of-range session to receive nothing, got %#v", msgs)
+		}
+
+		worldMobsForTests(World1)["mob_wolf_01"].HP = 1
+		withFixedRandIntn(9999, func() {
+			if _, ok, reason := attackMob(attacker, "mob_wolf_01", ""); !ok {
+				t.Fatalf("attackMob failed: %s", reason)
+			}
+		})
+		if !hasMessage(viewerConn.DrainMessages(t), RespMobDied, nil) {
+			t.Fatalf("expected nearby viewer to receive MOB_DIED")
+		}
+	})
+}
+
+func TestMobMovedBatchesOnlyChangedPositions(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	viewer, viewerConn := newVisibilityTestSession("Scout", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(viewer)
+
+	still := &MobEntity{ID: "mob_still", WorldID: World1, HP: 10, MaxHP: 10, Position: Position{X: 105, Y: 0, Z: 100}}
+	still.lastSentPos = still.Position
+	walker := &MobEntity{ID: "mob_walker", WorldID: World1, HP: 10, MaxHP: 10, Position: Position{X: 110, Y: 0, Z: 100}}
+	walker.lastSentPos = walker.Position
+	walker.Position.X += 5
+
+	var outbox mobOutbox
+	outbox.moved(still)
+	outbox.moved(walker)
+	outbox.flush()
+
+	msgs := viewerConn.DrainMessages(t)
+	if len(msgs) != 1 || msgs[0].Command != RespMobMoved {
+		t.Fatalf("expected one MOB_MOVED batch, got %#v", msgs)
+	}
+	batch, _ := toMap(msgs[0].Payload)["mobs"].([]interface{})
+	if len(batch) != 1 || toString(toMap(batch[0]), "id") != "mob_walker" {
+		t.Fatalf("expected only the moved mob in the batch, got %#v", batch)
+	}
+
+	outbox.moved(walker)
+	outbox.flush()
+	if msgs := viewerConn.DrainMessages(t); len(msgs) != 0 {
+		t.Fatalf("expected no batch once positions are unchanged, got %#v", msgs)
+	}
+}
@@ -8,6 +8,9 @@ type MoveRequest struct {
 	Z float64 `json:"z"`
 }
 
+// maxMoveStep is the furthest a single MOVE may go; slows shorten it.
+const maxMoveStep = 10.0
+
 // Simple distance check (anti-teleport)
 func isMoveValid(from, to Position) bool {
 	dx := to.X - from.X
@@ -17,6 +20,6 @@ func isMoveValid(from, to Position) bool {
 	distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
 
 	// Max distance per tick (tunable later)
-	return distance <= 10.0
+	return distance <= maxMoveStep
 }
 
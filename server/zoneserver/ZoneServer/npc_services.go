@@ -0,0 +1,61 @@
+package main
+
+// NPC service types, listed in NPCEntity.Services.
+const (
+	NPCServiceStorage    = "storage"
+	NPCServiceVendor     = "vendor"
+	NPCServiceSmith      = "smith"
+	NPCServiceTeleporter = "teleporter"
+	NPCServiceQuestGiver = "quest_giver"
+	NPCServiceTrainer    = "trainer"
+)
+
+// npcServiceCommands maps each NPC-gated command to the service it needs.
+var npcServiceCommands = map[string]string{
+	ReqStorageView:    NPCServiceStorage,
+	ReqStorageDepMat:  NPCServiceStorage,
+	ReqStorageWdrMat:  NPCServiceStorage,
+	ReqStorageDepItm:  NPCServiceStorage,
+	ReqStorageWdrItm:  NPCServiceStorage,
+	ReqStorageDepGold: NPCServiceStorage,
+	ReqStorageWdrGold: NPCServiceStorage,
+	ReqUpgradeGear:    NPCServiceSmith,
+	ReqCraftItem:      NPCServiceSmith,
+	ReqTeleport:       NPCServiceTeleporter,
+	ReqLearnSkill:     NPCServiceTrainer,
+}
+
+func (npc NPCEntity) provides(service string) bool {
+	for _, s := range npc.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// nearbyNPCWithService returns an NPC in the session's world, within
+// visibility range, that provides service.
+func nearbyNPCWithService(session *ClientSession, service string) *NPCEntity {
+	if session == nil || session.World == nil {
+		return nil
+	}
+	npcs := worldNPCs[session.World.ID]
+	for i := range npcs {
+		if npcs[i].provides(service) && isVisible(session.Position, npcs[i].Position) {
+			return &npcs[i]
+		}
+	}
+	return nil
+}
+
+// requireNPCService reports whether command may run where the session
+// stands. If not, it answers with rejectCommand and NPC_REQUIRED.
+func requireNPCService(conn WSConn, session *ClientSession, command, rejectCommand string) bool {
+	service, gated := npcServiceCommands[command]
+	if !gated || nearbyNPCWithService(session, service) != nil {
+		return true
+	}
+	sendMessage(conn, ServerMessage{Command: rejectCommand, Payload: "NPC_REQUIRED"})
+	return false
+}
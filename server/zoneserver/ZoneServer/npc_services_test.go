@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestNPCGatedCommandsRequireService(t *testing.T) {
+	resetSocialStateForTests()
+	resetPersistenceRuntimeStateForTests()
+	worlds = DefaultWorlds()
+
+	conn := &captureConn{}
+	session := NewSession(conn)
+	session.Character = MockCharacter()
+	ensureCharacterDefaults(session.Character)
+	session.World = worlds[World1]
+	visible := map[*ClientSession]bool{}
+	bound := ""
+
+	cases := []struct {
+		cmd, reject string
+		payload     map[string]interface{}
+		npcAt       Position
+	}{
+		{ReqUpgradeGear, RespGearUpgradeReject, map[string]interface{}{"item_id": "missing"}, Position{X: 10, Z: -10}},
+		{ReqCraftItem, RespCraftRejected, map[string]interface{}{"recipe_id": "missing"}, Position{X: 10, Z: -10}},
+		{ReqLearnSkill, RespSkillRejected, map[string]interface{}{"skill_id": "missing"}, Position{X: -5, Z: -5}},
+		{ReqTeleport, RespError, map[string]interface{}{"world_id": int(World1)}, Position{X: 0, Z: 15}},
+	}
+	for _, tc := range cases {
+		session.Position = Position{X: 200, Y: 0, Z: 200}
+		handleClientCommand(conn, session, visible, "npc-gate-peer", &bound, tc.cmd, tc.payload)
+		msgs := conn.DrainMessages(t)
+		if len(msgs) != 1 || msgs[0].Command != tc.reject || msgs[0].Payload != "NPC_REQUIRED" {
+			t.Fatalf("%s away from NPCs: expected %s/NPC_REQUIRED, got %#v", tc.cmd, tc.reject, msgs)
+		}
+
+		session.Position = tc.npcAt
+		handleClientCommand(conn, session, visible, "npc-gate-peer", &bound, tc.cmd, tc.payload)
+		if hasMessage(conn.DrainMessages(t), tc.reject, "NPC_REQUIRED") {
+			t.Fatalf("%s next to its NPC: expected the NPC check to pass", tc.cmd)
+		}
+	}
+}
+
+func TestNPCServicesAreTyped(t *testing.T) {
+	worlds = DefaultWorlds()
+	session := &ClientSession{Character: MockCharacter(), World: worlds[World1], Position: Position{X: 10, Z: -10}}
+
+	// The Gear Smith is a smith, not a storage keeper or trainer, whatever
+	// else stands in range.
+	smith := nearbyNPCWithService(session, NPCServiceSmith)
+	if smith == nil || smith.ID != "npc_gear_smith" {
+		t.Fatalf("expected the gear smith to provide smithing, got %#v", smith)
+	}
+	session.World = worlds[World2]
+	if npc := nearbyNPCWithService(session, NPCServiceVendor); npc != nil {
+		t.Fatalf("expected no vendor in world 2, got %#v", npc)
+	}
+	for worldID, npcs := range worldNPCs {
+		for _, npc := range npcs {
+			if len(npc.Services) == 0 {
+				t.Fatalf("NPC %s in world %d provides no services", npc.ID, worldID)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"container/heap"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"sync"
+)
+
+//go:embed terrain/*.json
+var defaultTerrainFiles embed.FS
+
+const (
+	// mobPathBudgetPerTick caps A* searches per world tick; mobs over budget
+	// keep following their cached path, or wait a tick if they have none.
+	mobPathBudgetPerTick = 8
+	// maxPathNodes bounds a single search so an unreachable goal in a large
+	// open grid gives up quickly.
+	maxPathNodes = 4096
+)
+
+// TerrainFile is the on-disk terrain grid for one world. Each rune in Rows
+// is one cell: '#' is blocked, '.' is ground level and '1'-'9' are raised
+// walkable ground, height_step units per level. Row 0 is the lowest Z.
+type TerrainFile struct {
+	WorldID    WorldID  `json:"world_id"`
+	CellSize   float64  `json:"cell_size"`
+	OriginX    float64  `json:"origin_x"`
+	OriginZ    float64  `json:"origin_z"`
+	HeightStep float64  `json:"height_step"`
+	Rows       []string `json:"rows"`
+}
+
+// TerrainGrid is a loaded TerrainFile. Positions outside it are open ground
+// with no terrain, where mobs move in straight lines.
+type TerrainGrid struct {
+	WorldID    WorldID
+	CellSize   float64
+	OriginX    float64
+	OriginZ    float64
+	HeightStep float64
+	Width      int
+	Height     int
+	levels     []int8 // -1 blocked, otherwise height level
+}
+
+type gridCell struct {
+	X, Z int
+}
+
+var (
+	terrainMu    sync.RWMutex
+	worldTerrain map[WorldID]*TerrainGrid
+)
+
+func terrainFor(worldID WorldID) *TerrainGrid {
+	terrainMu.RLock()
+	loaded := worldTerrain
+	terrainMu.RUnlock()
+	if loaded == nil {
+		grids, err := loadTerrain(defaultTerrainFiles)
+		if err != nil {
+			log.Printf("embedded terrain is invalid: %v", err)
+			grids = map[WorldID]*TerrainGrid{}
+		}
+		terrainMu.Lock()
+		if worldTerrain == nil {
+			worldTerrain = grids
+		}
+		loaded = worldTerrain
+		terrainMu.Unlock()
+	}
+	return loaded[worldID]
+}
+
+func loadTerrain(fsys fs.FS) (map[WorldID]*TerrainGrid, error) {
+	names, err := fs.Glob(fsys, "terrain/*.json")
+	if err != nil {
+		return nil, err
+	}
+	grids := map[WorldID]*TerrainGrid{}
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		var file TerrainFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		grid, err := newTerrainGrid(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if _, dup := grids[grid.WorldID]; dup {
+			return nil, fmt.Errorf("%s: duplicate terrain for world %d", name, grid.WorldID)
+		}
+		grids[grid.WorldID] = grid
+	}
+	return grids, nil
+}
+
+func newTerrainGrid(file TerrainFile) (*TerrainGrid, error) {
+	if file.CellSize <= 0 || len(file.Rows) == 0 || len(file.Rows[0]) == 0 {
+		return nil, fmt.Errorf("terrain for world %d needs a positive cell_size and rows", file.WorldID)
+	}
+	g := &TerrainGrid{
+		WorldID:    file.WorldID,
+		CellSize:   file.CellSize,
+		OriginX:    file.OriginX,
+		OriginZ:    file.OriginZ,
+		HeightStep: file.HeightStep,
+		Width:      len(file.Rows[0]),
+		Height:     len(file.Rows),
+	}
+	g.levels = make([]int8, 0, g.Width*g.Height)
+	for z, row := range file.Rows {
+		if len(row) != g.Width {
+			return nil, fmt.Errorf("terrain row %d has %d cells, want %d", z, len(row), g.Width)
+		}
+		for x, r := range row {
+			switch {
+			case r == '#':
+				g.levels = append(g.levels, -1)
+			case r == '.':
+				g.levels = append(g.levels, 0)
+			case r >= '1' && r <= '9':
+				g.levels = append(g.levels, int8(r-'0'))
+			default:
+				return nil, fmt.Errorf("terrain cell (%d,%d) has unknown rune %q", x, z, r)
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *TerrainGrid) cellAt(p Position) (gridCell, bool) {
+	x := int(math.Floor((p.X - g.OriginX) / g.CellSize))
+	z := int(math.Floor((p.Z - g.OriginZ) / g.CellSize))
+	c := gridCell{X: x, Z: z}
+	return c, g.inBounds(c)
+}
+
+func (g *TerrainGrid) inBounds(c gridCell) bool {
+	return c.X >= 0 && c.Z >= 0 && c.X < g.Width && c.Z < g.Height
+}
+
+func (g *TerrainGrid) level(c gridCell) int {
+	return int(g.levels[c.Z*g.Width+c.X])
+}
+
+func (g *TerrainGrid) walkable(c gridCell) bool {
+	return g.inBounds(c) && g.level(c) >= 0
+}
+
+func (g *TerrainGrid) center(c gridCell) Position {
+	return Position{
+		X: g.OriginX + (float64(c.X)+0.5)*g.CellSize,
+		Y: float64(maxInt(g.level(c), 0)) * g.HeightStep,
+		Z: g.OriginZ + (float64(c.Z)+0.5)*g.CellSize,
+	}
+}
+
+// groundY is the terrain height under p, or p.Y off the grid.
+func (g *TerrainGrid) groundY(p Position) float64 {
+	if c, ok := g.cellAt(p); ok && g.walkable(c) {
+		return float64(g.level(c)) * g.HeightStep
+	}
+	return p.Y
+}
+
+// findPath runs A* from from to to over 8-connected walkable cells. Diagonal
+// steps may not cut blocked corners and a step may climb or drop at most one
+// height level. The returned waypoints are cell centres after the start
+// cell, ending at to itself.
+func (g *TerrainGrid) findPath(from, to Position, maxNodes int) ([]Position, bool) {
+	start, ok := g.cellAt(from)
+	if !ok {
+		return nil, false
+	}
+	goal, ok := g.cellAt(to)
+	if !ok || !g.walkable(goal) {
+		return nil, false
+	}
+	end := to
+	end.Y = g.groundY(to)
+	if start == goal {
+		return []Position{end}, true
+	}
+
+	open := &pathQueue{}
+	heap.Push(open, &pathNode{cell: start, f: octile(start, goal)})
+	cost := map[gridCell]float64{start: 0}
+	parent := map[gridCell]gridCell{}
+	closed := map[gridCell]bool{}
+
+	for open.Len() > 0 && len(closed) < maxNodes {
+		node := heap.Pop(open).(*pathNode)
+		if closed[node.cell] {
+			continue
+		}
+		if node.cell == goal {
+			return g.smoothPath(from, g.buildPath(parent, start, goal, end)), true
+		}
+		closed[node.cell] = true
+		for _, step := range pathSteps {
+			next := gridCell{X: node.cell.X + step.dx, Z: node.cell.Z + step.dz}
+			if closed[next] || !g.canStep(node.cell, next, step) {
+				continue
+			}
+			g2 := cost[node.cell] + step.cost
+			if known, seen := cost[next]; seen && g2 >= known {
+				continue
+			}
+			cost[next] = g2
+			parent[next] = node.cell
+			heap.Push(open, &pathNode{cell: next, g: g2, f: g2 + octile(next, goal)})
+		}
+	}
+	return nil, false
+}
+
+func (g *TerrainGrid) canStep(from, to gridCell, step pathStep) bool {
+	if !g.walkable(to) {
+		return false
+	}
+	if from := g.level(from); from >= 0 && absInt(g.level(to)-from) > 1 {
+		return false
+	}
+	if step.dx != 0 && step.dz != 0 {
+		return g.walkable(gridCell{X: from.X + step.dx, Z: from.Z}) && g.walkable(gridCell{X: from.X, Z: from.Z + step.dz})
+	}
+	return true
+}
+
+func (g *TerrainGrid) buildPath(parent map[gridCell]gridCell, start, goal gridCell, end Position) []Position {
+	cells := make([]gridCell, 0)
+	for c := goal; c != start; c = parent[c] {
+		cells = append(cells, c)
+	}
+	path := make([]Position, len(cells))
+	for i, c := range cells {
+		path[len(cells)-1-i] = g.center(c)
+	}
+	path[len(path)-1] = end
+	return path
+}
+
+// smoothPath drops waypoints that can be skipped in a straight walkable line
+// from the previous kept point, so mobs cut across open ground instead of
+// stepping between cell centres.
+func (g *TerrainGrid) smoothPath(from Position, path []Position) []Position {
+	out := make([]Position, 0, len(path))
+	current := from
+	for i := 0; i < len(path); {
+		j := len(path) - 1
+		for j > i && !g.clearLine(current, path[j]) {
+			j--
+		}
+		out = append(out, path[j])
+		current = path[j]
+		i = j + 1
+	}
+	return out
+}
+
+// clearLine samples the segment a-b at quarter-cell spacing and reports
+// whether every sample is walkable without a climb of more than one level.
+func (g *TerrainGrid) clearLine(a, b Position) bool {
+	dist := distance2D(a, b)
+	steps := int(math.Ceil(dist / (g.CellSize / 4)))
+	prev, ok := g.cellAt(a)
+	if !ok {
+		return false
+	}
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		c, ok := g.cellAt(Position{X: a.X + (b.X-a.X)*t, Z: a.Z + (b.Z-a.Z)*t})
+		if !ok || !g.walkable(c) {
+			return false
+		}
+		if c != prev && g.level(prev) >= 0 && absInt(g.level(c)-g.level(prev)) > 1 {
+			return false
+		}
+		prev = c
+	}
+	return true
+}
+
+type pathStep struct {
+	dx, dz int
+	cost   float64
+}
+
+var pathSteps = []pathStep{
+	{1, 0, 1}, {-1, 0, 1}, {0, 1, 1}, {0, -1, 1},
+	{1, 1, math.Sqrt2}, {1, -1, math.Sqrt2}, {-1, 1, math.Sqrt2}, {-1, -1, math.Sqrt2},
+}
+
+func octile(a, b gridCell) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dz := math.Abs(float64(a.Z - b.Z))
+	return math.Max(dx, dz) + (math.Sqrt2-1)*math.Min(dx, dz)
+}
+
+type pathNode struct {
+	cell gridCell
+	g, f float64
+}
+
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].g > q[j].g
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// moveMobTo walks mob up to speed units toward dest along a terrain path and
+// reports whether it arrived and whether dest is reachable at all. The path
+// is cached on the mob and only searched again when dest moves to another
+// cell, within the world's per-tick search budget. Off-grid movement is a
+// straight line.
+func moveMobTo(w *worldSim, mob *MobEntity, dest Position, speed float64) (arrived, reachable bool) {
+	grid := terrainFor(mob.WorldID)
+	if grid == nil {
+		return moveMobToward(mob, dest, speed), true
+	}
+	_, fromOK := grid.cellAt(mob.Position)
+	goal, toOK := grid.cellAt(dest)
+	if !fromOK || !toOK {
+		mob.path = nil
+		return moveMobToward(mob, dest, speed), true
+	}
+	if !grid.walkable(goal) {
+		mob.path = nil
+		return false, false
+	}
+
+	if mob.path == nil || mob.pathGoal != goal {
+		if w.pathBudget <= 0 {
+			// Over budget: keep walking the stale path, if any, and search
+			// on a later tick.
+			w.pathsDeferred++
+			followPath(grid, mob, speed)
+			return false, true
+		}
+		w.pathBudget--
+		w.pathSearches++
+		path, ok := grid.findPath(mob.Position, dest, maxPathNodes)
+		if !ok {
+			mob.path = nil
+			return false, false
+		}
+		mob.path, mob.pathGoal = path, goal
+	}
+	// Same goal cell: retarget the last waypoint without a new search.
+	end := dest
+	end.Y = grid.groundY(dest)
+	if len(mob.path) == 0 {
+		mob.path = []Position{end}
+	} else {
+		mob.path[len(mob.path)-1] = end
+	}
+	return followPath(grid, mob, speed), true
+}
+
+// followPath moves mob up to speed units along its cached waypoints, keeping
+// it on the ground, and reports whether it reached the last one.
+func followPath(grid *TerrainGrid, mob *MobEntity, speed float64) bool {
+	remaining := speed
+	for len(mob.path) > 0 {
+		next := mob.path[0]
+		dist := distance2D(mob.Position, next)
+		if dist > remaining {
+			mob.Position.X += (next.X - mob.Position.X) / dist * remaining
+			mob.Position.Z += (next.Z - mob.Position.Z) / dist * remaining
+			mob.Position.Y = grid.groundY(mob.Position)
+			return false
+		}
+		remaining -= dist
+		mob.Position = next
+		mob.path = mob.path[1:]
+	}
+	return true
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
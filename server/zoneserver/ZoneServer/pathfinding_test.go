@@ -0,0 +1,217 @@
+package main
+
+import "testing"
+
+// withTerrain replaces every world's terrain with a single grid for worldID
+// made from rows, with 10-unit cells starting at the origin.
+func withTerrain(t *testing.T, worldID WorldID, rows []string, fn func(grid *TerrainGrid)) {
+	grid, err := newTerrainGrid(TerrainFile{WorldID: worldID, CellSize: 10, HeightStep: 2, Rows: rows})
+	if err != nil {
+		t.Fatalf("bad test terrain: %v", err)
+	}
+	terrainMu.Lock()
+	orig := worldTerrain
+	worldTerrain = map[WorldID]*TerrainGrid{worldID: grid}
+	terrainMu.Unlock()
+	defer func() {
+		terrainMu.Lock()
+		worldTerrain = orig
+		terrainMu.Unlock()
+	}()
+	fn(grid)
+}
+
+// A wall down column 5 with gaps at both ends.
+var walledTerrain = []string{
+	"..........",
+	".....#....",
+	".....#....",
+	".....#....",
+	".....#....",
+	".....#....",
+	".....#....",
+	".....#....",
+	".....#....",
+	"..........",
+}
+
+func TestFindPathRoutesAroundWallsAndCliffs(t *testing.T) {
+	withTerrain(t, World1, walledTerrain, func(grid *TerrainGrid) {
+		from := Position{X: 25, Z: 45}
+		to := Position{X: 85, Z: 45}
+		path, ok := grid.findPath(from, to, maxPathNodes)
+		if !ok || path[len(path)-1] != to {
+			t.Fatalf("expected a path ending at the goal, got ok=%v path=%v", ok, path)
+		}
+		prev, length := from, 0.0
+		for _, p := range path {
+			if !grid.clearLine(prev, p) {
+				t.Fatalf("path segment %v -> %v crosses blocked terrain", prev, p)
+			}
+			length += distance2D(prev, p)
+			prev = p
+		}
+		if length <= distance2D(from, to)+20 {
+			t.Fatalf("expected a detour around the wall, path length %.1f", length)
+		}
+	})
+
+	// A level-3 ridge down column 2 with a level-1 saddle in the last row.
+	withTerrain(t, World1, []string{
+		"..3..",
+		"..3..",
+		"..1..",
+	}, func(grid *TerrainGrid) {
+		path, ok := grid.findPath(Position{X: 5, Z: 5}, Position{X: 45, Z: 5}, maxPathNodes)
+		if !ok {
+			t.Fatalf("expected to cross the ridge at the saddle")
+		}
+		prev := Position{X: 5, Z: 5}
+		for _, p := range path {
+			if !grid.clearLine(prev, p) {
+				t.Fatalf("path segment %v -> %v climbs a cliff", prev, p)
+			}
+			prev = p
+		}
+		if len(path) < 2 || path[len(path)-1].Y != 0 {
+			t.Fatalf("expected a detour ending on the ground, got %v", path)
+		}
+		if _, ok := grid.findPath(Position{X: 5, Z: 5}, Position{X: 25, Z: 5}, maxPathNodes); ok {
+			t.Fatalf("expected the ridge top to be unreachable up a two-level cliff")
+		}
+	})
+}
+
+func TestMobChasesAroundObstacleAndGivesUpWithoutPath(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	withTerrain(t, World1, walledTerrain, func(grid *TerrainGrid) {
+		player, playerConn := newVisibilityTestSession("Hider", worlds[World1], Position{X: 75, Z: 45})
+		defer unregisterSession(player)
+
+		withFixedRandFloat64(0.99, func() {
+			withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 35, Z: 45}), func() {
+				mob := worldMobsForTests(World1)["mob_wolf_01"]
+				for i := 0; i < 20 && mob.AIState != MobStateAttack; i++ {
+					processServerTick()
+					if c, _ := grid.cellAt(mob.Position); !grid.walkable(c) {
+						t.Fatalf("mob walked into blocked terrain at %v", mob.Position)
+					}
+				}
+				if mob.AIState != MobStateAttack {
+					t.Fatalf("expected the mob to path round the wall and attack, got %s at %v", mob.AIState, mob.Position)
+				}
+			})
+		})
+
+		// Seal the gap: the player is now unreachable.
+		sealed := append([]string{".....#...."}, walledTerrain[1:9]...)
+		sealed = append(sealed, ".....#....")
+		withTerrain(t, World1, sealed, func(*TerrainGrid) {
+			withFixedRandFloat64(0.99, func() {
+				withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 35, Z: 45}), func() {
+					mob := worldMobsForTests(World1)["mob_wolf_01"]
+					playerConn.DrainMessages(t)
+					processServerTick()
+					if mob.AIState != MobStateReturn || !hasMessage(playerConn.DrainMessages(t), RespMobEvaded, nil) {
+						t.Fatalf("expected the mob to give up the chase, got %s", mob.AIState)
+					}
+					processServerTick()
+					processServerTick()
+					if mob.AIState != MobStateIdle {
+						t.Fatalf("expected the mob to stay idle while its target is unreachable, got %s", mob.AIState)
+					}
+				})
+			})
+		})
+	})
+}
+
+func TestPathSearchBudgetAndCache(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	withTerrain(t, World1, walledTerrain, func(*TerrainGrid) {
+		player, _ := newVisibilityTestSession("Bait", worlds[World1], Position{X: 75, Z: 45})
+		defer unregisterSession(player)
+
+		mobs := map[string]*MobEntity{}
+		for i := 0; i < mobPathBudgetPerTick+4; i++ {
+			mob := aiTestMob(Position{X: 35, Z: 45})
+			mob.ID = "mob_wolf_" + string(rune('a'+i))
+			mob.threat = map[string]int{"Bait": 10}
+			mobs[mob.ID] = mob
+		}
+		withWorldMob(World1, "unused", nil, func() {
+			sim := worldSimFor(World1)
+			sim.mobs = mobs
+
+			processServerTick()
+			m := sim.tickMetrics()
+			if m.PathSearches != mobPathBudgetPerTick || m.PathsDeferred != 4 {
+				t.Fatalf("expected %d searches and 4 deferred, got %#v", mobPathBudgetPerTick, m)
+			}
+			processServerTick()
+			m = sim.tickMetrics()
+			if m.PathSearches != mobPathBudgetPerTick+4 || m.PathsDeferred != 4 {
+				t.Fatalf("expected cached paths to be reused and only deferred mobs to search, got %#v", m)
+			}
+		})
+	})
+}
+
+func TestEmbeddedTerrainCoversSpawnAreas(t *testing.T) {
+	grids, err := loadTerrain(defaultTerrainFiles)
+	if err != nil {
+		t.Fatalf("embedded terrain failed to load: %v", err)
+	}
+	data, err := loadSpawnData("")
+	if err != nil {
+		t.Fatalf("spawn data: %v", err)
+	}
+	for _, placement := range data.Worlds {
+		grid := grids[placement.WorldID]
+		if grid == nil {
+			continue
+		}
+		for _, groupID := range placement.Groups {
+			area := data.Groups[groupID].Area
+			for _, p := range []Position{{X: area.MinX, Z: area.MinZ}, {X: area.MaxX, Z: area.MaxZ}} {
+				if c, ok := grid.cellAt(p); ok && !grid.walkable(c) {
+					t.Fatalf("spawn group %s corner %v is on blocked terrain", groupID, p)
+				}
+			}
+		}
+	}
+	for _, boss := range data.Bosses {
+		if grid := grids[boss.WorldID]; grid != nil {
+			if c, ok := grid.cellAt(boss.Position); ok && !grid.walkable(c) {
+				t.Fatalf("world boss %s spawns on blocked terrain at %v", boss.ID, boss.Position)
+			}
+		}
+	}
+}
+
+func TestEvadingMobWalledOffFromSpawnIsPutBackOnIt(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	sealed := append([]string{".....#...."}, walledTerrain[1:9]...)
+	sealed = append(sealed, ".....#....")
+	withTerrain(t, World1, sealed, func(grid *TerrainGrid) {
+		withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 35, Z: 45}), func() {
+			mob := worldMobsForTests(World1)["mob_wolf_01"]
+			mob.Position = Position{X: 75, Z: 45}
+			mob.AIState = MobStateReturn
+
+			processServerTick()
+			if c, _ := grid.cellAt(mob.Position); !grid.walkable(c) {
+				t.Fatalf("mob walked into blocked terrain at %v", mob.Position)
+			}
+			if mob.AIState != MobStateIdle || distance2D(mob.Position, mob.Spawn) > 0.01 {
+				t.Fatalf("expected the mob back on its spawn and idle, got %s at %v", mob.AIState, mob.Position)
+			}
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -159,30 +160,37 @@ func persistCharacter(c *Character) error {
 	if c == nil {
 		return nil
 	}
-	ensureCharacterDefaults(c)
+	snap, err := snapshotCharacter(c)
+	if err != nil {
+		return err
+	}
+	return writeCharacterSnapshot(snap)
+}
 
+// storeCharacterSnapshot writes snap to the active persistence mode.
+func storeCharacterSnapshot(snap characterSnapshot) error {
 	mode := activePersistenceMode()
 	switch mode {
 	case persistenceJSON:
-		return persistCharacterLegacy(c)
+		return persistCharacterRecordLegacy(snap.name, snap.data)
 	case persistenceDB:
 		db, err := openCharacterDB()
 		if err != nil {
 			return fmt.Errorf("character db unavailable in db mode: %w", err)
 		}
-		return persistCharacterToDB(db, c)
+		return persistCharacterRecordToDB(db, snap.name, snap.data)
 	case persistenceHybrid:
 		db, err := openCharacterDB()
 		if err == nil {
-			if persistErr := persistCharacterToDB(db, c); persistErr == nil {
+			if persistErr := persistCharacterRecordToDB(db, snap.name, snap.data); persistErr == nil {
 				return nil
 			} else {
-				log.Printf("Character DB write failed for %q, falling back to JSON: %v", c.Name, persistErr)
+				log.Printf("Character DB write failed for %q, falling back to JSON: %v", snap.name, persistErr)
 			}
 		} else {
 			log.Printf("Character DB unavailable, falling back to JSON: %v", err)
 		}
-		return persistCharacterLegacy(c)
+		return persistCharacterRecordLegacy(snap.name, snap.data)
 	default:
 		return fmt.Errorf("unknown persistence mode %q", mode)
 	}
@@ -515,9 +523,13 @@ func persistCharacterToDB(db *sql.DB, c *Character) error {
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(
+	return persistCharacterRecordToDB(db, c.Name, payload)
+}
+
+func persistCharacterRecordToDB(db *sql.DB, name string, payload []byte) error {
+	_, err := db.Exec(
 		characterUpsertQuery(),
-		sanitizeCharacterName(c.Name),
+		sanitizeCharacterName(name),
 		string(payload),
 	)
 	return err
@@ -644,16 +656,16 @@ func loadAccountFromLegacy(username string) (*Account, bool, error) {
 	return &a, true, nil
 }
 
-func persistCharacterLegacy(c *Character) error {
+func persistCharacterRecordLegacy(name string, payload []byte) error {
 	if err := os.MkdirAll(characterStoreDir, 0o755); err != nil {
 		return err
 	}
-	path := filepath.Join(characterStoreDir, sanitizeCharacterName(c.Name)+".json")
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
+	path := filepath.Join(characterStoreDir, sanitizeCharacterName(name)+".json")
+	var data bytes.Buffer
+	if err := json.Indent(&data, payload, "", "  "); err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0o644)
+	return os.WriteFile(path, data.Bytes(), 0o644)
 }
 
 func persistAccountLegacy(a *Account) error {
@@ -695,15 +707,16 @@ func classStarterInventory(class string) []Item {
 	case "Warrior":
 		return []Item{
 			{
-				ID:        "starter_blade",
-				Name:      "Recruit Blade",
-				Grade:     2,
-				Rarity:    RarityCommon,
-				Slot:      SlotWeapon,
-				Element:   ElementNone,
-				GearLevel: 1,
-				MinSTR:    12,
-				MinDEX:    8,
+				ID:            "starter_blade",
+				Name:          "Recruit Blade",
+				Grade:         2,
+				Rarity:        RarityCommon,
+				Slot:          SlotWeapon,
+				Element:       ElementNone,
+				GearLevel:     1,
+				MinSTR:        12,
+				MinDEX:        8,
+				AttackSpeedMS: 1500,
 			},
 			{
 				ID:        "starter_plate",
@@ -720,15 +733,16 @@ func classStarterInventory(class string) []Item {
 	case "Mage":
 		return []Item{
 			{
-				ID:        "starter_focus",
-				Name:      "Initiate Focus",
-				Grade:     2,
-				Rarity:    RarityCommon,
-				Slot:      SlotWeapon,
-				Element:   ElementNone,
-				GearLevel: 1,
-				MinSTR:    6,
-				MinDEX:    12,
+				ID:            "starter_focus",
+				Name:          "Initiate Focus",
+				Grade:         2,
+				Rarity:        RarityCommon,
+				Slot:          SlotWeapon,
+				Element:       ElementNone,
+				GearLevel:     1,
+				MinSTR:        6,
+				MinDEX:        12,
+				AttackSpeedMS: 1900,
 			},
 			{
 				ID:        "starter_robe",
@@ -745,15 +759,16 @@ func classStarterInventory(class string) []Item {
 	case "Healing Knight":
 		return []Item{
 			{
-				ID:        "starter_mace",
-				Name:      "Initiate Mace",
-				Grade:     2,
-				Rarity:    RarityCommon,
-				Slot:      SlotWeapon,
-				Element:   ElementLight,
-				GearLevel: 1,
-				MinSTR:    10,
-				MinDEX:    8,
+				ID:            "starter_mace",
+				Name:          "Initiate Mace",
+				Grade:         2,
+				Rarity:        RarityCommon,
+				Slot:          SlotWeapon,
+				Element:       ElementLight,
+				GearLevel:     1,
+				MinSTR:        10,
+				MinDEX:        8,
+				AttackSpeedMS: 1700,
 			},
 			{
 				ID:        "starter_guard_mail",
@@ -781,15 +796,16 @@ func classStarterInventory(class string) []Item {
 	default:
 		return []Item{
 			{
-				ID:        "starter_bow",
-				Name:      "Scout Bow",
-				Grade:     2,
-				Rarity:    RarityCommon,
-				Slot:      SlotWeapon,
-				Element:   ElementNone,
-				GearLevel: 1,
-				MinSTR:    8,
-				MinDEX:    12,
+				ID:            "starter_bow",
+				Name:          "Scout Bow",
+				Grade:         2,
+				Rarity:        RarityCommon,
+				Slot:          SlotWeapon,
+				Element:       ElementNone,
+				GearLevel:     1,
+				MinSTR:        8,
+				MinDEX:        12,
+				AttackSpeedMS: 1300,
 			},
 			{
 				ID:        "starter_mail",
@@ -853,6 +869,9 @@ func ensureCharacterDefaults(c *Character) {
 	if c.Trust == nil {
 		c.Trust = make(map[string]int)
 	}
+	if c.DialogueFlags == nil {
+		c.DialogueFlags = map[string]bool{}
+	}
 	if c.Quests == nil {
 		c.Quests = make(map[string]*QuestProgress)
 	}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Saves queued from world loops run on a single persistence worker, so a
+// slow database never stalls a tick. A queued save is snapshotted when it is
+// queued, and every snapshot is numbered so that an older one never
+// overwrites a newer one, whichever path writes it.
+
+const characterPersistQueueSize = 256
+
+// characterSnapshot is a character encoded for storage at one moment.
+type characterSnapshot struct {
+	name string
+	seq  uint64
+	data []byte
+}
+
+type queuedCharacterSave struct {
+	snap characterSnapshot
+	why  string
+}
+
+var (
+	characterSnapshotSeq atomic.Uint64
+
+	// persistedSeqs is the newest snapshot written per character, each
+	// behind its own lock so saves of different characters run in parallel.
+	persistedSeqsMu sync.Mutex
+	persistedSeqs   map[string]*persistedSeq
+
+	persistQueueMu  sync.RWMutex
+	persistQueue    chan queuedCharacterSave
+	persistQueueEnd chan struct{}
+)
+
+type persistedSeq struct {
+	mu  sync.Mutex
+	seq uint64
+}
+
+func snapshotCharacter(c *Character) (characterSnapshot, error) {
+	ensureCharacterDefaults(c)
+	// World loops tick Effects while the rest of the character is encoded.
+	effectsMu.Lock()
+	data, err := json.Marshal(c)
+	effectsMu.Unlock()
+	if err != nil {
+		return characterSnapshot{}, err
+	}
+	return characterSnapshot{name: c.Name, seq: characterSnapshotSeq.Add(1), data: data}, nil
+}
+
+// writeCharacterSnapshot stores snap unless a newer snapshot of the same
+// character has already been stored.
+func writeCharacterSnapshot(snap characterSnapshot) error {
+	key := sanitizeCharacterName(snap.name)
+	persistedSeqsMu.Lock()
+	if persistedSeqs == nil {
+		persistedSeqs = map[string]*persistedSeq{}
+	}
+	last := persistedSeqs[key]
+	if last == nil {
+		last = &persistedSeq{}
+		persistedSeqs[key] = last
+	}
+	persistedSeqsMu.Unlock()
+
+	last.mu.Lock()
+	defer last.mu.Unlock()
+	if snap.seq <= last.seq {
+		return nil
+	}
+	if err := storeCharacterSnapshot(snap); err != nil {
+		return err
+	}
+	last.seq = snap.seq
+	return nil
+}
+
+// queuePersistCharacter snapshots c now and saves it on the persistence
+// worker; before the worker is started (at startup and in tests) it saves
+// inline. Failures are logged as "Failed to persist <name> after <why>".
+func queuePersistCharacter(c *Character, why string) {
+	if c == nil {
+		return
+	}
+	snap, err := snapshotCharacter(c)
+	if err != nil {
+		log.Printf("Failed to persist %s after %s: %v", c.Name, why, err)
+		return
+	}
+	save := queuedCharacterSave{snap: snap, why: why}
+
+	persistQueueMu.RLock()
+	defer persistQueueMu.RUnlock()
+	if persistQueue == nil {
+		saveQueuedCharacter(save)
+		return
+	}
+	persistQueue <- save
+}
+
+func saveQueuedCharacter(save queuedCharacterSave) {
+	if err := writeCharacterSnapshot(save.snap); err != nil {
+		log.Printf("Failed to persist %s after %s: %v", save.snap.name, save.why, err)
+	}
+}
+
+// startCharacterPersister starts the persistence worker.
+func startCharacterPersister() {
+	persistQueueMu.Lock()
+	defer persistQueueMu.Unlock()
+	if persistQueue != nil {
+		return
+	}
+	queue := make(chan queuedCharacterSave, characterPersistQueueSize)
+	done := make(chan struct{})
+	persistQueue, persistQueueEnd = queue, done
+	go func() {
+		defer close(done)
+		for save := range queue {
+			saveQueuedCharacter(save)
+		}
+	}()
+}
+
+// stopCharacterPersister writes everything still queued and stops the
+// worker; later saves run inline again.
+func stopCharacterPersister() {
+	persistQueueMu.Lock()
+	queue, done := persistQueue, persistQueueEnd
+	persistQueue, persistQueueEnd = nil, nil
+	persistQueueMu.Unlock()
+	if queue == nil {
+		return
+	}
+	close(queue)
+	<-done
+}
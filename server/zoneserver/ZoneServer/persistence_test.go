@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestActivePersistenceMode(t *testing.T) {
@@ -450,6 +451,60 @@ func TestLoadCharacterJSONModeKeepsExistingClassOnAuthHint(t *testing.T) {
 	}
 }
 
+func TestQueuedSavesNeverOverwriteNewerOnes(t *testing.T) {
+	t.Cleanup(resetPersistenceRuntimeStateForTests)
+	resetPersistenceRuntimeStateForTests()
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+
+	restoreWD := enterTempDir(t)
+	defer restoreWD()
+
+	c := MockCharacter()
+	c.Name = "QueuedHero"
+	c.Level = 10
+	stale, err := snapshotCharacter(c)
+	if err != nil {
+		t.Fatalf("snapshotCharacter: %v", err)
+	}
+	c.Level = 11
+	if err := persistCharacter(c); err != nil {
+		t.Fatalf("persistCharacter: %v", err)
+	}
+	if err := writeCharacterSnapshot(stale); err != nil {
+		t.Fatalf("writeCharacterSnapshot: %v", err)
+	}
+	if loaded, found, err := loadExistingCharacter(c.Name); err != nil || !found || loaded.Level != 11 {
+		t.Fatalf("expected the older snapshot to be skipped, got %#v found=%v err=%v", loaded, found, err)
+	}
+
+	startCharacterPersister()
+	c.Level = 12
+	queuePersistCharacter(c, "a test")
+	c.Level = 13 // changes after queueing are not part of the save
+	stopCharacterPersister()
+	if loaded, _, err := loadExistingCharacter(c.Name); err != nil || loaded.Level != 12 {
+		t.Fatalf("expected the worker to save the queued snapshot, got %#v err=%v", loaded, err)
+	}
+}
+
+func TestSnapshotWhileEffectsChange(t *testing.T) {
+	s := &ClientSession{Character: MockCharacter()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		now := time.Now()
+		for i := 0; i < 200; i++ {
+			applySessionEffect(s, "bleeding", "test", 1, time.Minute, now.Add(time.Duration(i)*time.Millisecond), &mobOutbox{})
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		if _, err := snapshotCharacter(s.Character); err != nil {
+			t.Fatalf("snapshotCharacter: %v", err)
+		}
+	}
+	<-done
+}
+
 func enterTempDir(t *testing.T) func() {
 	t.Helper()
 	wd, err := os.Getwd()
@@ -57,6 +57,25 @@ func applyDeathPenalty(c *Character, at Position) {
 	}
 }
 
+// damageCharacter is the single path for damage taken by a character, from
+// players and mobs alike. Damage is mitigated by the character's defense
+// first, then soaked by damage shields; a killing blow applies the death
+// penalty and leaves the character on half HP. It returns the damage actually
+// dealt.
+func damageCharacter(c *Character, at Position, damage int) (dealt int, died bool) {
+	damage = absorbCharacterDamage(c, mitigateDamage(damage, characterDefense(c)))
+	c.HP -= damage
+	if c.HP > 0 {
+		return damage, false
+	}
+	applyDeathPenalty(c, at)
+	c.HP = c.MaxHP / 2
+	if c.HP < 1 {
+		c.HP = 1
+	}
+	return damage, true
+}
+
 func recoverCorpse(c *Character) bool {
 	if c.Corpse == nil {
 		return false
@@ -66,50 +85,51 @@ func recoverCorpse(c *Character) bool {
 	return true
 }
 
-func calculateAttack(c *Character, targetLevel int) (damage int, died bool) {
+// calculateAttack resolves one swing by c at a target of targetLevel with
+// targetEvasion. Accuracy against evasion decides a hit; a hit's damage is
+// level, gear and STR power plus variance, companion bonuses and, on a crit,
+// the class crit multiplier. Fights well above c's level may kill c whether
+// or not the swing lands, less often the more evasive c is.
+func calculateAttack(c *Character, targetLevel, targetEvasion int) AttackResult {
 	if targetLevel < 1 {
 		targetLevel = 1
 	}
-
-	gearAtk := 0
-	for _, item := range c.Inventory {
-		if c.Equipped[item.Slot] != item.ID {
-			continue
-		}
-		ensureItemDefaults(&item)
-		gearAtk += item.Grade * 2
-		gearAtk += maxInt(0, item.GearLevel-1)
-		if item.Rarity == RarityEpic {
-			gearAtk += 2
-		}
-		if item.Rarity == RarityUnique {
-			gearAtk += 4
-		}
-	}
-
-	bonusPct := 0
-	if c.Pet.Summoned {
-		bonusPct += 5
+	stats := deriveStats(c)
+
+	result := AttackResult{
+		Outcome:        AttackMiss,
+		HitChance:      hitChancePct(stats.Accuracy, targetEvasion),
+		CritChance:     stats.CritChance,
+		Base:           stats.Attack,
+		Power:          stats.Power,
+		BonusPct:       stats.AttackBonusPct,
+		CritMultiplier: 100,
 	}
-	if c.Mercenary.Recruited {
-		bonusPct += 8
+	if randIntn(100) < result.HitChance {
+		result.Outcome = AttackHit
+		result.Variance = randIntn(8)
+		damage := result.Base + result.Power + result.Variance
+		damage += damage * stats.AttackBonusPct / 100
+		if randIntn(100) >= 100-stats.CritChance {
+			result.Outcome = AttackCrit
+			result.CritMultiplier = stats.CritMultiplier
+			damage = damage * stats.CritMultiplier / 100
+		}
+		result.Damage = damage
 	}
 
-	base := (c.Level * 2) + 12 + gearAtk
-	damage = base + randIntn(8)
-	damage += damage * bonusPct / 100
-
 	risk := targetLevel - c.Level
 	if risk > 4 {
 		chance := 0.15 + (float64(risk-4) * 0.06)
 		if chance > 0.8 {
 			chance = 0.8
 		}
+		chance = chance * deathEvasionBase / float64(deathEvasionBase+maxInt(stats.Evasion, 0))
 		if randFloat64() < chance {
-			died = true
+			result.Died = true
 		}
 	}
-	return damage, died
+	return result
 }
 
 func maybeLegendaryDrop(c *Character) *Item {
@@ -1,81 +1,101 @@
 package main
 
-func applyPvPPenalty(attacker *Character, victimLevel int) map[string]interface{} {
+func applyPvPPenalty(attacker *Character, victimLevel int, region Region) map[string]interface{} {
 	levelDiff := attacker.Level - victimLevel
+	pct := pvpPenaltyPct[region.Kind]
 	penalty := map[string]interface{}{
-		"level_diff": levelDiff,
-		"xp_debt":    0,
-		"pk_gain":    0,
-		"honor_loss": 0,
+		"level_diff":  levelDiff,
+		"region":      region.Kind,
+		"penalty_pct": pct,
+		"xp_debt":     0,
+		"pk_gain":     0,
+		"honor_loss":  0,
 	}
 
-	if levelDiff >= 10 {
-		debt := 80 + levelDiff*5
-		attacker.XPDebt += debt
-		attacker.PKScore += 3
-		attacker.Honor -= 20
-		penalty["xp_debt"] = debt
-		penalty["pk_gain"] = 3
-		penalty["honor_loss"] = 20
-		return penalty
-	}
-	if levelDiff >= 5 {
-		debt := 35 + levelDiff*4
-		attacker.XPDebt += debt
-		attacker.PKScore += 2
-		attacker.Honor -= 10
-		penalty["xp_debt"] = debt
-		penalty["pk_gain"] = 2
-		penalty["honor_loss"] = 10
-		return penalty
-	}
-	if levelDiff >= 2 {
-		debt := 20 + levelDiff*2
-		attacker.XPDebt += debt
-		attacker.PKScore++
-		attacker.Honor -= 4
-		penalty["xp_debt"] = debt
-		penalty["pk_gain"] = 1
-		penalty["honor_loss"] = 4
+	var debt, pkGain, honorLoss int
+	switch {
+	case levelDiff >= 10:
+		debt, pkGain, honorLoss = 80+levelDiff*5, 3, 20
+	case levelDiff >= 5:
+		debt, pkGain, honorLoss = 35+levelDiff*4, 2, 10
+	case levelDiff >= 2:
+		debt, pkGain, honorLoss = 20+levelDiff*2, 1, 4
+	default:
+		// Near-equal level fight: low penalty and slight honor gain.
+		attacker.Honor += 2
+		penalty["honor_gain"] = 2
 		return penalty
 	}
 
-	// Near-equal level fight: low penalty and slight honor gain.
-	attacker.Honor += 2
-	penalty["honor_gain"] = 2
+	debt = debt * pct / 100
+	pkGain = pkGain * pct / 100
+	honorLoss = honorLoss * pct / 100
+	attacker.XPDebt += debt
+	attacker.PKScore += pkGain
+	attacker.Honor -= honorLoss
+	penalty["xp_debt"] = debt
+	penalty["pk_gain"] = pkGain
+	penalty["honor_loss"] = honorLoss
 	return penalty
 }
 
-func attackPlayer(attacker *ClientSession, victim *ClientSession, skillID string) map[string]interface{} {
-	victimLevel := victim.Character.Level
-	damage, attackerDied := calculateAttack(attacker.Character, victimLevel)
-	damage += skillBonus(attacker.Character, skillID)
-	if damage < 1 {
-		damage = 1
+func attackPlayer(attacker *ClientSession, victim *ClientSession, skillID string) (map[string]interface{}, bool, string) {
+	if sessionStunned(attacker) {
+		return nil, false, "STUNNED"
+	}
+	region := sessionRegion(attacker)
+	if region.Kind == RegionSafe || sessionRegion(victim).Kind == RegionSafe {
+		return nil, false, "SAFE_ZONE"
+	}
+	if sessionCasting(attacker) {
+		return nil, false, "ALREADY_CASTING"
+	}
+	def, skilled := learnedSkill(attacker, skillID)
+	if skilled {
+		if def.CastMS > 0 {
+			return nil, false, "SKILL_NOT_INSTANT"
+		}
+		if ok, reason := useSkill(attacker, def, simTickClock()); !ok {
+			return nil, false, reason
+		}
 	}
 
-	penalty := applyPvPPenalty(attacker.Character, victimLevel)
+	victimLevel := victim.Character.Level
+	attack := calculateAttack(attacker.Character, victimLevel, characterEvasion(victim.Character))
+	element := attackElement(attacker.Character)
+	elementPct := elementalPct(element, armorElement(victim.Character), nil)
 
-	// Apply direct damage to victim and death penalty flow.
-	victim.Character.HP -= damage
-	victimDied := victim.Character.HP <= 0
-	if victimDied {
-		applyDeathPenalty(victim.Character, victim.Position)
-		victim.Character.HP = victim.Character.MaxHP / 2
-		if victim.Character.HP < 1 {
-			victim.Character.HP = 1
-		}
+	penalty := applyPvPPenalty(attacker.Character, victimLevel, region)
+
+	damage, victimDied := 0, false
+	if attack.Landed() {
+		damage = applyElement(attack.Damage+skillBonus(attacker.Character, skillID), elementPct)
+		damage, victimDied = damageCharacter(victim.Character, victim.Position, damage)
 	}
 
+	attackerDied := attack.Died
 	if attackerDied {
 		applyDeathPenalty(attacker.Character, attacker.Position)
 	}
+	if skilled && attack.Landed() {
+		var outbox mobOutbox
+		target := victim
+		if victimDied {
+			target = nil
+		}
+		applySkillEffect(attacker, skillID, nil, target, simTickClock(), &outbox)
+		outbox.flush()
+	}
 
 	return map[string]interface{}{
 		"target":        victim.Character.Name,
 		"target_level":  victimLevel,
 		"damage":        damage,
+		"element":       element,
+		"effectiveness": effectivenessLabel(elementPct),
 		"skill_id":      skillID,
+		"outcome":       attack.Outcome,
+		"attack":        attack,
 		"attacker_died": attackerDied,
 		"target_died":   victimDied,
 		"penalty":       penalty,
@@ -88,5 +108,5 @@ func attackPlayer(attacker *ClientSession, victim *ClientSession, skillID string
 			"hp":      victim.Character.HP,
 			"xp_debt": victim.Character.XPDebt,
 		},
-	}
+	}, true, "OK"
 }
@@ -1,19 +1,34 @@
 package main
 
-import (
-	"fmt"
-	"strings"
-)
+import "fmt"
 
-func trustDelta(choice string) int {
-	switch strings.ToLower(strings.TrimSpace(choice)) {
-	case "honor", "help", "protect":
-		return 15
-	case "ignore":
-		return -5
-	default:
-		return 5
+// canAcceptQuest applies the ACCEPT_QUEST rules without changing c.
+func canAcceptQuest(c *Character, questID string) (Quest, bool, string) {
+	q, exists := quests[questID]
+	if !exists {
+		return Quest{}, false, "QUEST_NOT_FOUND"
 	}
+	if q.Hidden && c.Trust[q.RequiredNPC] < q.MinTrust {
+		return Quest{}, false, "QUEST_HIDDEN"
+	}
+	if q.MinLevel > c.Level {
+		return Quest{}, false, "LEVEL_TOO_LOW"
+	}
+	return q, true, "OK"
+}
+
+func acceptQuest(c *Character, questID string) (Quest, bool, string) {
+	q, ok, reason := canAcceptQuest(c, questID)
+	if !ok {
+		return q, false, reason
+	}
+	cur := c.Quests[questID]
+	if cur == nil {
+		cur = &QuestProgress{}
+	}
+	cur.Accepted = true
+	c.Quests[questID] = cur
+	return q, true, "OK"
 }
 
 func applyQuestCompletion(c *Character, questID string) (map[string]interface{}, bool, string) {
@@ -201,11 +201,8 @@ func handleChatSayEvent(p ChatSayPayload) {
 	senderPos := Position{X: p.X, Y: p.Y, Z: p.Z}
 	worldID := WorldID(p.WorldID)
 
-	forEachSession(func(other *ClientSession) {
-		if !other.Authenticated || other.Character == nil || other.World == nil {
-			return
-		}
-		if other.World.ID != worldID {
+	forEachSessionInWorld(worldID, func(other *ClientSession) {
+		if !other.Authenticated || other.Character == nil {
 			return
 		}
 		if other.Character.Name != p.From && !isBlocked(other.Character.Name, p.From) {
@@ -231,11 +228,8 @@ func handleChatWorldEvent(p ChatWorldPayload) {
 	}
 	worldID := WorldID(p.WorldID)
 
-	forEachSession(func(other *ClientSession) {
-		if !other.Authenticated || other.Character == nil || other.World == nil {
-			return
-		}
-		if other.World.ID != worldID {
+	forEachSessionInWorld(worldID, func(other *ClientSession) {
+		if !other.Authenticated || other.Character == nil {
 			return
 		}
 		// don't send to blocked
@@ -0,0 +1,81 @@
+package main
+
+const (
+	RegionSafe   = "safe"
+	RegionNormal = "normal"
+	RegionFFA    = "ffa"
+)
+
+// Region is an axis-aligned X/Z area of a world with its own PvP rules.
+// Anything outside every listed region is the world's normal wilderness.
+type Region struct {
+	ID   string
+	Name string
+	Kind string
+	Min  Position
+	Max  Position
+}
+
+func (r Region) contains(p Position) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Z >= r.Min.Z && p.Z <= r.Max.Z
+}
+
+var wildernessRegion = Region{ID: "wilds", Name: "Wilds", Kind: RegionNormal}
+
+var worldRegions = map[WorldID][]Region{
+	World1: {
+		{ID: "w1_town", Name: "Rowan's Rest", Kind: RegionSafe, Min: Position{X: -30, Z: -30}, Max: Position{X: 30, Z: 30}},
+		{ID: "w1_blood_ring", Name: "Blood Ring", Kind: RegionFFA, Min: Position{X: 200, Z: 200}, Max: Position{X: 260, Z: 260}},
+	},
+	World2: {
+		{ID: "w2_refuge", Name: "Shattered Refuge", Kind: RegionSafe, Min: Position{X: -25, Z: -25}, Max: Position{X: 25, Z: 25}},
+	},
+	World3: {
+		{ID: "w3_sanctum", Name: "Warden's Sanctum", Kind: RegionSafe, Min: Position{X: -20, Z: -20}, Max: Position{X: 20, Z: 20}},
+		{ID: "w3_myth_wastes", Name: "Mythic Wastes", Kind: RegionFFA, Min: Position{X: 900, Z: 900}, Max: Position{X: 1100, Z: 1100}},
+	},
+}
+
+// pvpPenaltyPct scales the level-gap PvP penalty by region kind.
+var pvpPenaltyPct = map[string]int{
+	RegionNormal: 100,
+	RegionFFA:    0,
+}
+
+func regionAt(worldID WorldID, pos Position) Region {
+	for _, r := range worldRegions[worldID] {
+		if r.contains(pos) {
+			return r
+		}
+	}
+	return wildernessRegion
+}
+
+func sessionRegion(s *ClientSession) Region {
+	if s.World == nil {
+		return wildernessRegion
+	}
+	return regionAt(s.World.ID, s.Position)
+}
+
+func regionPayload(r Region) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   r.ID,
+		"name": r.Name,
+		"kind": r.Kind,
+	}
+}
+
+// syncSessionRegion sends REGION_CHANGED when the session's position now
+// falls in a different region than the last one it was told about.
+func syncSessionRegion(s *ClientSession) {
+	r := sessionRegion(s)
+	if r.ID == s.RegionID {
+		return
+	}
+	previous := s.RegionID
+	s.RegionID = r.ID
+	payload := regionPayload(r)
+	payload["previous"] = previous
+	sendMessage(s.Conn, ServerMessage{Command: RespRegionChanged, Payload: payload})
+}
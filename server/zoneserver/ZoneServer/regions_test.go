@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+func TestAttackPlayerRespectsRegionRules(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	attacker, _ := newVisibilityTestSession("Raider", worlds[World1], Position{X: 5, Y: 0, Z: 5})
+	victim, _ := newVisibilityTestSession("Villager", worlds[World1], Position{X: 40, Y: 0, Z: 40})
+	defer unregisterSession(attacker)
+	defer unregisterSession(victim)
+	attacker.Character.Level = 60
+	victim.Character.Level = 45
+
+	if _, ok, reason := attackPlayer(attacker, victim, ""); ok || reason != "SAFE_ZONE" {
+		t.Fatalf("expected SAFE_ZONE from town, got ok=%v reason=%s", ok, reason)
+	}
+	attacker.Position = Position{X: 45, Y: 0, Z: 45}
+	victim.Position = Position{X: 10, Y: 0, Z: 10}
+	if _, ok, reason := attackPlayer(attacker, victim, ""); ok || reason != "SAFE_ZONE" {
+		t.Fatalf("expected SAFE_ZONE against a victim in town, got ok=%v reason=%s", ok, reason)
+	}
+
+	victim.Position = Position{X: 50, Y: 0, Z: 50}
+	withFixedRandIntn(0, func() {
+		result, ok, reason := attackPlayer(attacker, victim, "")
+		if !ok {
+			t.Fatalf("expected PvP in the wilds, got %s", reason)
+		}
+		penalty := toMap(result["penalty"])
+		if toInt(penalty, "xp_debt") == 0 || toString(penalty, "region") != RegionNormal {
+			t.Fatalf("expected full level-gap penalty in the wilds, got %#v", penalty)
+		}
+	})
+
+	debt := attacker.Character.XPDebt
+	attacker.Position = Position{X: 220, Y: 0, Z: 220}
+	victim.Position = Position{X: 225, Y: 0, Z: 225}
+	withFixedRandIntn(0, func() {
+		result, ok, reason := attackPlayer(attacker, victim, "")
+		if !ok {
+			t.Fatalf("expected PvP in the free-for-all ring, got %s", reason)
+		}
+		penalty := toMap(result["penalty"])
+		if toInt(penalty, "xp_debt") != 0 || toString(penalty, "region") != RegionFFA {
+			t.Fatalf("expected no penalty in free-for-all, got %#v", penalty)
+		}
+	})
+	if attacker.Character.XPDebt != debt {
+		t.Fatalf("expected XP debt unchanged in free-for-all, got %d -> %d", debt, attacker.Character.XPDebt)
+	}
+}
+
+func TestMoveAcrossRegionBoundarySendsRegionChanged(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	s, conn := newVisibilityTestSession("Strider", worlds[World1], Position{X: 25, Y: 0, Z: 0})
+	defer unregisterSession(s)
+	s.RegionID = "w1_town"
+	bound := "Strider"
+	visible := map[*ClientSession]bool{}
+
+	handleClientCommand(conn, s, visible, "region-peer", &bound, ReqMove, map[string]interface{}{"x": 29.0, "y": 0.0, "z": 0.0})
+	if hasMessage(conn.DrainMessages(t), RespRegionChanged, nil) {
+		t.Fatalf("expected no REGION_CHANGED while inside town")
+	}
+
+	handleClientCommand(conn, s, visible, "region-peer", &bound, ReqMove, map[string]interface{}{"x": 35.0, "y": 0.0, "z": 0.0})
+	var changed map[string]interface{}
+	for _, msg := range conn.DrainMessages(t) {
+		if msg.Command == RespRegionChanged {
+			changed = toMap(msg.Payload)
+		}
+	}
+	if toString(changed, "id") != "wilds" || toString(changed, "previous") != "w1_town" || toString(changed, "kind") != RegionNormal {
+		t.Fatalf("expected REGION_CHANGED into the wilds, got %#v", changed)
+	}
+}
+
+func TestMobsIgnorePlayersInSafeRegions(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	s, _ := newVisibilityTestSession("Sheltered", worlds[World1], Position{X: 10, Y: 0, Z: 10})
+	defer unregisterSession(s)
+	hp := s.Character.HP
+
+	withWorldMob(World1, "mob_wolf_01", &MobEntity{
+		ID: "mob_wolf_01", Name: "Rift Wolf", WorldID: World1, Level: 42, HP: 100, MaxHP: 100,
+		Position: Position{X: 12, Y: 0, Z: 10}, RespawnSec: 8,
+	}, func() {
+		processServerTick()
+		if s.Character.HP != hp {
+			t.Fatalf("expected no mob damage inside a safe region, HP %d -> %d", hp, s.Character.HP)
+		}
+
+		s.Position = Position{X: 60, Y: 0, Z: 60}
+		worldMobsForTests(World1)["mob_wolf_01"].Position = Position{X: 62, Y: 0, Z: 60}
+		processServerTick()
+		if s.Character.HP >= hp {
+			t.Fatalf("expected mob to attack outside the safe region")
+		}
+	})
+}
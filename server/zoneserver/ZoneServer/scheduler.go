@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the scheduler's time source; tests substitute a fake one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Scheduled event kinds, dispatched through scheduledHandlers.
+const (
+	eventMobRespawn   = "mob_respawn"
+	eventEffectExpire = "effect_expire"
+)
+
+const (
+	schedulerResolution = 250 * time.Millisecond
+	schedulerWheelSize  = 512 // slots; one revolution covers 128s
+)
+
+// ScheduledEvent is one delayed world event. Key identifies it for
+// replacement and cancellation; Subject is kind-specific (a mob ID for
+// respawns).
+type ScheduledEvent struct {
+	Key     string    `json:"key"`
+	Kind    string    `json:"kind"`
+	WorldID WorldID   `json:"world_id"`
+	Subject string    `json:"subject"`
+	DueAt   time.Time `json:"due_at"`
+
+	tick      int64
+	cancelled bool
+}
+
+// Scheduler is a hashed timer wheel. Events land in the slot for the tick
+// they are due on; events more than one revolution out share a slot with
+// nearer ones and are skipped until their own tick comes round. It is driven
+// by runScheduledEvents from the server tick, never by its own goroutines.
+type Scheduler struct {
+	mu     sync.Mutex
+	clock  Clock
+	origin time.Time
+	cursor int64 // next wheel tick to process
+	slots  [][]*ScheduledEvent
+	byKey  map[string]*ScheduledEvent
+}
+
+func newScheduler(clock Clock) *Scheduler {
+	return &Scheduler{
+		clock:  clock,
+		origin: clock.Now(),
+		slots:  make([][]*ScheduledEvent, schedulerWheelSize),
+		byKey:  map[string]*ScheduledEvent{},
+	}
+}
+
+var worldScheduler = newScheduler(systemClock{})
+
+// scheduledHandlers runs due events by kind, outside the scheduler lock.
+var scheduledHandlers = map[string]func(ScheduledEvent){
+	eventMobRespawn:   func(evt ScheduledEvent) { respawnMob(evt.WorldID, evt.Subject) },
+	eventEffectExpire: expireEffect,
+	eventBossSpawn:    spawnWorldBoss,
+	eventBossDespawn:  despawnWorldBoss,
+}
+
+// Schedule queues evt to fire after delay, replacing any pending event with
+// the same key.
+func (s *Scheduler) Schedule(evt ScheduledEvent, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evt.DueAt = s.clock.Now().Add(delay)
+	s.insertLocked(evt)
+}
+
+// ScheduleAt is Schedule with an absolute due time; past times fire on the
+// next Due.
+func (s *Scheduler) ScheduleAt(evt ScheduledEvent, dueAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evt.DueAt = dueAt
+	s.insertLocked(evt)
+}
+
+func (s *Scheduler) insertLocked(evt ScheduledEvent) {
+	if old, ok := s.byKey[evt.Key]; ok {
+		old.cancelled = true
+	}
+	tick := int64((evt.DueAt.Sub(s.origin) + schedulerResolution - 1) / schedulerResolution)
+	if tick < s.cursor {
+		tick = s.cursor
+	}
+	e := evt
+	e.tick = tick
+	e.cancelled = false
+	slot := tick % schedulerWheelSize
+	s.slots[slot] = append(s.slots[slot], &e)
+	s.byKey[e.Key] = &e
+}
+
+// Cancel drops the pending event with key and reports whether there was one.
+func (s *Scheduler) Cancel(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evt, ok := s.byKey[key]
+	if !ok {
+		return false
+	}
+	evt.cancelled = true
+	delete(s.byKey, key)
+	return true
+}
+
+// CancelWorld drops every pending event for worldID, e.g. when a dungeon
+// instance is destroyed.
+func (s *Scheduler) CancelWorld(worldID WorldID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, evt := range s.byKey {
+		if evt.WorldID == worldID {
+			evt.cancelled = true
+			delete(s.byKey, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Due advances the wheel to the clock's current time and removes and returns
+// every event that has come due, oldest first.
+func (s *Scheduler) Due() []ScheduledEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := int64(s.clock.Now().Sub(s.origin) / schedulerResolution)
+	if now < s.cursor {
+		return nil
+	}
+	steps := now - s.cursor + 1
+	if steps > schedulerWheelSize {
+		steps = schedulerWheelSize
+	}
+	due := make([]ScheduledEvent, 0)
+	for i := int64(0); i < steps; i++ {
+		slot := (s.cursor + i) % schedulerWheelSize
+		kept := s.slots[slot][:0]
+		for _, evt := range s.slots[slot] {
+			switch {
+			case evt.cancelled:
+			case evt.tick <= now:
+				delete(s.byKey, evt.Key)
+				due = append(due, *evt)
+			default:
+				kept = append(kept, evt)
+			}
+		}
+		s.slots[slot] = kept
+	}
+	s.cursor = now + 1
+	sortScheduledEvents(due)
+	return due
+}
+
+// Pending returns a snapshot of the events still waiting, soonest first.
+func (s *Scheduler) Pending() []ScheduledEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ScheduledEvent, 0, len(s.byKey))
+	for _, evt := range s.byKey {
+		out = append(out, *evt)
+	}
+	sortScheduledEvents(out)
+	return out
+}
+
+func sortScheduledEvents(events []ScheduledEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].DueAt.Equal(events[j].DueAt) {
+			return events[i].DueAt.Before(events[j].DueAt)
+		}
+		return events[i].Key < events[j].Key
+	})
+}
+
+// runScheduledEvents fires everything due on worldScheduler. Called from the
+// server tick before the mob AI runs.
+func runScheduledEvents() {
+	for _, evt := range worldScheduler.Due() {
+		handler, ok := scheduledHandlers[evt.Kind]
+		if !ok {
+			log.Printf("scheduler: no handler for %s event %s", evt.Kind, evt.Key)
+			continue
+		}
+		handler(evt)
+	}
+}
+
+// killMobForRestore marks a mob with a restored respawn as dead and reports
+// whether this node hosts it.
+func killMobForRestore(worldID WorldID, mobID string) bool {
+	w := worldSimFor(worldID)
+	if w == nil {
+		return false
+	}
+	found := false
+	w.do(func(w *worldSim, _ *mobOutbox) {
+		if mob, ok := w.mobs[mobID]; ok {
+			mob.HP = 0
+			found = true
+		}
+	})
+	return found
+}
+
+func mobRespawnKey(worldID WorldID, mobID string) string {
+	return fmt.Sprintf("%s:%d:%s", eventMobRespawn, worldID, mobID)
+}
+
+// scheduleMobRespawn queues mobID in worldID to come back after wait.
+func scheduleMobRespawn(worldID WorldID, mobID string, wait time.Duration) {
+	worldScheduler.Schedule(ScheduledEvent{
+		Key:     mobRespawnKey(worldID, mobID),
+		Kind:    eventMobRespawn,
+		WorldID: worldID,
+		Subject: mobID,
+	}, wait)
+}
+
+// saveSchedulerState writes the pending events to path so a restart can pick
+// them up with restoreSchedulerState.
+func saveSchedulerState(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(worldScheduler.Pending(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// restoreSchedulerState requeues events saved by saveSchedulerState at their
+// original due times. Respawns are only restored for mobs this node still
+// hosts, and those mobs stay dead until the event fires. The file is removed
+// once loaded so a crash cannot replay it.
+func restoreSchedulerState(path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var events []ScheduledEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	restored := 0
+	for _, evt := range events {
+		if _, ok := scheduledHandlers[evt.Kind]; !ok {
+			continue
+		}
+		if evt.Kind == eventMobRespawn && !killMobForRestore(evt.WorldID, evt.Subject) {
+			continue
+		}
+		worldScheduler.ScheduleAt(evt, evt.DueAt)
+		restored++
+	}
+
+	if err := os.Remove(path); err != nil {
+		log.Printf("scheduler: could not remove %s after restore: %v", path, err)
+	}
+	return restored, nil
+}
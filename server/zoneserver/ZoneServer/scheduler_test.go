@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// withFakeScheduler swaps worldScheduler for one driven by a fake clock.
+func withFakeScheduler(fn func(clock *fakeClock)) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	orig := worldScheduler
+	worldScheduler = newScheduler(clock)
+	defer func() { worldScheduler = orig }()
+	fn(clock)
+}
+
+func dueKeys(events []ScheduledEvent) []string {
+	keys := make([]string, 0, len(events))
+	for _, evt := range events {
+		keys = append(keys, evt.Key)
+	}
+	return keys
+}
+
+func TestSchedulerFiresInOrderAndHonoursCancellation(t *testing.T) {
+	withFakeScheduler(func(clock *fakeClock) {
+		s := worldScheduler
+		s.Schedule(ScheduledEvent{Key: "late", Kind: eventMobRespawn}, 3*time.Second)
+		s.Schedule(ScheduledEvent{Key: "early", Kind: eventMobRespawn}, time.Second)
+		s.Schedule(ScheduledEvent{Key: "cancelled", Kind: eventMobRespawn}, 2*time.Second)
+		// Further out than one wheel revolution, so it shares a slot.
+		s.Schedule(ScheduledEvent{Key: "next_lap", Kind: eventMobRespawn}, schedulerResolution*schedulerWheelSize+time.Second)
+
+		if !s.Cancel("cancelled") || s.Cancel("cancelled") {
+			t.Fatalf("expected cancel to succeed exactly once")
+		}
+		if got := s.Due(); len(got) != 0 {
+			t.Fatalf("expected nothing due yet, got %v", dueKeys(got))
+		}
+
+		clock.Advance(5 * time.Second)
+		got := dueKeys(s.Due())
+		if len(got) != 2 || got[0] != "early" || got[1] != "late" {
+			t.Fatalf("expected early then late, got %v", got)
+		}
+
+		s.Schedule(ScheduledEvent{Key: "late", Kind: eventMobRespawn}, time.Second)
+		s.Schedule(ScheduledEvent{Key: "late", Kind: eventMobRespawn}, 10*time.Second)
+		clock.Advance(2 * time.Second)
+		if got := s.Due(); len(got) != 0 {
+			t.Fatalf("expected rescheduling a key to replace it, got %v", dueKeys(got))
+		}
+		if pending := dueKeys(s.Pending()); len(pending) != 2 || pending[0] != "late" || pending[1] != "next_lap" {
+			t.Fatalf("unexpected pending events %v", pending)
+		}
+
+		clock.Advance(schedulerResolution * schedulerWheelSize)
+		if got := dueKeys(s.Due()); len(got) != 2 || got[0] != "late" || got[1] != "next_lap" {
+			t.Fatalf("expected both remaining events after a full lap, got %v", got)
+		}
+	})
+}
+
+func TestKilledMobRespawnsThroughScheduler(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	player, _ := newVisibilityTestSession("Reaper", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(player)
+
+	withFakeScheduler(func(clock *fakeClock) {
+		mob := aiTestMob(Position{X: 105, Y: 0, Z: 100})
+		mob.HP = 1
+		withWorldMob(World1, "mob_wolf_01", mob, func() {
+			if result, ok, _ := attackMob(player, "mob_wolf_01", ""); !ok || result["defeated"] != true {
+				t.Fatalf("expected the mob to die, got %#v", result)
+			}
+			pending := worldScheduler.Pending()
+			if len(pending) != 1 || pending[0].Key != mobRespawnKey(World1, "mob_wolf_01") {
+				t.Fatalf("expected one pending respawn, got %#v", pending)
+			}
+
+			clock.Advance(time.Duration(mob.RespawnSec-1) * time.Second)
+			runScheduledEvents()
+			if mob.HP != 0 {
+				t.Fatalf("expected the mob to stay dead before respawn_sec")
+			}
+			clock.Advance(2 * time.Second)
+			runScheduledEvents()
+			if mob.HP != mob.MaxHP || len(worldScheduler.Pending()) != 0 {
+				t.Fatalf("expected the mob back at full HP, got hp=%d", mob.HP)
+			}
+		})
+	})
+}
+
+func TestSchedulerStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler", "zone-test.json")
+	withFakeScheduler(func(clock *fakeClock) {
+		mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+		withWorldMob(World1, "mob_wolf_01", mob, func() {
+			scheduleMobRespawn(World1, "mob_wolf_01", 5*time.Second)
+			scheduleMobRespawn(World1, "mob_gone_01", 5*time.Second)
+			if err := saveSchedulerState(path); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			worldScheduler = newScheduler(clock)
+			restored, err := restoreSchedulerState(path)
+			if err != nil || restored != 1 {
+				t.Fatalf("expected only the known mob's respawn restored, got n=%d err=%v", restored, err)
+			}
+			if mob.HP != 0 {
+				t.Fatalf("expected the restored mob to stay dead until its respawn")
+			}
+			if again, _ := restoreSchedulerState(path); again != 0 {
+				t.Fatalf("expected the state file to be consumed, restored %d again", again)
+			}
+
+			clock.Advance(6 * time.Second)
+			runScheduledEvents()
+			if mob.HP != mob.MaxHP {
+				t.Fatalf("expected the restored respawn to fire, got hp=%d", mob.HP)
+			}
+		})
+	})
+}
+
+func TestAdminSchedulerEndpoint(t *testing.T) {
+	withFakeScheduler(func(clock *fakeClock) {
+		scheduleMobRespawn(World1, "mob_wolf_01", 4*time.Second)
+		mux := http.NewServeMux()
+		registerAdminEndpoints(mux, "s3cret")
+
+		do := func(method, target, token string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			return rec
+		}
+
+		if rec := do(http.MethodGet, "/admin/scheduler", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 without the admin token, got %d", rec.Code)
+		}
+		rec := do(http.MethodGet, "/admin/scheduler", "s3cret")
+		var body struct {
+			Pending int                      `json:"pending"`
+			Events  []map[string]interface{} `json:"events"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		if body.Pending != 1 || body.Events[0]["kind"] != eventMobRespawn || body.Events[0]["due_in_ms"] != float64(4000) {
+			t.Fatalf("unexpected scheduler listing %#v", body)
+		}
+
+		key := mobRespawnKey(World1, "mob_wolf_01")
+		if rec := do(http.MethodDelete, "/admin/scheduler?key="+key, "s3cret"); rec.Code != http.StatusOK {
+			t.Fatalf("expected cancel to succeed, got %d %s", rec.Code, rec.Body.String())
+		}
+		if rec := do(http.MethodDelete, "/admin/scheduler?key="+key, "s3cret"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected a second cancel to 404, got %d", rec.Code)
+		}
+	})
+
+	mux := http.NewServeMux()
+	registerAdminEndpoints(mux, "")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the admin api to be off without a token, got %d", rec.Code)
+	}
+}
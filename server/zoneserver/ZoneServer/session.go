@@ -1,6 +1,8 @@
 package main
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +17,38 @@ type ClientSession struct {
 	AuthFailures  int
 	WindowStart   time.Time
 	WindowCount   int
+	// HandingOff marks a session that is reconnecting to another node, so
+	// its disconnect must not tear down party state.
+	HandingOff bool
+	// RegionID is the region the client was last told it stands in.
+	RegionID string
+	// dialogue is the open NPC conversation, if any; see talkToNPC.
+	dialogue *dialogueCursor
+
+	// visible is the connection's visibility set. The read loop holds
+	// actionMu while handling a command, and server-initiated actions such
+	// as forced world moves take it too, so the two never interleave.
+	visible  map[*ClientSession]bool
+	actionMu sync.Mutex
+
+	// posted is character work queued by other goroutines, such as a world
+	// loop landing a cast, to run in order under actionMu; see post. serving
+	// is set once the read loop runs; until then (and in tests) work posted
+	// to the session runs inline.
+	postMu  sync.Mutex
+	posted  []func()
+	posting bool
+	serving atomic.Bool
+
+	// stunnedUntil is the sim-clock time, in Unix nanoseconds, until which
+	// a mob stun keeps the player from moving or attacking. It is written by
+	// world loops and read by the connection's read loop.
+	stunnedUntil atomic.Int64
+
+	// casting is set while a cast-time skill is pending on the world sim;
+	// see startSkillCast. The skill pool and cooldowns are per character;
+	// see skillStateOf.
+	casting atomic.Bool
 }
 
 func NewSession(conn WSConn) *ClientSession {
@@ -43,3 +77,41 @@ func (s *ClientSession) allowCommand(now time.Time) bool {
 	}
 	return s.WindowCount <= limit
 }
+
+// post runs fn under actionMu on the session's action path, after anything
+// posted before it. World loops use it to change characters they don't own,
+// so a character is only ever written by one goroutine at a time.
+func (s *ClientSession) post(fn func()) {
+	if !s.serving.Load() {
+		s.actionMu.Lock()
+		defer s.actionMu.Unlock()
+		fn()
+		return
+	}
+	s.postMu.Lock()
+	s.posted = append(s.posted, fn)
+	start := !s.posting
+	s.posting = true
+	s.postMu.Unlock()
+	if start {
+		go s.runPosted()
+	}
+}
+
+func (s *ClientSession) runPosted() {
+	for {
+		s.postMu.Lock()
+		if len(s.posted) == 0 {
+			s.posting = false
+			s.postMu.Unlock()
+			return
+		}
+		fn := s.posted[0]
+		s.posted = s.posted[1:]
+		s.postMu.Unlock()
+
+		s.actionMu.Lock()
+		fn()
+		s.actionMu.Unlock()
+	}
+}
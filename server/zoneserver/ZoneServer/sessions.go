@@ -5,24 +5,45 @@ import "sync"
 var (
 	sessions       = make(map[*ClientSession]bool)
 	sessionsByName = make(map[string]*ClientSession)
-	sessionsMu     sync.RWMutex
+	// sessionsByWorld indexes live sessions by the world they currently occupy.
+	// Say-chat delivery, party proximity and visibility all scan this index, so
+	// a session must only change worlds through moveSessionToWorld.
+	sessionsByWorld = make(map[WorldID]map[*ClientSession]bool)
+	sessionWorldOf  = make(map[*ClientSession]WorldID)
+	sessionsMu      sync.RWMutex
 )
 
 func registerSession(s *ClientSession) {
 	sessionsMu.Lock()
 	defer sessionsMu.Unlock()
 	sessions[s] = true
+	if s.World != nil {
+		indexSessionWorldLocked(s, s.World.ID)
+	}
 }
 
 func unregisterSession(s *ClientSession) {
+	var leftWorld WorldID
+	defer func() {
+		if leftWorld != 0 {
+			if s.Character != nil {
+				clearPlayerThreat(leftWorld, s.Character.Name)
+			}
+			releaseDungeonIfEmpty(leftWorld)
+		}
+	}()
 	sessionsMu.Lock()
 	defer sessionsMu.Unlock()
 	delete(sessions, s)
+	leftWorld = sessionWorldOf[s]
+	unindexSessionWorldLocked(s)
 	if s.Character != nil && s.Character.Name != "" {
 		if s.Authenticated {
 			markCharacterOffline(s.Character.Name)
 		}
-		go handleSocialDisconnect(s.Character.Name)
+		if !s.HandingOff {
+			go handleSocialDisconnect(s.Character.Name)
+		}
 	}
 	if s.Character != nil && s.Character.Name != "" {
 		if current, ok := sessionsByName[s.Character.Name]; ok && current == s {
@@ -31,6 +52,59 @@ func unregisterSession(s *ClientSession) {
 	}
 }
 
+func indexSessionWorldLocked(s *ClientSession, worldID WorldID) {
+	unindexSessionWorldLocked(s)
+	members := sessionsByWorld[worldID]
+	if members == nil {
+		members = make(map[*ClientSession]bool)
+		sessionsByWorld[worldID] = members
+	}
+	members[s] = true
+	sessionWorldOf[s] = worldID
+}
+
+func unindexSessionWorldLocked(s *ClientSession) {
+	worldID, ok := sessionWorldOf[s]
+	if !ok {
+		return
+	}
+	delete(sessionWorldOf, s)
+	members := sessionsByWorld[worldID]
+	delete(members, s)
+	if len(members) == 0 {
+		delete(sessionsByWorld, worldID)
+	}
+}
+
+// moveSessionToWorld points s at w and re-homes it in the per-world index.
+// Unregistered sessions only have their World field updated.
+func moveSessionToWorld(s *ClientSession, w *World) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s.World = w
+	if !sessions[s] {
+		return
+	}
+	if w == nil {
+		unindexSessionWorldLocked(s)
+		return
+	}
+	indexSessionWorldLocked(s, w.ID)
+}
+
+func sessionInWorld(s *ClientSession, worldID WorldID) bool {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	current, ok := sessionWorldOf[s]
+	return ok && current == worldID
+}
+
+func countSessionsInWorld(worldID WorldID) int {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	return len(sessionsByWorld[worldID])
+}
+
 func getSessions() []*ClientSession {
 	sessionsMu.RLock()
 	defer sessionsMu.RUnlock()
@@ -50,6 +124,14 @@ func forEachSession(fn func(*ClientSession)) {
 	}
 }
 
+func forEachSessionInWorld(worldID WorldID, fn func(*ClientSession)) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	for s := range sessionsByWorld[worldID] {
+		fn(s)
+	}
+}
+
 func bindSessionCharacterName(s *ClientSession, name string) {
 	if name == "" {
 		return
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisWorldOwnerKeyPrefix = "zoneserver:world_owner:"
+const redisTransferNonceKeyPrefix = "zoneserver:transfer_nonce:"
+const redisWorldOwnerTTL = 90 * time.Second
+
+const transferTicketIssuer = "projecta3-zone-transfer"
+const transferTicketVersion = 1
+const transferTicketTTL = 30 * time.Second
+
+// zoneNode is this process's identity within the shard map. An empty owned
+// set means the node runs every world, which is the single-node default.
+type zoneNode struct {
+	ID         string
+	PublicAddr string
+	Owned      map[WorldID]bool
+}
+
+// WorldOwner is the ownership record advertised in Redis for a world.
+type WorldOwner struct {
+	NodeID string `json:"node_id"`
+	Addr   string `json:"addr"`
+}
+
+type transferClaims struct {
+	Username string  `json:"username"`
+	WorldID  WorldID `json:"world_id"`
+	FromNode string  `json:"from_node"`
+	ToNode   string  `json:"to_node"`
+	Nonce    string  `json:"nonce"`
+	Iss      string  `json:"iss"`
+	Ver      int     `json:"ver"`
+	Iat      int64   `json:"iat"`
+	Exp      int64   `json:"exp"`
+}
+
+var (
+	localNode = zoneNode{ID: "zone-local", Owned: map[WorldID]bool{}}
+
+	// lookupWorldOwner resolves which node runs a world; tests swap it out.
+	lookupWorldOwner = redisWorldOwner
+
+	transferNonceMu   sync.Mutex
+	usedTransferNonce = map[string]time.Time{}
+)
+
+func configureSharding(cfg ZoneConfig) {
+	node := zoneNode{ID: cfg.NodeID, PublicAddr: cfg.PublicAddr, Owned: map[WorldID]bool{}}
+	if node.ID == "" {
+		node.ID = localNode.ID
+	}
+	for _, id := range cfg.OwnedWorlds {
+		node.Owned[WorldID(id)] = true
+	}
+	localNode = node
+}
+
+func ownsWorld(id WorldID) bool {
+	if len(localNode.Owned) == 0 {
+		return true
+	}
+	return localNode.Owned[id]
+}
+
+func ownedWorldIDs() []WorldID {
+	ids := make([]WorldID, 0)
+	for id := range worlds {
+		if ownsWorld(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func worldOwnerRedisKey(id WorldID) string {
+	return fmt.Sprintf("%s%d", redisWorldOwnerKeyPrefix, id)
+}
+
+// advertiseWorldOwnership publishes this node as owner of its worlds. It runs
+// at startup and on the presence ticker so the records outlive their TTL only
+// while the node is alive.
+func advertiseWorldOwnership() {
+	if rdb == nil {
+		return
+	}
+	data, _ := json.Marshal(WorldOwner{NodeID: localNode.ID, Addr: localNode.PublicAddr})
+	for _, id := range ownedWorldIDs() {
+		key := worldOwnerRedisKey(id)
+		if current, ok := redisWorldOwner(id); ok && current.NodeID != localNode.ID {
+			log.Printf("world %d is already advertised by node %s; node %s is overriding it", id, current.NodeID, localNode.ID)
+		}
+		if err := rdb.Set(redisCtx, key, data, redisWorldOwnerTTL).Err(); err != nil {
+			log.Printf("failed to advertise ownership of world %d: %v", id, err)
+		}
+	}
+}
+
+// withdrawWorldOwnership drops the records this node still holds on shutdown.
+func withdrawWorldOwnership() {
+	if rdb == nil {
+		return
+	}
+	for _, id := range ownedWorldIDs() {
+		if current, ok := redisWorldOwner(id); ok && current.NodeID == localNode.ID {
+			_ = rdb.Del(redisCtx, worldOwnerRedisKey(id)).Err()
+		}
+	}
+}
+
+func redisWorldOwner(id WorldID) (WorldOwner, bool) {
+	if rdb == nil {
+		return WorldOwner{}, false
+	}
+	raw, err := rdb.Get(redisCtx, worldOwnerRedisKey(id)).Result()
+	if err != nil {
+		return WorldOwner{}, false
+	}
+	var owner WorldOwner
+	if err := json.Unmarshal([]byte(raw), &owner); err != nil || owner.NodeID == "" || owner.Addr == "" {
+		return WorldOwner{}, false
+	}
+	return owner, true
+}
+
+func issueTransferTicket(username string, worldID WorldID, toNode string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	claims := transferClaims{
+		Username: username,
+		WorldID:  worldID,
+		FromNode: localNode.ID,
+		ToNode:   toNode,
+		Nonce:    hex.EncodeToString(nonce),
+		Iss:      transferTicketIssuer,
+		Ver:      transferTicketVersion,
+		Iat:      now.Unix(),
+		Exp:      now.Add(transferTicketTTL).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payloadEnc := base64.RawURLEncoding.EncodeToString(payload)
+	return payloadEnc + "." + signTokenPayload(payloadEnc, authSecret()), nil
+}
+
+// validateTransferTicket checks a ticket minted by another node for this one
+// and burns its nonce, so each ticket admits exactly one connection.
+func validateTransferTicket(ticket string) (transferClaims, error) {
+	parts := strings.Split(strings.TrimSpace(ticket), ".")
+	if len(parts) != 2 {
+		return transferClaims{}, errors.New("invalid ticket format")
+	}
+	expected := signTokenPayload(parts[0], authSecret())
+	if !hmac.Equal([]byte(parts[1]), []byte(expected)) {
+		return transferClaims{}, errors.New("invalid ticket signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return transferClaims{}, fmt.Errorf("decode payload: %w", err)
+	}
+	var claims transferClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return transferClaims{}, fmt.Errorf("invalid payload: %w", err)
+	}
+	if claims.Iss != transferTicketIssuer || claims.Ver != transferTicketVersion {
+		return transferClaims{}, errors.New("invalid ticket issuer")
+	}
+	if strings.TrimSpace(claims.Username) == "" || claims.Nonce == "" {
+		return transferClaims{}, errors.New("missing ticket claims")
+	}
+	if claims.ToNode != localNode.ID || !ownsWorld(claims.WorldID) {
+		return transferClaims{}, errors.New("ticket issued for another node")
+	}
+	if time.Now().UTC().Unix() > claims.Exp {
+		return transferClaims{}, errors.New("ticket expired")
+	}
+	if !consumeTransferNonce(claims.Nonce, time.Unix(claims.Exp, 0)) {
+		return transferClaims{}, errors.New("ticket already used")
+	}
+	return claims, nil
+}
+
+func consumeTransferNonce(nonce string, expiresAt time.Time) bool {
+	ttl := time.Until(expiresAt) + time.Second
+	if rdb != nil {
+		ok, err := rdb.SetNX(redisCtx, redisTransferNonceKeyPrefix+nonce, localNode.ID, ttl).Result()
+		if err != nil {
+			log.Printf("failed to record transfer nonce: %v", err)
+			return false
+		}
+		return ok
+	}
+
+	transferNonceMu.Lock()
+	defer transferNonceMu.Unlock()
+	now := time.Now()
+	for n, exp := range usedTransferNonce {
+		if now.After(exp) {
+			delete(usedTransferNonce, n)
+		}
+	}
+	if _, used := usedTransferNonce[nonce]; used {
+		return false
+	}
+	usedTransferNonce[nonce] = now.Add(ttl)
+	return true
+}
+
+// handOffToWorldOwner moves an authenticated session to the node that owns
+// target: the character is saved in the new world, a single-use transfer
+// ticket is issued, and the connection is closed after WORLD_HANDOFF so the
+// client can reconnect to the owner with AUTH_TOKEN {ticket}.
+func handOffToWorldOwner(session *ClientSession, visible map[*ClientSession]bool, target *World) (bool, string) {
+	owner, ok := lookupWorldOwner(target.ID)
+	if !ok || owner.NodeID == localNode.ID {
+		return false, "WORLD_UNAVAILABLE"
+	}
+
+	previousWorld := session.Character.WorldID
+	session.Character.WorldID = target.ID
+	if err := persistSessionState(session); err != nil {
+		log.Printf("Failed to persist character %s before handoff: %v", session.Character.Name, err)
+		session.Character.WorldID = previousWorld
+		return false, "HANDOFF_FAILED"
+	}
+	ticket, err := issueTransferTicket(session.Character.Name, target.ID, owner.NodeID)
+	if err != nil {
+		session.Character.WorldID = previousWorld
+		return false, "HANDOFF_FAILED"
+	}
+
+	leaveWorldViewers(session, visible)
+	session.HandingOff = true
+	session.Active = false
+	sendMessage(session.Conn, ServerMessage{Command: RespWorldHandoff, Payload: map[string]interface{}{
+		"world_id":       target.ID,
+		"world":          target.Name,
+		"node_id":        owner.NodeID,
+		"addr":           owner.Addr,
+		"ticket":         ticket,
+		"expires_in_sec": int(transferTicketTTL.Seconds()),
+	}})
+	log.Printf("handing %s off to node %s for world %d", session.Character.Name, owner.NodeID, target.ID)
+	return true, "OK"
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func withShardNode(node zoneNode, owners map[WorldID]WorldOwner, fn func()) {
+	origNode, origLookup := localNode, lookupWorldOwner
+	localNode = node
+	lookupWorldOwner = func(id WorldID) (WorldOwner, bool) {
+		owner, ok := owners[id]
+		return owner, ok
+	}
+	defer func() {
+		localNode, lookupWorldOwner = origNode, origLookup
+	}()
+	fn()
+}
+
+func TestEnterWorldOwnedElsewhereHandsOffWithSingleUseTicket(t *testing.T) {
+	resetSocialStateForTests()
+	resetPersistenceRuntimeStateForTests()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	t.Setenv("A3_AUTH_SECRET", "test-auth-secret")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	worlds = DefaultWorlds()
+
+	owners := map[WorldID]WorldOwner{
+		World1: {NodeID: "node-a", Addr: "ws://node-a/ws"},
+		World2: {NodeID: "node-b", Addr: "ws://node-b/ws"},
+	}
+	nodeA := zoneNode{ID: "node-a", Owned: map[WorldID]bool{World1: true}}
+	nodeB := zoneNode{ID: "node-b", Owned: map[WorldID]bool{World2: true}}
+
+	var ticket string
+	withShardNode(nodeA, owners, func() {
+		session, conn := newVisibilityTestSession("Wayfarer", worlds[World1], DefaultSpawnPosition(World1))
+		defer unregisterSession(session)
+		session.Character.Level = 60
+		session.Character.UnlockedWorlds[World2] = true
+
+		bound := "Wayfarer"
+		handleClientCommand(conn, session, map[*ClientSession]bool{}, "shard-peer", &bound, ReqEnterWorld, map[string]interface{}{"world_id": 2})
+		msgs := conn.DrainMessages(t)
+		if len(msgs) != 1 || msgs[0].Command != RespWorldHandoff {
+			t.Fatalf("expected a single WORLD_HANDOFF, got %#v", msgs)
+		}
+		handoff := toMap(msgs[0].Payload)
+		if toString(handoff, "node_id") != "node-b" || toString(handoff, "addr") != "ws://node-b/ws" {
+			t.Fatalf("expected handoff to node-b, got %#v", handoff)
+		}
+		ticket = toString(handoff, "ticket")
+		if session.Active || !session.HandingOff || session.World.ID != World1 {
+			t.Fatalf("expected session closed for handoff without entering world 2 locally")
+		}
+		saved, err := loadCharacter("Wayfarer", "")
+		if err != nil || saved.WorldID != World2 {
+			t.Fatalf("expected character persisted in world 2 before handoff, got %#v err=%v", saved, err)
+		}
+	})
+
+	withShardNode(nodeB, owners, func() {
+		conn := &captureConn{}
+		session := NewSession(conn)
+		session.Character = MockCharacter()
+		ensureCharacterDefaults(session.Character)
+		session.World = worlds[World1]
+		registerSession(session)
+		defer unregisterSession(session)
+
+		bound := ""
+		handleClientCommand(conn, session, map[*ClientSession]bool{}, "shard-peer-b", &bound, ReqAuthToken, map[string]interface{}{"ticket": ticket})
+		msgs := conn.DrainMessages(t)
+		if len(msgs) != 3 || msgs[0].Command != RespAuthOK {
+			t.Fatalf("expected ticket auth to succeed, got %#v", msgs)
+		}
+		if session.World.ID != World2 || session.Character.Name != "Wayfarer" {
+			t.Fatalf("expected Wayfarer admitted to world 2, got %s in %d", session.Character.Name, session.World.ID)
+		}
+
+		replay := NewSession(&captureConn{})
+		replay.Character = MockCharacter()
+		replay.World = worlds[World1]
+		replayConn := replay.Conn.(*captureConn)
+		replayBound := ""
+		handleClientCommand(replayConn, replay, map[*ClientSession]bool{}, "shard-peer-c", &replayBound, ReqAuthToken, map[string]interface{}{"ticket": ticket})
+		if !hasMessage(replayConn.DrainMessages(t), RespAuthRejected, "TICKET_INVALID") {
+			t.Fatalf("expected replayed ticket to be rejected")
+		}
+	})
+}
+
+func TestEnterWorldWithoutOwnerIsUnavailable(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	withShardNode(zoneNode{ID: "node-a", Owned: map[WorldID]bool{World1: true}}, map[WorldID]WorldOwner{}, func() {
+		session, conn := newVisibilityTestSession("Stranded", worlds[World1], DefaultSpawnPosition(World1))
+		defer unregisterSession(session)
+		session.Character.Level = 60
+		session.Character.UnlockedWorlds[World2] = true
+
+		bound := "Stranded"
+		handleClientCommand(conn, session, map[*ClientSession]bool{}, "shard-peer", &bound, ReqEnterWorld, map[string]interface{}{"world_id": 2})
+		if !hasMessage(conn.DrainMessages(t), RespEnterDenied, "WORLD_UNAVAILABLE") {
+			t.Fatalf("expected WORLD_UNAVAILABLE when no node owns the world")
+		}
+		if !session.Active || session.Character.WorldID != World1 {
+			t.Fatalf("expected session to stay put in world 1")
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Skill target shapes.
+const (
+	SkillShapeSingle = "single" // the mob_id target only
+	SkillShapeCone   = "cone"   // a cone from the caster toward mob_id
+	SkillShapeCircle = "circle" // a circle around the caster
+	SkillShapeGround = "ground" // a circle around a chosen position in Range
+)
+
+// AttackMobRequest is the ATTACK_MOB payload. Position aims ground-targeted
+// skills.
+type AttackMobRequest struct {
+	MobID    string    `json:"mob_id"`
+	SkillID  string    `json:"skill_id"`
+	Position *Position `json:"position"`
+}
+
+func skillShape(def SkillDefinition) string {
+	if def.Shape == "" {
+		return SkillShapeSingle
+	}
+	return def.Shape
+}
+
+// areaSkill returns skillID's definition if s has learned it and it hits an
+// area rather than a single mob.
+func areaSkill(s *ClientSession, skillID string) (SkillDefinition, bool) {
+	def, ok := learnedSkill(s, skillID)
+	return def, ok && skillShape(def) != SkillShapeSingle
+}
+
+// areaTargetsLocked picks the mobs an area skill hits, nearest the centre
+// first, up to def.MaxTargets. Cones need mobID to aim at; ground skills
+// need a position within def.Range.
+func areaTargetsLocked(w *worldSim, s *ClientSession, def SkillDefinition, mobID string, ground *Position) ([]*MobEntity, string) {
+	center := s.Position
+	var aim *MobEntity
+	switch skillShape(def) {
+	case SkillShapeCone:
+		var reason string
+		if aim, reason = attackableMobLocked(w, s, mobID); aim == nil {
+			return nil, reason
+		}
+		if distance2D(s.Position, aim.Position) > def.Radius {
+			return nil, "MOB_OUT_OF_RANGE"
+		}
+	case SkillShapeGround:
+		if ground == nil {
+			return nil, "POSITION_REQUIRED"
+		}
+		if distance2D(s.Position, *ground) > def.Range {
+			return nil, "POSITION_OUT_OF_RANGE"
+		}
+		center = *ground
+	}
+
+	targets := make([]*MobEntity, 0)
+	for _, mob := range w.mobs {
+		if mob.HP <= 0 || mob.AIState == MobStateReturn || !isVisible(s.Position, mob.Position) {
+			continue
+		}
+		if distance2D(center, mob.Position) > def.Radius {
+			continue
+		}
+		if aim != nil && !inCone(s.Position, aim.Position, mob.Position, def.Angle) {
+			continue
+		}
+		targets = append(targets, mob)
+	}
+	if len(targets) == 0 {
+		return nil, "NO_TARGETS"
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		di, dj := distance2D(center, targets[i].Position), distance2D(center, targets[j].Position)
+		if di != dj {
+			return di < dj
+		}
+		return targets[i].ID < targets[j].ID
+	})
+	if def.MaxTargets > 0 && len(targets) > def.MaxTargets {
+		targets = targets[:def.MaxTargets]
+	}
+	return targets, "OK"
+}
+
+// inCone reports whether p lies within the angle-degree cone from origin
+// toward aim on the X/Z plane.
+func inCone(origin, aim, p Position, angle float64) bool {
+	ax, az := aim.X-origin.X, aim.Z-origin.Z
+	px, pz := p.X-origin.X, p.Z-origin.Z
+	alen, plen := math.Hypot(ax, az), math.Hypot(px, pz)
+	if alen == 0 || plen == 0 {
+		return true
+	}
+	cos := (ax*px + az*pz) / (alen * plen)
+	return cos >= math.Cos(angle/2*math.Pi/180)-1e-9
+}
+
+// attackAreaInWorld resolves an area skill, hitting each target as
+// attackMobInWorld would, with kills and loot handled per mob. The skill has
+// already been paid for.
+func attackAreaInWorld(w *worldSim, outbox *mobOutbox, s *ClientSession, def SkillDefinition, mobID string, ground *Position) (map[string]interface{}, bool, string) {
+	targets, reason := areaTargetsLocked(w, s, def, mobID, ground)
+	if targets == nil {
+		return nil, false, reason
+	}
+	hits := make([]map[string]interface{}, 0, len(targets))
+	defeated, xpGain := 0, 0
+	status := "OK"
+	for _, mob := range targets {
+		hit, ok, _ := attackMobInWorld(w, outbox, s, mob.ID, def.ID)
+		if !ok {
+			continue
+		}
+		hits = append(hits, hit)
+		if hit["status"] == "PLAYER_DIED" {
+			status = "PLAYER_DIED"
+			break // the caster fell mid-sweep
+		}
+		if hit["defeated"] == true {
+			defeated++
+			xpGain += toInt(hit, "xp_gain")
+		}
+	}
+	result := map[string]interface{}{
+		"skill_id": def.ID,
+		"shape":    skillShape(def),
+		"status":   status,
+		"targets":  hits,
+		"hits":     len(hits),
+		"defeated": defeated,
+		"xp_gain":  xpGain,
+	}
+	if ground != nil {
+		result["position"] = *ground
+	}
+	if status == "PLAYER_DIED" {
+		outbox.post(s, func() { // after the death penalty attackMobInWorld posted
+			result["xp_debt"] = s.Character.XPDebt
+			result["corpse"] = s.Character.Corpse
+		})
+	}
+	return result, true, "OK"
+}
+
+// landSkillLocked resolves an attack with skillID, over an area or on mobID.
+func landSkillLocked(w *worldSim, outbox *mobOutbox, s *ClientSession, mobID, skillID string, ground *Position) (map[string]interface{}, bool, string) {
+	if def, ok := areaSkill(s, skillID); ok {
+		return attackAreaInWorld(w, outbox, s, def, mobID, ground)
+	}
+	return attackMobInWorld(w, outbox, s, mobID, skillID)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func areaTestMob(id string, pos Position) *MobEntity {
+	mob := aiTestMob(pos)
+	mob.ID = id
+	mob.HP, mob.MaxHP = 100000, 100000
+	return mob
+}
+
+func hitMobIDs(result map[string]interface{}) []string {
+	ids := make([]string, 0)
+	for _, hit := range result["targets"].([]map[string]interface{}) {
+		ids = append(ids, toString(hit, "mob_id"))
+	}
+	return ids
+}
+
+func TestConeAndCircleSkillsHitSeveralMobs(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	warrior, _ := newVisibilityTestSession("Sweeper", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(warrior)
+	warrior.Character.Class = "Warrior"
+	warrior.Character.Skills = map[string]int{"cleave": 1, "battle_rush": 1}
+	warrior.Character.HP, warrior.Character.MaxHP = 100000, 100000
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			withEmptyWorld(World1, func(w *worldSim) {
+				for _, mob := range []*MobEntity{
+					areaTestMob("ahead", Position{X: 105, Y: 0, Z: 100}),
+					areaTestMob("flank", Position{X: 104, Y: 0, Z: 103}),
+					areaTestMob("behind", Position{X: 95, Y: 0, Z: 100}),
+					areaTestMob("far", Position{X: 120, Y: 0, Z: 100}),
+				} {
+					w.mobs[mob.ID] = mob
+				}
+
+				if _, ok, reason := attackMob(warrior, "far", "cleave"); ok || reason != "MOB_OUT_OF_RANGE" {
+					t.Fatalf("expected a cone aimed out of reach to be rejected, got ok=%v reason=%s", ok, reason)
+				}
+				result, ok, reason := attackMob(warrior, "ahead", "cleave")
+				if !ok {
+					t.Fatalf("cleave failed: %s", reason)
+				}
+				ids := hitMobIDs(result)
+				if result["shape"] != SkillShapeCone || len(ids) != 2 || ids[0] != "ahead" || ids[1] != "flank" {
+					t.Fatalf("expected cleave to hit the mobs in front, got %v", ids)
+				}
+				if w.mobs["behind"].HP != w.mobs["behind"].MaxHP || w.mobs["far"].HP != w.mobs["far"].MaxHP {
+					t.Fatalf("expected mobs outside the cone to be untouched")
+				}
+
+				// Battle Rush hits up to 3 mobs around the warrior, nearest first.
+				w.mobs["extra"] = areaTestMob("extra", Position{X: 101, Y: 0, Z: 101})
+				result, ok, reason = attackMob(warrior, "", "battle_rush")
+				if !ok {
+					t.Fatalf("battle_rush failed: %s", reason)
+				}
+				if ids := hitMobIDs(result); len(ids) != 3 || ids[0] != "extra" || result["hits"] != 3 {
+					t.Fatalf("expected battle_rush to hit the 3 nearest mobs, got %v", ids)
+				}
+
+				clock.Advance(10 * time.Second)
+				warrior.Position = Position{X: 300, Y: 0, Z: 300}
+				if _, ok, reason := attackMob(warrior, "", "battle_rush"); ok || reason != "NO_TARGETS" {
+					t.Fatalf("expected NO_TARGETS with nothing around, got ok=%v reason=%s", ok, reason)
+				}
+				if _, current := skillResource(warrior); current < 50 {
+					t.Fatalf("expected a skill with no targets not to be paid for, got %d rage", current)
+				}
+			})
+		})
+	})
+}
+
+func TestGroundTargetedCastHitsAndKillsEachMob(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	mage, conn := newVisibilityTestSession("Novaist", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(mage)
+	mage.Character.Class = "Mage"
+	mage.Character.Skills = map[string]int{"cataclysm_nova": 1}
+	mage.Character.HP, mage.Character.MaxHP = 100000, 100000
+
+	withFakeScheduler(func(_ *fakeClock) {
+		withSimClock(func(clock *fakeClock) {
+			withFixedRandIntn(0, func() {
+				withEmptyWorld(World1, func(w *worldSim) {
+					w.tickRate = 100 * time.Millisecond
+					weak := areaTestMob("weak", Position{X: 120, Y: 0, Z: 100})
+					weak.HP = 1
+					w.mobs["weak"] = weak
+					w.mobs["tough"] = areaTestMob("tough", Position{X: 122, Y: 0, Z: 102})
+					w.mobs["outside"] = areaTestMob("outside", Position{X: 100, Y: 0, Z: 120})
+
+					if _, ok, reason := attackMob(mage, "", "cataclysm_nova"); ok || reason != "POSITION_REQUIRED" {
+						t.Fatalf("expected POSITION_REQUIRED, got ok=%v reason=%s", ok, reason)
+					}
+					if _, ok, reason := attackMobAt(mage, "", "cataclysm_nova", &Position{X: 150, Y: 0, Z: 100}); ok || reason != "POSITION_OUT_OF_RANGE" {
+						t.Fatalf("expected POSITION_OUT_OF_RANGE, got ok=%v reason=%s", ok, reason)
+					}
+					result, ok, reason := attackMobAt(mage, "", "cataclysm_nova", &Position{X: 121, Y: 0, Z: 101})
+					if !ok || result["status"] != "CASTING" {
+						t.Fatalf("expected the nova to start casting, got ok=%v reason=%s %#v", ok, reason, result)
+					}
+					conn.DrainMessages(t)
+
+					clock.Advance(2 * time.Second)
+					processServerTick()
+					msgs := conn.DrainMessages(t)
+					landed := toMap(lastMessage(msgs, RespMobAttackResult).Payload)
+					targets, _ := landed["targets"].([]interface{})
+					if len(targets) != 2 || toInt(landed, "defeated") != 1 || toInt(landed, "xp_gain") <= 0 {
+						t.Fatalf("expected the nova to hit both mobs and kill one, got %#v", landed)
+					}
+					if weak.HP > 0 || w.mobs["tough"].HP >= w.mobs["tough"].MaxHP || w.mobs["outside"].HP != w.mobs["outside"].MaxHP {
+						t.Fatalf("unexpected HP after the nova: weak=%d tough=%d outside=%d", weak.HP, w.mobs["tough"].HP, w.mobs["outside"].HP)
+					}
+				})
+			})
+		})
+	})
+}
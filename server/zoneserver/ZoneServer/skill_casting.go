@@ -0,0 +1,294 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Class resources that skills spend.
+const (
+	ResourceMana  = "mana"
+	ResourceRage  = "rage"
+	ResourceFocus = "focus"
+)
+
+// maxSkillResource is the size of every class's resource pool.
+const maxSkillResource = 100
+
+type classResource struct {
+	Kind        string
+	RegenPerSec float64
+}
+
+var classResources = map[string]classResource{
+	"Warrior":        {Kind: ResourceRage, RegenPerSec: 4},
+	"Mage":           {Kind: ResourceMana, RegenPerSec: 6},
+	"Archer":         {Kind: ResourceFocus, RegenPerSec: 8},
+	"Healing Knight": {Kind: ResourceMana, RegenPerSec: 5},
+}
+
+func resourceForClass(class string) classResource {
+	if r, ok := classResources[canonicalCharacterClass(class)]; ok {
+		return r
+	}
+	return classResources["Archer"]
+}
+
+// skillState is a character's skill resource pool and cooldowns. It is kept
+// in memory per character rather than per connection, so reconnecting
+// neither clears cooldowns nor refills the pool; pruneSkillStates drops it
+// once the character is offline and it has nothing left to remember.
+type skillState struct {
+	mu      sync.Mutex
+	spent   float64 // 0 is a full pool
+	readyAt map[string]time.Time
+	// regenAt is the sim-clock time the pool last regenerated, at
+	// regenPerSec.
+	regenAt     time.Time
+	regenPerSec float64
+}
+
+// settled reports whether st would be a full pool with every skill ready by
+// now, which is no different from a fresh state. The caller holds st.mu.
+func (st *skillState) settled(now time.Time) bool {
+	for _, at := range st.readyAt {
+		if now.Before(at) {
+			return false
+		}
+	}
+	if st.spent <= 0 {
+		return true
+	}
+	if st.regenPerSec <= 0 || st.regenAt.IsZero() {
+		return false
+	}
+	full := st.regenAt.Add(time.Duration(st.spent / st.regenPerSec * float64(time.Second)))
+	return !now.Before(full)
+}
+
+var (
+	skillStatesMu sync.Mutex
+	skillStates   = map[string]*skillState{}
+)
+
+// skillStateOf returns the skill state of s's character.
+func skillStateOf(s *ClientSession) *skillState {
+	skillStatesMu.Lock()
+	defer skillStatesMu.Unlock()
+	st := skillStates[s.Character.Name]
+	if st == nil {
+		st = &skillState{}
+		skillStates[s.Character.Name] = st
+	}
+	return st
+}
+
+// pruneSkillStates forgets the skill state of characters with no session on
+// this server whose cooldowns have passed and whose pool has refilled.
+func pruneSkillStates(now time.Time) {
+	online := map[string]bool{}
+	forEachSession(func(s *ClientSession) {
+		if s.Character != nil {
+			online[s.Character.Name] = true
+		}
+	})
+	skillStatesMu.Lock()
+	defer skillStatesMu.Unlock()
+	for name, st := range skillStates {
+		if online[name] {
+			continue
+		}
+		st.mu.Lock()
+		settled := st.settled(now)
+		st.mu.Unlock()
+		if settled {
+			delete(skillStates, name)
+		}
+	}
+}
+
+// skillCast is a cast-time skill waiting to land on a mob, on an area for
+// area skills, or on the player ally for support skills.
+type skillCast struct {
+	mobID   string
+	skillID string
+	ground  *Position
+	ally    string
+	landsAt time.Time
+}
+
+// rejectCommand is how the caster is told the cast failed.
+func (c *skillCast) rejectCommand() string {
+	if c.ally != "" {
+		return RespSupportRejected
+	}
+	return RespMobAttackRejected
+}
+
+func skillResource(s *ClientSession) (kind string, current int) {
+	r := resourceForClass(s.Character.Class)
+	st := skillStateOf(s)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return r.Kind, int(maxSkillResource - st.spent)
+}
+
+func skillResourcePayload(s *ClientSession) map[string]interface{} {
+	kind, current := skillResource(s)
+	return map[string]interface{}{"kind": kind, "current": current, "max": maxSkillResource}
+}
+
+// learnedSkill returns the definition of skillID if the session's character
+// has at least one rank in it. Unlearned skills make a plain attack.
+func learnedSkill(s *ClientSession, skillID string) (SkillDefinition, bool) {
+	if skillID == "" || s.Character.Skills[skillID] <= 0 {
+		return SkillDefinition{}, false
+	}
+	def, ok := skillListForClass(s.Character.Class)[skillID]
+	return def, ok
+}
+
+// useSkill checks and pays for one use of skillID: it must be off cooldown
+// and affordable. Rejections are SKILL_ON_COOLDOWN or INSUFFICIENT_<RESOURCE>.
+func useSkill(s *ClientSession, def SkillDefinition, now time.Time) (bool, string) {
+	r := resourceForClass(s.Character.Class)
+	st := skillStateOf(s)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if now.Before(st.readyAt[def.ID]) {
+		return false, "SKILL_ON_COOLDOWN"
+	}
+	if float64(maxSkillResource)-st.spent < float64(def.Cost) {
+		return false, "INSUFFICIENT_" + strings.ToUpper(r.Kind)
+	}
+	st.spent += float64(def.Cost)
+	if st.readyAt == nil {
+		st.readyAt = map[string]time.Time{}
+	}
+	st.readyAt[def.ID] = now.Add(time.Duration(def.CooldownMS) * time.Millisecond)
+	return true, "OK"
+}
+
+// regenSkillResource refills the pool of s's character for one tick of
+// elapsed, or for all the time since it last regenerated if the character
+// was away for longer.
+func regenSkillResource(s *ClientSession, elapsed time.Duration) {
+	if s.Character == nil {
+		return
+	}
+	st := skillStateOf(s)
+	now := simTickClock()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if away := now.Sub(st.regenAt); !st.regenAt.IsZero() && away > 2*elapsed {
+		elapsed = away
+	}
+	st.regenAt = now
+	st.regenPerSec = resourceForClass(s.Character.Class).RegenPerSec
+	regen := st.regenPerSec * elapsed.Seconds()
+	st.spent = maxFloat(0, st.spent-regen)
+}
+
+func maxFloat(a, b float64) float64 {
+	if a >= b {
+		return a
+	}
+	return b
+}
+
+// startSkillCast pays for a cast-time skill and queues it on the world sim;
+// it lands in resolveSkillCastsLocked once def.CastMS has passed. The
+// caller holds the world lock and has checked the target and that s is not
+// already casting.
+func startSkillCast(w *worldSim, s *ClientSession, mobID string, ground *Position, def SkillDefinition, now time.Time) (map[string]interface{}, bool, string) {
+	if ok, reason := useSkill(s, def, now); !ok {
+		return nil, false, reason
+	}
+	queueSkillCastLocked(w, s, &skillCast{mobID: mobID, skillID: def.ID, ground: ground, landsAt: now.Add(time.Duration(def.CastMS) * time.Millisecond)})
+	result := map[string]interface{}{
+		"mob_id":   mobID,
+		"skill_id": def.ID,
+		"status":   "CASTING",
+		"cast_ms":  def.CastMS,
+		"resource": skillResourcePayload(s),
+	}
+	if mob := w.mobs[mobID]; mob != nil {
+		result["mob"] = mob.Name
+	}
+	if ground != nil {
+		result["position"] = *ground
+	}
+	return result, true, "OK"
+}
+
+// queueSkillCastLocked marks s as casting until cast lands. The caller
+// holds the world lock and has paid for the skill.
+func queueSkillCastLocked(w *worldSim, s *ClientSession, cast *skillCast) {
+	s.casting.Store(true)
+	if w.casts == nil {
+		w.casts = map[*ClientSession]*skillCast{}
+	}
+	w.casts[s] = cast
+}
+
+// resolveSkillCastsLocked lands every cast whose time has come, answering
+// the caster with MOB_ATTACK_RESULT or MOB_ATTACK_REJECTED (SUPPORT_RESULT
+// or SUPPORT_REJECTED for support casts). Results are sent from the
+// caster's action path, after the changes the cast made to their character.
+func resolveSkillCastsLocked(w *worldSim, outbox *mobOutbox) {
+	for s, cast := range w.casts {
+		if w.now.Before(cast.landsAt) {
+			continue
+		}
+		finishSkillCastLocked(w, s)
+		if !s.Active || s.World == nil || s.World.ID != w.ID {
+			continue // left the world mid-cast
+		}
+		if sessionStunned(s) {
+			outbox.send(s, ServerMessage{Command: cast.rejectCommand(), Payload: "CAST_INTERRUPTED"})
+			continue
+		}
+		if cast.ally != "" {
+			landSupportCastLocked(w, outbox, s, cast)
+			continue
+		}
+		result, ok, reason := landSkillLocked(w, outbox, s, cast.mobID, cast.skillID, cast.ground)
+		if !ok {
+			outbox.send(s, ServerMessage{Command: RespMobAttackRejected, Payload: reason})
+			continue
+		}
+		result["resource"] = skillResourcePayload(s)
+		outbox.post(s, func() { // once the cast's own character work has run
+			sendMessage(s.Conn, ServerMessage{Command: RespMobAttackResult, Payload: result})
+			queuePersistCharacter(s.Character, "a cast")
+		})
+	}
+}
+
+func sessionCasting(s *ClientSession) bool {
+	return s.casting.Load()
+}
+
+func finishSkillCastLocked(w *worldSim, s *ClientSession) {
+	delete(w.casts, s)
+	s.casting.Store(false)
+}
+
+// interruptSkillCast cancels the session's pending cast, if any, as moving
+// does. The resource and cooldown stay spent.
+func interruptSkillCast(s *ClientSession) {
+	if !sessionCasting(s) || s.World == nil {
+		return
+	}
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return
+	}
+	w.do(func(w *worldSim, outbox *mobOutbox) {
+		if cast, ok := w.casts[s]; ok {
+			finishSkillCastLocked(w, s)
+			outbox.send(s, ServerMessage{Command: cast.rejectCommand(), Payload: "CAST_INTERRUPTED"})
+		}
+	})
+}
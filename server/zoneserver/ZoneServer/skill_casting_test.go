@@ -0,0 +1,289 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSkillCooldownsAndResources(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	warrior, _ := newVisibilityTestSession("Berserker", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(warrior)
+	warrior.Character.Class = "Warrior"
+	warrior.Character.Skills = map[string]int{"cleave": 1}
+	victim, _ := newVisibilityTestSession("Duelist", worlds[World1], Position{X: 220, Y: 0, Z: 220})
+	defer unregisterSession(victim)
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.HP, mob.MaxHP = 100000, 100000
+			withWorldMob(World1, mob.ID, mob, func() {
+				worldSimFor(World1).tickRate = time.Second
+				warrior.Character.HP, warrior.Character.MaxHP = 100000, 100000
+
+				result, ok, reason := attackMob(warrior, mob.ID, "cleave")
+				if !ok || toInt(toMap(result["resource"]), "current") != 80 {
+					t.Fatalf("expected cleave to cost 20 rage, got ok=%v reason=%s %#v", ok, reason, result)
+				}
+				if _, ok, reason := attackMob(warrior, mob.ID, "cleave"); ok || reason != "SKILL_ON_COOLDOWN" {
+					t.Fatalf("expected SKILL_ON_COOLDOWN, got ok=%v reason=%s", ok, reason)
+				}
+				if _, ok, _ := attackMob(warrior, mob.ID, ""); !ok {
+					t.Fatalf("expected a plain attack to ignore skill cooldowns")
+				}
+				if _, ok, _ := attackMob(warrior, mob.ID, "battle_rush"); !ok {
+					t.Fatalf("expected an unlearned skill to make a plain attack")
+				}
+
+				clock.Advance(4 * time.Second)
+				skillStateOf(warrior).spent = 90
+				if _, ok, reason := attackMob(warrior, mob.ID, "cleave"); ok || reason != "INSUFFICIENT_RAGE" {
+					t.Fatalf("expected INSUFFICIENT_RAGE, got ok=%v reason=%s", ok, reason)
+				}
+				processServerTick()
+				processServerTick()
+				processServerTick()
+				if _, current := skillResource(warrior); current != 22 {
+					t.Fatalf("expected rage to regenerate 4/s over 3 ticks, got %d", current)
+				}
+				if _, ok, reason := attackMob(warrior, mob.ID, "cleave"); !ok {
+					t.Fatalf("expected cleave once rage regenerated, got %s", reason)
+				}
+
+				// PvP spends from the same pool and cooldowns.
+				warrior.Position = Position{X: 215, Y: 0, Z: 215}
+				if _, ok, reason := attackPlayer(warrior, victim, "cleave"); ok || reason != "SKILL_ON_COOLDOWN" {
+					t.Fatalf("expected PvP to share skill cooldowns, got ok=%v reason=%s", ok, reason)
+				}
+			})
+		})
+	})
+}
+
+func TestCastTimeSkillsLandOnTheTickAndMovingInterrupts(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	mage, mageConn := newVisibilityTestSession("Caster", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(mage)
+	mage.Character.Class = "Mage"
+	mage.Character.Skills = map[string]int{"arc_bolt": 1}
+	mage.Character.HP, mage.Character.MaxHP = 100000, 100000
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.HP, mob.MaxHP = 100000, 100000
+			withWorldMob(World1, mob.ID, mob, func() {
+				worldSimFor(World1).tickRate = 500 * time.Millisecond
+
+				result, ok, reason := attackMob(mage, mob.ID, "arc_bolt")
+				if !ok || result["status"] != "CASTING" || mob.HP != mob.MaxHP {
+					t.Fatalf("expected arc_bolt to start casting, got ok=%v reason=%s %#v", ok, reason, result)
+				}
+				if _, ok, reason := attackMob(mage, mob.ID, ""); ok || reason != "ALREADY_CASTING" {
+					t.Fatalf("expected attacks to wait for the cast, got ok=%v reason=%s", ok, reason)
+				}
+				mageConn.DrainMessages(t)
+
+				clock.Advance(500 * time.Millisecond)
+				processServerTick()
+				if mob.HP != mob.MaxHP {
+					t.Fatalf("expected the bolt to still be casting")
+				}
+				clock.Advance(500 * time.Millisecond)
+				processServerTick()
+				landed := lastMessage(mageConn.DrainMessages(t), RespMobAttackResult)
+				if landed.Command != RespMobAttackResult || toString(toMap(landed.Payload), "skill_id") != "arc_bolt" || mob.HP >= mob.MaxHP {
+					t.Fatalf("expected the bolt to land after cast_ms, got %#v", landed)
+				}
+
+				clock.Advance(2 * time.Second)
+				if _, ok, reason := attackMob(mage, mob.ID, "arc_bolt"); !ok {
+					t.Fatalf("expected a second cast after the cooldown, got %s", reason)
+				}
+				hp := mob.HP
+				bound := ""
+				handleClientCommand(mageConn, mage, map[*ClientSession]bool{}, "cast-peer", &bound, ReqMove, map[string]interface{}{"x": 106, "y": 0, "z": 100})
+				if !hasMessage(mageConn.DrainMessages(t), RespMobAttackRejected, "CAST_INTERRUPTED") {
+					t.Fatalf("expected moving to interrupt the cast")
+				}
+				clock.Advance(time.Second)
+				processServerTick()
+				if mob.HP != hp || sessionCasting(mage) {
+					t.Fatalf("expected the interrupted bolt not to land")
+				}
+			})
+		})
+	})
+}
+
+func TestLandedCastRewardsOnTheCastersActionPath(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	mage, mageConn := newVisibilityTestSession("Finisher", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(mage)
+	mage.Character.Class = "Mage"
+	mage.Character.Skills = map[string]int{"arc_bolt": 1}
+	mage.Character.HP, mage.Character.MaxHP = 100000, 100000
+	mage.serving.Store(true) // as if its read loop were running
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.HP = 1
+			withWorldMob(World1, mob.ID, mob, func() {
+				if _, ok, reason := attackMob(mage, mob.ID, "arc_bolt"); !ok {
+					t.Fatalf("cast failed: %s", reason)
+				}
+				mageConn.DrainMessages(t)
+				xp := mage.Character.XP
+
+				// A command in progress holds actionMu; the kill lands on the
+				// mob but the reward waits for the command to finish.
+				mage.actionMu.Lock()
+				clock.Advance(time.Second)
+				processServerTick()
+				msgs := mageConn.DrainMessages(t)
+				if mob.HP > 0 || mage.Character.XP != xp || countMessages(msgs, RespMobAttackResult) != 0 {
+					mage.actionMu.Unlock()
+					t.Fatalf("expected only the mob side to land during the command, got HP=%d XP=%d %#v", mob.HP, mage.Character.XP, msgs)
+				}
+				mage.actionMu.Unlock()
+
+				waitForPosted(t, mage)
+				landed := lastMessage(mageConn.DrainMessages(t), RespMobAttackResult)
+				result := toMap(landed.Payload)
+				if result["defeated"] != true || toInt(result, "xp_gain") <= 0 {
+					t.Fatalf("expected the kill to be reported once posted, got %#v", landed)
+				}
+				if mage.Character.XP == xp && mage.Character.Level == 45 {
+					t.Fatalf("expected the kill XP on the caster")
+				}
+			})
+		})
+	})
+}
+
+// waitForPosted waits until work posted to s has all run.
+func waitForPosted(t *testing.T, s *ClientSession) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		s.postMu.Lock()
+		idle := !s.posting
+		s.postMu.Unlock()
+		if idle {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for posted work on %s", s.Character.Name)
+}
+
+func TestSkillPoolAndCooldownsSurviveReconnect(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	withSimClock(func(clock *fakeClock) {
+		first, _ := newVisibilityTestSession("Returner", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+		first.Character.Class = "Warrior"
+		first.Character.Skills = map[string]int{"cleave": 1}
+		def, _ := learnedSkill(first, "cleave")
+		if ok, reason := useSkill(first, def, clock.Now()); !ok {
+			t.Fatalf("cleave failed: %s", reason)
+		}
+		unregisterSession(first)
+
+		second, _ := newVisibilityTestSession("Returner", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+		defer unregisterSession(second)
+		second.Character.Class = "Warrior"
+		second.Character.Skills = map[string]int{"cleave": 1}
+		if ok, reason := useSkill(second, def, clock.Now()); ok || reason != "SKILL_ON_COOLDOWN" {
+			t.Fatalf("expected the cooldown to survive a reconnect, got ok=%v reason=%s", ok, reason)
+		}
+		if _, current := skillResource(second); current != 80 {
+			t.Fatalf("expected the spent pool to survive a reconnect, got %d", current)
+		}
+
+		// Time away counts toward regeneration once the character is back.
+		regenSkillResource(second, time.Second)
+		clock.Advance(3 * time.Second)
+		regenSkillResource(second, time.Second)
+		if _, current := skillResource(second); current != 96 {
+			t.Fatalf("expected 3s of rage regeneration after the gap, got %d", current)
+		}
+	})
+}
+
+func TestOfflineSkillStateIsForgottenOnceSettled(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	withSimClock(func(clock *fakeClock) {
+		stayer, _ := newVisibilityTestSession("Stayer", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+		defer unregisterSession(stayer)
+		skillStateOf(stayer)
+		player, _ := newVisibilityTestSession("Drifter", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+		player.Character.Class = "Warrior"
+		player.Character.Skills = map[string]int{"cleave": 1}
+		def, _ := learnedSkill(player, "cleave")
+		if ok, reason := useSkill(player, def, clock.Now()); !ok {
+			t.Fatalf("cleave failed: %s", reason)
+		}
+		regenSkillResource(player, 0)
+		unregisterSession(player)
+
+		stateKept := func(name string) bool {
+			skillStatesMu.Lock()
+			defer skillStatesMu.Unlock()
+			return skillStates[name] != nil
+		}
+		// A 4s cooldown and 20 rage to regenerate at 4/s.
+		clock.Advance(2 * time.Second)
+		pruneSkillStates(clock.Now())
+		if !stateKept("Drifter") {
+			t.Fatalf("expected the skill state kept while the cooldown runs")
+		}
+		clock.Advance(2500 * time.Millisecond)
+		pruneSkillStates(clock.Now())
+		if !stateKept("Drifter") {
+			t.Fatalf("expected the skill state kept until the pool refills")
+		}
+		clock.Advance(500 * time.Millisecond)
+		pruneSkillStates(clock.Now())
+		if stateKept("Drifter") {
+			t.Fatalf("expected a settled offline skill state to be forgotten")
+		}
+		if !stateKept("Stayer") {
+			t.Fatalf("expected an online character's skill state to be kept")
+		}
+	})
+}
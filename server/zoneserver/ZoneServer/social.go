@@ -999,7 +999,7 @@ func partyNearbyMembers(session *ClientSession) []*ClientSession {
 		if other == nil || !other.Authenticated || other.Character == nil || other.World == nil {
 			continue
 		}
-		if other.World.ID != session.World.ID {
+		if !sessionInWorld(other, session.World.ID) {
 			continue
 		}
 		if !isVisible(session.Position, other.Position) {
@@ -1090,6 +1090,23 @@ func setPartyReady(member string, ready bool) (map[string]interface{}, bool, str
 	}, true, "OK"
 }
 
+// partyRosterForMember reports the party member belongs to, its leader and
+// whether every member has flagged PARTY_READY.
+func partyRosterForMember(member string) (partyID, leader string, allReady, ok bool) {
+	partyMu.RLock()
+	defer partyMu.RUnlock()
+	pid, inParty := partyByMember[member]
+	if !inParty {
+		return "", "", false, false
+	}
+	p := parties[pid]
+	if p == nil {
+		return "", "", false, false
+	}
+	ready, _ := readySnapshotLocked(p)["all_ready"].(bool)
+	return p.ID, p.Leader, ready, true
+}
+
 func partyStatusForMember(member string) (map[string]interface{}, bool, string) {
 	partyMu.RLock()
 	defer partyMu.RUnlock()
@@ -23,12 +23,18 @@ func resetSocialStateForTests() {
 
 	// Keep test isolation deterministic for helpers that consult online-session state.
 	resetSessionStateForTests()
+
+	skillStatesMu.Lock()
+	skillStates = map[string]*skillState{}
+	skillStatesMu.Unlock()
 }
 
 func resetSessionStateForTests() {
 	sessionsMu.Lock()
 	sessions = map[*ClientSession]bool{}
 	sessionsByName = map[string]*ClientSession{}
+	sessionsByWorld = map[WorldID]map[*ClientSession]bool{}
+	sessionWorldOf = map[*ClientSession]WorldID{}
 	sessionsMu.Unlock()
 }
 
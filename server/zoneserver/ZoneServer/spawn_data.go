@@ -0,0 +1,362 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed spawns/*.json
+var defaultSpawnFiles embed.FS
+
+const (
+	mobTemplatesFile  = "mob_templates.json"
+	spawnGroupsFile   = "spawn_groups.json"
+	worldSpawnsFile   = "world_spawns.json"
+	dungeonSpawnsFile = "dungeon_spawns.json"
+)
+
+// MobTemplate is the shared definition every spawned mob of a kind copies.
+type MobTemplate struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Level      int    `json:"level"`
+	HP         int    `json:"hp"`
+	LootTable  string `json:"loot_table"`
+	RespawnSec int    `json:"respawn_sec"`
+
+	// AttackIntervalMS is the real time between basic attacks, independent
+	// of the server tick rate. Each swing deals DamageMin..DamageMax.
+	AttackIntervalMS int          `json:"attack_interval_ms"`
+	DamageMin        int          `json:"damage_min"`
+	DamageMax        int          `json:"damage_max"`
+	Abilities        []MobAbility `json:"abilities"`
+	// Defense mitigates incoming player damage; see mitigateDamage.
+	Defense int `json:"defense"`
+	// Element is the mob's own element, used for its attacks and against
+	// player attacks; Resistances (percent, negative for a weakness) scale
+	// player damage of each element. See element.go.
+	Element     Element         `json:"element"`
+	Resistances map[Element]int `json:"resistances"`
+	// Training marks a practice dummy: it needs no loot table or attacks.
+	Training bool `json:"training"`
+}
+
+func (t MobTemplate) elementOrNone() Element {
+	if t.Element == "" {
+		return ElementNone
+	}
+	return t.Element
+}
+
+// SpawnArea is the X/Z rectangle a spawn group scatters its mobs across.
+type SpawnArea struct {
+	MinX float64 `json:"min_x"`
+	MinZ float64 `json:"min_z"`
+	MaxX float64 `json:"max_x"`
+	MaxZ float64 `json:"max_z"`
+}
+
+// SpawnGroup places Count mobs of one template inside Area.
+type SpawnGroup struct {
+	ID       string    `json:"id"`
+	Template string    `json:"template"`
+	Count    int       `json:"count"`
+	Area     SpawnArea `json:"area"`
+}
+
+// WorldSpawn lists the spawn groups placed in a world.
+type WorldSpawn struct {
+	WorldID WorldID  `json:"world_id"`
+	Groups  []string `json:"groups"`
+}
+
+// DungeonSpawn lists the spawn groups every instance of a dungeon gets.
+type DungeonSpawn struct {
+	DungeonID string   `json:"dungeon_id"`
+	Groups    []string `json:"groups"`
+}
+
+type SpawnData struct {
+	Templates map[string]MobTemplate
+	Groups    map[string]SpawnGroup
+	Worlds    []WorldSpawn
+	Dungeons  map[string]DungeonSpawn
+	Bosses    map[string]*WorldBoss
+	Dialogues map[string]*Dialogue // by NPC ID
+}
+
+var spawnData *SpawnData
+
+// configureSpawnData loads and validates spawn definitions from dir, or the
+// embedded defaults when dir is empty. It must succeed before the server
+// accepts players.
+func configureSpawnData(dir string) error {
+	data, err := loadSpawnData(dir)
+	if err != nil {
+		return err
+	}
+	spawnData = data
+	return nil
+}
+
+func activeSpawnData() *SpawnData {
+	if spawnData == nil {
+		data, err := loadSpawnData("")
+		if err != nil {
+			log.Printf("embedded spawn data is invalid: %v", err)
+			data = &SpawnData{Templates: map[string]MobTemplate{}, Groups: map[string]SpawnGroup{}, Dungeons: map[string]DungeonSpawn{}, Bosses: map[string]*WorldBoss{}, Dialogues: map[string]*Dialogue{}}
+		}
+		spawnData = data
+	}
+	return spawnData
+}
+
+func loadSpawnData(dir string) (*SpawnData, error) {
+	var fsys fs.FS
+	if strings.TrimSpace(dir) == "" {
+		sub, err := fs.Sub(defaultSpawnFiles, "spawns")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	} else {
+		fsys = os.DirFS(dir)
+	}
+
+	var templates []MobTemplate
+	var groups []SpawnGroup
+	var placements []WorldSpawn
+	if err := readSpawnFile(fsys, mobTemplatesFile, &templates); err != nil {
+		return nil, err
+	}
+	if err := readSpawnFile(fsys, spawnGroupsFile, &groups); err != nil {
+		return nil, err
+	}
+	if err := readSpawnFile(fsys, worldSpawnsFile, &placements); err != nil {
+		return nil, err
+	}
+	var dungeons []DungeonSpawn
+	if err := readSpawnFile(fsys, dungeonSpawnsFile, &dungeons); err != nil {
+		return nil, err
+	}
+	// World bosses are optional.
+	var bosses []WorldBoss
+	if err := readSpawnFile(fsys, worldBossesFile, &bosses); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// So are NPC dialogues.
+	var dialogues []Dialogue
+	if err := readSpawnFile(fsys, dialoguesFile, &dialogues); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	data := &SpawnData{
+		Templates: map[string]MobTemplate{},
+		Groups:    map[string]SpawnGroup{},
+		Worlds:    placements,
+		Dungeons:  map[string]DungeonSpawn{},
+		Bosses:    map[string]*WorldBoss{},
+		Dialogues: map[string]*Dialogue{},
+	}
+	problems := make([]string, 0)
+	for _, t := range templates {
+		if t.ID == "" {
+			problems = append(problems, "mob template without id")
+			continue
+		}
+		if _, dup := data.Templates[t.ID]; dup {
+			problems = append(problems, fmt.Sprintf("duplicate mob template %q", t.ID))
+		}
+		if t.Level <= 0 || t.HP <= 0 {
+			problems = append(problems, fmt.Sprintf("mob template %q needs positive level and hp", t.ID))
+		}
+		if t.RespawnSec <= 0 {
+			problems = append(problems, fmt.Sprintf("mob template %q needs a positive respawn_sec", t.ID))
+		}
+		if t.Training {
+			if t.LootTable != "" || len(t.Abilities) > 0 {
+				problems = append(problems, fmt.Sprintf("training mob template %q cannot have loot or abilities", t.ID))
+			}
+		} else {
+			if _, ok := lootTables[t.LootTable]; !ok {
+				problems = append(problems, fmt.Sprintf("mob template %q references unknown loot table %q", t.ID, t.LootTable))
+			}
+			if t.AttackIntervalMS <= 0 {
+				problems = append(problems, fmt.Sprintf("mob template %q needs a positive attack_interval_ms", t.ID))
+			}
+			if t.DamageMin <= 0 || t.DamageMax < t.DamageMin {
+				problems = append(problems, fmt.Sprintf("mob template %q needs 0 < damage_min <= damage_max", t.ID))
+			}
+		}
+		if t.Defense < 0 {
+			problems = append(problems, fmt.Sprintf("mob template %q needs a non-negative defense", t.ID))
+		}
+		if !validElement(t.Element) {
+			problems = append(problems, fmt.Sprintf("mob template %q has unknown element %q", t.ID, t.Element))
+		}
+		for e, pct := range t.Resistances {
+			if e == ElementNone || !validElement(e) || pct < -100 || pct > 100 {
+				problems = append(problems, fmt.Sprintf("mob template %q needs resistances to known elements in -100..100, got %s=%d", t.ID, e, pct))
+			}
+		}
+		problems = append(problems, validateMobAbilities(t)...)
+		data.Templates[t.ID] = t
+	}
+	for _, g := range groups {
+		if g.ID == "" {
+			problems = append(problems, "spawn group without id")
+			continue
+		}
+		if _, dup := data.Groups[g.ID]; dup {
+			problems = append(problems, fmt.Sprintf("duplicate spawn group %q", g.ID))
+		}
+		if _, ok := data.Templates[g.Template]; !ok {
+			problems = append(problems, fmt.Sprintf("spawn group %q references unknown mob template %q", g.ID, g.Template))
+		}
+		if g.Count <= 0 {
+			problems = append(problems, fmt.Sprintf("spawn group %q needs a positive count", g.ID))
+		}
+		if g.Area.MaxX < g.Area.MinX || g.Area.MaxZ < g.Area.MinZ {
+			problems = append(problems, fmt.Sprintf("spawn group %q has an inverted area", g.ID))
+		}
+		data.Groups[g.ID] = g
+	}
+	known := DefaultWorlds()
+	for _, placement := range placements {
+		if _, ok := known[placement.WorldID]; !ok {
+			problems = append(problems, fmt.Sprintf("world spawn references unknown world %d", placement.WorldID))
+		}
+		for _, groupID := range placement.Groups {
+			if _, ok := data.Groups[groupID]; !ok {
+				problems = append(problems, fmt.Sprintf("world %d references unknown spawn group %q", placement.WorldID, groupID))
+			}
+		}
+	}
+	problems = append(problems, validateWorldBosses(bosses, data)...)
+	problems = append(problems, validateDialogues(dialogues, data)...)
+	for _, placement := range dungeons {
+		if _, ok := dungeonDefinitions[placement.DungeonID]; !ok {
+			problems = append(problems, fmt.Sprintf("dungeon spawn references unknown dungeon %q", placement.DungeonID))
+		}
+		if _, dup := data.Dungeons[placement.DungeonID]; dup {
+			problems = append(problems, fmt.Sprintf("duplicate dungeon spawn for %q", placement.DungeonID))
+		}
+		for _, groupID := range placement.Groups {
+			group, ok := data.Groups[groupID]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("dungeon %q references unknown spawn group %q", placement.DungeonID, groupID))
+				continue
+			}
+			if data.Templates[group.Template].Training {
+				problems = append(problems, fmt.Sprintf("dungeon %q cannot place training spawn group %q", placement.DungeonID, groupID))
+			}
+		}
+		data.Dungeons[placement.DungeonID] = placement
+	}
+	for id := range dungeonDefinitions {
+		if len(data.Dungeons[id].Groups) == 0 {
+			problems = append(problems, fmt.Sprintf("dungeon %q has no spawn groups", id))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New("invalid spawn data: " + strings.Join(problems, "; "))
+	}
+	return data, nil
+}
+
+func readSpawnFile(fsys fs.FS, name string, out interface{}) error {
+	raw, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// spawnWorldMobs creates one MobEntity per spawn slot. IDs are
+// mob_<template>_<nn>, numbered per template in placement order, so every
+// spawned mob is a distinct instance.
+func spawnWorldMobs(data *SpawnData) map[WorldID]map[string]*MobEntity {
+	out := map[WorldID]map[string]*MobEntity{}
+	seq := map[string]int{}
+	for _, placement := range data.Worlds {
+		if out[placement.WorldID] == nil {
+			out[placement.WorldID] = map[string]*MobEntity{}
+		}
+		spawnGroupMobs(data, placement.Groups, placement.WorldID, seq, out[placement.WorldID])
+	}
+	return out
+}
+
+// spawnDungeonMobs creates a fresh roster of dungeonID's spawn groups for
+// the instance worldID. Instance mobs are numbered per instance like world
+// mobs and never respawn.
+func spawnDungeonMobs(data *SpawnData, dungeonID string, worldID WorldID) map[string]*MobEntity {
+	out := map[string]*MobEntity{}
+	spawnGroupMobs(data, data.Dungeons[dungeonID].Groups, worldID, map[string]int{}, out)
+	for _, mob := range out {
+		mob.RespawnSec = 0
+	}
+	return out
+}
+
+// spawnGroupMobs adds a mob for every slot of groups to out, numbering them
+// through seq.
+func spawnGroupMobs(data *SpawnData, groups []string, worldID WorldID, seq map[string]int, out map[string]*MobEntity) {
+	for _, groupID := range groups {
+		group := data.Groups[groupID]
+		tmpl := data.Templates[group.Template]
+		for i := 0; i < group.Count; i++ {
+			seq[tmpl.ID]++
+			mob := newTemplateMob(tmpl, fmt.Sprintf("mob_%s_%02d", tmpl.ID, seq[tmpl.ID]), worldID, randomPointInArea(group.Area))
+			out[mob.ID] = mob
+		}
+	}
+}
+
+// newTemplateMob creates an idle mob of tmpl at pos, anchored there.
+func newTemplateMob(tmpl MobTemplate, id string, worldID WorldID, pos Position) *MobEntity {
+	mob := &MobEntity{
+		ID:         id,
+		Name:       tmpl.Name,
+		Template:   tmpl.ID,
+		LootTable:  tmpl.LootTable,
+		WorldID:    worldID,
+		Level:      tmpl.Level,
+		HP:         tmpl.HP,
+		MaxHP:      tmpl.HP,
+		Position:   pos,
+		RespawnSec: tmpl.RespawnSec,
+
+		AttackIntervalMS: tmpl.AttackIntervalMS,
+		DamageMin:        tmpl.DamageMin,
+		DamageMax:        tmpl.DamageMax,
+		Abilities:        tmpl.Abilities,
+		Defense:          tmpl.Defense,
+		Element:          tmpl.elementOrNone(),
+		Resistances:      tmpl.Resistances,
+		Training:         tmpl.Training,
+	}
+	mob.Spawn = mob.Position
+	mob.AIState = MobStateIdle
+	mob.lastSentPos = mob.Position
+	return mob
+}
+
+func randomPointInArea(a SpawnArea) Position {
+	return Position{
+		X: a.MinX + randFloat64()*(a.MaxX-a.MinX),
+		Y: 0,
+		Z: a.MinZ + randFloat64()*(a.MaxZ-a.MinZ),
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSpawnDataCreatesUniqueMobInstances(t *testing.T) {
+	data, err := loadSpawnData("")
+	if err != nil {
+		t.Fatalf("embedded spawn data failed validation: %v", err)
+	}
+
+	mobs := spawnWorldMobs(data)
+	wolves := 0
+	area := data.Groups["w1_wolf_pack"].Area
+	for id, mob := range mobs[World1] {
+		if id != mob.ID || mob.WorldID != World1 {
+			t.Fatalf("mob %q keyed inconsistently: %#v", id, mob)
+		}
+		if mob.Template != "wolf" {
+			continue
+		}
+		wolves++
+		if mob.LootTable != "rift_wolf" || mob.HP != mob.MaxHP || mob.RespawnSec <= 0 {
+			t.Fatalf("expected wolf to copy its template, got %#v", mob)
+		}
+		if mob.Position.X < area.MinX || mob.Position.X > area.MaxX || mob.Position.Z < area.MinZ || mob.Position.Z > area.MaxZ {
+			t.Fatalf("expected wolf inside its spawn area, got %#v", mob.Position)
+		}
+	}
+	if wolves != data.Groups["w1_wolf_pack"].Count {
+		t.Fatalf("expected %d distinct wolves, got %d", data.Groups["w1_wolf_pack"].Count, wolves)
+	}
+	if mobs[World1]["mob_wolf_01"] == nil || mobs[World1]["mob_wolf_02"] == nil {
+		t.Fatalf("expected numbered wolf instances, got %v", mobs[World1])
+	}
+}
+
+func TestLoadSpawnDataReportsUnknownReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		mobTemplatesFile: `[{"id": "wolf", "name": "Rift Wolf", "level": 42, "hp": 210, "loot_table": "no_such_loot", "respawn_sec": 8, "element": "Plasma"},
+			{"id": "dummy", "name": "Dummy", "level": 1, "hp": 100, "loot_table": "rift_wolf", "respawn_sec": 1, "training": true}]`,
+		spawnGroupsFile:   `[{"id": "pack", "template": "ghost", "count": 2, "area": {"min_x": 0, "min_z": 0, "max_x": 10, "max_z": 10}}]`,
+		worldSpawnsFile:   `[{"world_id": 1, "groups": ["pack", "missing_group"]}]`,
+		dungeonSpawnsFile: `[{"dungeon_id": "wolf_den", "groups": ["den_group"]}, {"dungeon_id": "no_dungeon", "groups": []}]`,
+		dialoguesFile: `[{"npc": "npc_nobody", "start": "a", "nodes": {}},
+			{"npc": "npc_elder_rowan", "start": "a", "nodes": {"a": {"choices": [{"id": "go", "next": "b", "effects": {"offer_quest": "no_quest", "apply_effect": "no_effect"}}]}}}]`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	_, err := loadSpawnData(dir)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{`unknown loot table "no_such_loot"`, `unknown mob template "ghost"`, `unknown spawn group "missing_group"`, `unknown element "Plasma"`, `unknown NPC "npc_nobody"`, `unknown node "b"`, `unknown quest "no_quest"`, `unknown effect "no_effect"`, `training mob template "dummy" cannot have loot`, `unknown dungeon "no_dungeon"`, `dungeon "wolf_den" references unknown spawn group "den_group"`, `dungeon "shard_vault" has no spawn groups`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
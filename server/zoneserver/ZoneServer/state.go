@@ -12,9 +12,12 @@ func statePayload(s *ClientSession) map[string]interface{} {
 		"xp_debt":      c.XPDebt,
 		"hp":           c.HP,
 		"max_hp":       c.MaxHP,
+		"defense":      characterDefense(c),
+		"combat":       characterCombatStats(c),
 		"aura_level":   c.AuraLevel,
 		"world":        s.World.Name,
 		"position":     s.Position,
+		"region":       regionPayload(sessionRegion(s)),
 		"trust":        c.Trust,
 		"quests":       c.Quests,
 		"pet":          c.Pet,
@@ -23,6 +26,8 @@ func statePayload(s *ClientSession) map[string]interface{} {
 		"presence":     canonicalPresenceStatus(c.Presence),
 		"skill_points": c.SkillPoints,
 		"skills":       c.Skills,
+		"resource":     skillResourcePayload(s),
+		"effects":      characterEffectsPayload(c, simTickClock()),
 		"pk_score":     c.PKScore,
 		"honor":        c.Honor,
 		"inventory":    c.Inventory,
@@ -0,0 +1,141 @@
+package main
+
+import "sort"
+
+// StatContribution is one source's share of a derived stat. Sources are
+// "base", "level", "class", "strength", "dexterity", "gear", "gear_level",
+// "rarity", "weapon", "pet", "mercenary", "skill:<id>" and "effect:<id>".
+type StatContribution struct {
+	Source string `json:"source"`
+	Value  int    `json:"value"`
+}
+
+// DerivedStats are a character's effective numbers. Combat reads them
+// through calculateAttack, characterDefense and characterCombatStats, and
+// GET_STATS shows them with a per-source breakdown, so both agree.
+type DerivedStats struct {
+	Strength       int `json:"strength"`
+	Dexterity      int `json:"dexterity"`
+	MaxHP          int `json:"max_hp"`
+	Attack         int `json:"attack"`           // a hit's flat damage before power and variance
+	AttackBonusPct int `json:"attack_bonus_pct"` // added to a hit after power and variance
+	Defense        int `json:"defense"`
+	AttackSpeedMS  int `json:"attack_speed_ms"` // auto-attack swing interval
+	CombatStats
+	Shield       int                           `json:"shield"` // damage shields left to absorb
+	MoveSpeedPct int                           `json:"move_speed_pct"`
+	Elements     map[string]Element            `json:"elements"`
+	Breakdown    map[string][]StatContribution `json:"breakdown"`
+}
+
+// add credits value from source to stat, whose running total is total.
+func (d *DerivedStats) add(stat string, total *int, source string, value int) {
+	if value == 0 {
+		return
+	}
+	*total += value
+	d.Breakdown[stat] = append(d.Breakdown[stat], StatContribution{Source: source, Value: value})
+}
+
+// deriveStats combines c's base stats, equipped gear, skills, pet,
+// mercenary and active effects.
+func deriveStats(c *Character) DerivedStats {
+	d := DerivedStats{Breakdown: map[string][]StatContribution{}}
+	scaling := combatScalingFor(c.Class)
+
+	d.add("strength", &d.Strength, "base", c.Strength)
+	d.add("dexterity", &d.Dexterity, "base", c.Dexterity)
+	d.add("max_hp", &d.MaxHP, "base", c.MaxHP)
+
+	d.add("attack", &d.Attack, "level", c.Level*2)
+	d.add("attack", &d.Attack, "base", 12)
+	d.add("attack_speed_ms", &d.AttackSpeedMS, "class", scaling.AttackSpeedMS)
+	// Every equipped item adds to attack; armor-slot items also defend, and
+	// a weapon may set its own swing interval.
+	var grade, gearLevel, rarity, armorGrade, armorLevel, armorRarity int
+	for _, item := range c.Inventory {
+		if c.Equipped[item.Slot] != item.ID {
+			continue
+		}
+		ensureItemDefaults(&item)
+		if item.Slot == SlotWeapon && item.AttackSpeedMS > 0 {
+			d.add("attack_speed_ms", &d.AttackSpeedMS, "weapon", item.AttackSpeedMS-scaling.AttackSpeedMS)
+		}
+		bonus := 0
+		if item.Rarity == RarityEpic {
+			bonus = 2
+		}
+		if item.Rarity == RarityUnique {
+			bonus = 4
+		}
+		grade += item.Grade * 2
+		gearLevel += maxInt(0, item.GearLevel-1)
+		rarity += bonus
+		if armorSlots[item.Slot] {
+			armorGrade += item.Grade * 2
+			armorLevel += maxInt(0, item.GearLevel-1)
+			armorRarity += bonus
+		}
+	}
+	d.add("attack", &d.Attack, "gear", grade)
+	d.add("attack", &d.Attack, "gear_level", gearLevel)
+	d.add("attack", &d.Attack, "rarity", rarity)
+	if c.Pet.Summoned {
+		d.add("attack_bonus_pct", &d.AttackBonusPct, "pet", 5)
+	}
+	if c.Mercenary.Recruited {
+		d.add("attack_bonus_pct", &d.AttackBonusPct, "mercenary", 8)
+	}
+
+	d.add("defense", &d.Defense, "gear", armorGrade)
+	d.add("defense", &d.Defense, "gear_level", armorLevel)
+	d.add("defense", &d.Defense, "rarity", armorRarity)
+	skills := make([]string, 0, len(defenseSkills))
+	for skillID := range defenseSkills {
+		skills = append(skills, skillID)
+	}
+	sort.Strings(skills)
+	for _, skillID := range skills {
+		d.add("defense", &d.Defense, "skill:"+skillID, skillBonus(c, skillID))
+	}
+
+	d.add("power", &d.Power, "strength", d.Strength*scaling.PowerPct/100)
+	if scaling.DexPerCrit > 0 {
+		d.add("crit_chance", &d.CritChance, "dexterity", minInt(d.Dexterity/scaling.DexPerCrit, maxCritChancePct))
+	}
+	d.add("crit_multiplier", &d.CritMultiplier, "class", scaling.CritMultiplier)
+	d.add("accuracy", &d.Accuracy, "level", c.Level)
+	d.add("accuracy", &d.Accuracy, "dexterity", d.Dexterity*scaling.AccuracyPct/100)
+	d.add("evasion", &d.Evasion, "level", c.Level/2)
+	d.add("evasion", &d.Evasion, "dexterity", d.Dexterity*scaling.EvasionPct/100)
+	d.add("evasion", &d.Evasion, "skill:evasion_step", skillBonus(c, "evasion_step")*evasionStepScale)
+
+	d.add("move_speed_pct", &d.MoveSpeedPct, "base", 100)
+	effectsMu.Lock()
+	slowest, slow := "", 0
+	for _, e := range c.Effects {
+		switch effectCatalog[e.ID].Kind {
+		case EffectDefense:
+			d.add("defense", &d.Defense, "effect:"+e.ID, e.Magnitude*e.Stacks)
+		case EffectDamageShield:
+			d.add("shield", &d.Shield, "effect:"+e.ID, e.Absorb)
+		case EffectSlow:
+			if e.Magnitude > slow {
+				slowest, slow = e.ID, e.Magnitude
+			}
+		}
+	}
+	effectsMu.Unlock()
+	d.add("move_speed_pct", &d.MoveSpeedPct, "effect:"+slowest, -minInt(slow, maxSlowPct))
+
+	petElement := c.Elemental["pet"]
+	if petElement == "" {
+		petElement = ElementNone
+	}
+	d.Elements = map[string]Element{
+		"attack":  attackElement(c),
+		"defense": armorElement(c),
+		"pet":     petElement,
+	}
+	return d
+}
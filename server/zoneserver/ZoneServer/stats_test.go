@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func breakdownTotal(d DerivedStats, stat string) int {
+	total := 0
+	for _, part := range d.Breakdown[stat] {
+		total += part.Value
+	}
+	return total
+}
+
+func TestDeriveStatsBreaksDownEverySource(t *testing.T) {
+	c := MockCharacter()
+	ensureCharacterDefaults(c)
+	c.Inventory = append(c.Inventory, Item{ID: "scale", Name: "Scale Mail", Grade: 5, Rarity: RarityUnique, Slot: SlotArmor, GearLevel: 3, Element: ElementIce})
+	c.Equipped[SlotArmor] = "scale"
+	c.Pet.Summoned = true
+	c.Mercenary.Recruited = true
+	c.Skills["evasion_step"] = 1
+	now := time.Now()
+	c.Effects, _ = addEffect(c.Effects, effectCatalog["fortified"], c.Name, 15, 8*time.Second, now)
+	c.Effects, _ = addEffect(c.Effects, effectCatalog["shocked"], "Caster", 40, 4*time.Second, now)
+
+	d := deriveStats(c)
+	// Bow 4 + scale 10+2+4 on top of level 90 and base 12.
+	if d.Attack != 122 || d.AttackBonusPct != 13 || d.Defense != 31 || d.Evasion != 44 || d.MoveSpeedPct != 60 {
+		t.Fatalf("unexpected derived stats %#v", d)
+	}
+	if d.Elements["defense"] != ElementIce || d.Elements["attack"] != ElementNone {
+		t.Fatalf("expected elements from gear, got %#v", d.Elements)
+	}
+	for stat, total := range map[string]int{
+		"attack": d.Attack, "attack_bonus_pct": d.AttackBonusPct, "defense": d.Defense,
+		"power": d.Power, "accuracy": d.Accuracy, "evasion": d.Evasion, "move_speed_pct": d.MoveSpeedPct, "attack_speed_ms": d.AttackSpeedMS,
+	} {
+		if got := breakdownTotal(d, stat); got != total {
+			t.Fatalf("expected %s breakdown to sum to %d, got %d: %#v", stat, total, got, d.Breakdown[stat])
+		}
+	}
+	sources := map[string]int{}
+	for _, part := range d.Breakdown["defense"] {
+		sources[part.Source] = part.Value
+	}
+	if sources["gear"] != 10 || sources["gear_level"] != 2 || sources["rarity"] != 4 || sources["effect:fortified"] != 15 {
+		t.Fatalf("unexpected defense breakdown %#v", d.Breakdown["defense"])
+	}
+
+	// Combat reads the same numbers.
+	withFixedRandIntn(0, func() {
+		if attack := calculateAttack(c, c.Level, 0); attack.Base != d.Attack || attack.BonusPct != d.AttackBonusPct || attack.Power != d.Power {
+			t.Fatalf("expected calculateAttack to use derived stats, got %#v", attack)
+		}
+	})
+	if characterDefense(c) != d.Defense || characterCombatStats(c) != d.CombatStats {
+		t.Fatalf("expected defense and combat stats to match GET_STATS")
+	}
+}
+
+func TestGetStatsCommand(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	session, conn := newVisibilityTestSession("Counter", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(session)
+
+	boundName := session.Character.Name
+	handled, modified := handleClientCommand(conn, session, map[*ClientSession]bool{}, "stats-peer", &boundName, ReqGetStats, nil)
+	if !handled || modified {
+		t.Fatalf("expected GET_STATS to be handled read-only, got handled=%v modified=%v", handled, modified)
+	}
+	msg := lastMessage(conn.DrainMessages(t), RespStats)
+	stats := toMap(msg.Payload)
+	if msg.Command != RespStats || toInt(stats, "attack") != 106 || toInt(stats, "crit_chance") != 11 {
+		t.Fatalf("unexpected STATS payload %#v", stats)
+	}
+	if breakdown, _ := stats["breakdown"].(map[string]interface{}); len(breakdown) == 0 {
+		t.Fatalf("expected a per-source breakdown, got %#v", stats["breakdown"])
+	}
+}
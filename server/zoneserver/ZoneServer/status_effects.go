@@ -0,0 +1,506 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status effect kinds.
+const (
+	EffectHealOverTime   = "heal_over_time"   // heals Magnitude per stack every PeriodMS
+	EffectDamageOverTime = "damage_over_time" // deals Magnitude per stack every PeriodMS
+	EffectStun           = "stun"             // no moving, attacking or (for mobs) acting
+	EffectSlow           = "slow"             // Magnitude percent slower movement
+	EffectDamageShield   = "damage_shield"    // absorbs Magnitude damage before HP
+	EffectDefense        = "defense"          // adds Magnitude per stack to defense
+)
+
+// Stacking rules for reapplying an effect the target already has.
+const (
+	StackRefresh = "refresh" // restart the duration, keep the stronger magnitude
+	StackAdd     = "stack"   // add a stack up to MaxStacks and restart the duration
+)
+
+// persistEffectMin is how long a beneficial effect must last to be saved
+// with the character and survive logout; shorter effects end with the
+// session.
+const persistEffectMin = 10 * time.Minute
+
+// maxSlowPct caps how much slows can reduce movement.
+const maxSlowPct = 80
+
+type EffectDefinition struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	DurationMS int    `json:"duration_ms"`
+	PeriodMS   int    `json:"period_ms,omitempty"`
+	// Magnitude is per skill rank when the effect comes from a skill.
+	Magnitude int    `json:"magnitude"`
+	Stacking  string `json:"stacking"`
+	MaxStacks int    `json:"max_stacks,omitempty"`
+	Harmful   bool   `json:"harmful"`
+	// OnSelf effects land on the skill's user rather than its target.
+	OnSelf bool `json:"on_self"`
+}
+
+var effectCatalog = map[string]EffectDefinition{
+	"bleeding":       {ID: "bleeding", Name: "Bleeding", Kind: EffectDamageOverTime, DurationMS: 6000, PeriodMS: 1000, Magnitude: 6, Stacking: StackAdd, MaxStacks: 3, Harmful: true},
+	"scorched":       {ID: "scorched", Name: "Scorched", Kind: EffectDamageOverTime, DurationMS: 5000, PeriodMS: 1000, Magnitude: 12, Stacking: StackRefresh, Harmful: true},
+	"shocked":        {ID: "shocked", Name: "Shocked", Kind: EffectSlow, DurationMS: 4000, Magnitude: 40, Stacking: StackRefresh, Harmful: true},
+	"hamstrung":      {ID: "hamstrung", Name: "Hamstrung", Kind: EffectSlow, DurationMS: 3000, Magnitude: 30, Stacking: StackRefresh, Harmful: true},
+	"stunned":        {ID: "stunned", Name: "Stunned", Kind: EffectStun, DurationMS: 1500, Stacking: StackRefresh, Harmful: true},
+	"renewal":        {ID: "renewal", Name: "Renewal", Kind: EffectHealOverTime, DurationMS: 8000, PeriodMS: 1000, Magnitude: 8, Stacking: StackRefresh, OnSelf: true},
+	"guardian_ward":  {ID: "guardian_ward", Name: "Guardian Ward", Kind: EffectDamageShield, DurationMS: 10000, Magnitude: 40, Stacking: StackRefresh, OnSelf: true},
+	"arcane_barrier": {ID: "arcane_barrier", Name: "Arcane Barrier", Kind: EffectDamageShield, DurationMS: 10000, Magnitude: 35, Stacking: StackRefresh, OnSelf: true},
+	"fortified":      {ID: "fortified", Name: "Fortified", Kind: EffectDefense, DurationMS: 8000, Magnitude: 15, Stacking: StackRefresh, OnSelf: true},
+	"rowan_blessing": {ID: "rowan_blessing", Name: "Elder's Blessing", Kind: EffectDefense, DurationMS: 30 * 60 * 1000, Magnitude: 10, Stacking: StackRefresh},
+}
+
+// skillEffects maps skills to the effect they apply when used.
+var skillEffects = map[string]string{
+	"cleave":          "bleeding",
+	"burst_arrow":     "bleeding",
+	"cataclysm_nova":  "scorched",
+	"arc_bolt":        "shocked",
+	"battle_rush":     "hamstrung",
+	"renewal_burst":   "renewal",
+	"guardian_shield": "guardian_ward",
+	"mana_barrier":    "arcane_barrier",
+	"iron_wall":       "fortified",
+}
+
+// ActiveEffect is an effect on a character or mob. Character effects are
+// saved with the character; only long buffs are kept on the next login.
+type ActiveEffect struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Stacks    int       `json:"stacks"`
+	Magnitude int       `json:"magnitude"` // per stack
+	Absorb    int       `json:"absorb,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	NextTick  time.Time `json:"next_tick,omitempty"`
+}
+
+// effectsMu guards Character.Effects, which world loops tick while
+// connection goroutines apply effects and save characters. Mob effects are
+// guarded by their world lock.
+var effectsMu sync.Mutex
+
+// effectPulse is one heal or damage tick produced by tickEffects.
+type effectPulse struct {
+	effect ActiveEffect
+	kind   string
+	amount int
+}
+
+// addEffect applies def to list under its stacking rule and returns the
+// new list and the effect as it now stands.
+func addEffect(list []ActiveEffect, def EffectDefinition, source string, magnitude int, duration time.Duration, now time.Time) ([]ActiveEffect, ActiveEffect) {
+	for i := range list {
+		e := &list[i]
+		if e.ID != def.ID {
+			continue
+		}
+		if def.Stacking == StackAdd {
+			e.Stacks = minInt(e.Stacks+1, maxInt(def.MaxStacks, 1))
+		}
+		e.Magnitude = maxInt(e.Magnitude, magnitude)
+		e.Source = source
+		e.ExpiresAt = now.Add(duration)
+		if def.Kind == EffectDamageShield {
+			e.Absorb = e.Magnitude
+		}
+		return list, *e
+	}
+	e := ActiveEffect{ID: def.ID, Source: source, Stacks: 1, Magnitude: magnitude, ExpiresAt: now.Add(duration)}
+	if def.PeriodMS > 0 {
+		e.NextTick = now.Add(time.Duration(def.PeriodMS) * time.Millisecond)
+	}
+	if def.Kind == EffectDamageShield {
+		e.Absorb = magnitude
+	}
+	return append(list, e), e
+}
+
+// tickEffects advances list to now, returning the effects still running,
+// the heal/damage pulses that came due (catching up on missed periods, as
+// mob swings do) and the damage shields that were used up. Effects that run
+// out of time are ended by their eventEffectExpire event instead.
+func tickEffects(list []ActiveEffect, now time.Time) (kept []ActiveEffect, pulses []effectPulse, depleted []ActiveEffect) {
+	for _, e := range list {
+		if p, ok := settleEffect(&e, now); ok {
+			pulses = append(pulses, p)
+		}
+		if effectCatalog[e.ID].Kind == EffectDamageShield && e.Absorb <= 0 {
+			depleted = append(depleted, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept, pulses, depleted
+}
+
+// takeExpiredEffect removes effectID from list if it has run out by now,
+// returning the pulses it still owed. A refreshed effect is left running.
+func takeExpiredEffect(list []ActiveEffect, effectID string, now time.Time) (kept []ActiveEffect, pulses []effectPulse, expired []ActiveEffect) {
+	for _, e := range list {
+		if e.ID != effectID || now.Before(e.ExpiresAt) {
+			kept = append(kept, e)
+			continue
+		}
+		if p, ok := settleEffect(&e, e.ExpiresAt); ok {
+			pulses = append(pulses, p)
+		}
+		expired = append(expired, e)
+	}
+	return kept, pulses, expired
+}
+
+// settleEffect runs e's periods that came due by now, never past its
+// expiry, and reports the pulse they add up to.
+func settleEffect(e *ActiveEffect, now time.Time) (effectPulse, bool) {
+	def := effectCatalog[e.ID]
+	if def.PeriodMS <= 0 {
+		return effectPulse{}, false
+	}
+	period := time.Duration(def.PeriodMS) * time.Millisecond
+	amount := 0
+	for !e.NextTick.IsZero() && !e.NextTick.After(now) && !e.NextTick.After(e.ExpiresAt) {
+		amount += e.Magnitude * e.Stacks
+		e.NextTick = e.NextTick.Add(period)
+	}
+	return effectPulse{effect: *e, kind: def.Kind, amount: amount}, amount > 0
+}
+
+// absorbDamage drains damage shields in list and returns what gets through.
+func absorbDamage(list []ActiveEffect, damage int) int {
+	for i := range list {
+		if damage <= 0 {
+			break
+		}
+		if effectCatalog[list[i].ID].Kind != EffectDamageShield || list[i].Absorb <= 0 {
+			continue
+		}
+		soaked := minInt(list[i].Absorb, damage)
+		list[i].Absorb -= soaked
+		damage -= soaked
+	}
+	return damage
+}
+
+// effectTotal sums the magnitude of every effect of kind in list.
+func effectTotal(list []ActiveEffect, kind string) int {
+	total := 0
+	for _, e := range list {
+		if effectCatalog[e.ID].Kind == kind {
+			total += e.Magnitude * e.Stacks
+		}
+	}
+	return total
+}
+
+// slowFactor is the movement multiplier left by the slows in list.
+func slowFactor(list []ActiveEffect) float64 {
+	slow := 0
+	for _, e := range list {
+		if effectCatalog[e.ID].Kind == EffectSlow {
+			slow = maxInt(slow, e.Magnitude)
+		}
+	}
+	return float64(100-minInt(slow, maxSlowPct)) / 100
+}
+
+func effectPayload(targetKind, target string, e ActiveEffect, now time.Time) map[string]interface{} {
+	def := effectCatalog[e.ID]
+	return map[string]interface{}{
+		"target_kind": targetKind,
+		"target":      target,
+		"effect_id":   e.ID,
+		"name":        def.Name,
+		"kind":        def.Kind,
+		"source":      e.Source,
+		"stacks":      e.Stacks,
+		"magnitude":   e.Magnitude,
+		"duration_ms": e.ExpiresAt.Sub(now).Milliseconds(),
+	}
+}
+
+func expiredPayload(targetKind, target string, e ActiveEffect) map[string]interface{} {
+	return map[string]interface{}{"target_kind": targetKind, "target": target, "effect_id": e.ID}
+}
+
+// effectExpireKey identifies the eventEffectExpire event for one effect.
+// Player effects carry world zero so they follow the character between
+// worlds and survive CancelWorld.
+func effectExpireKey(worldID WorldID, targetKind, target, effectID string) string {
+	return fmt.Sprintf("%s:%d:%s", eventEffectExpire, worldID, effectExpireSubject(targetKind, target, effectID))
+}
+
+func effectExpireSubject(targetKind, target, effectID string) string {
+	return targetKind + ":" + target + ":" + effectID
+}
+
+// scheduleEffectExpiry queues e to end at its expiry, replacing the event
+// from an earlier application of the same effect.
+func scheduleEffectExpiry(worldID WorldID, targetKind, target string, e ActiveEffect, now time.Time) {
+	worldScheduler.Schedule(ScheduledEvent{
+		Key:     effectExpireKey(worldID, targetKind, target, e.ID),
+		Kind:    eventEffectExpire,
+		WorldID: worldID,
+		Subject: effectExpireSubject(targetKind, target, e.ID),
+	}, e.ExpiresAt.Sub(now))
+}
+
+// scheduleCharacterEffectExpiries queues the effects c logged in with.
+func scheduleCharacterEffectExpiries(c *Character, now time.Time) {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	for _, e := range c.Effects {
+		scheduleEffectExpiry(0, "player", c.Name, e, now)
+	}
+}
+
+// expireEffect is the eventEffectExpire handler. Effects on characters who
+// have logged out or mobs that have since died are already gone.
+func expireEffect(evt ScheduledEvent) {
+	first, last := strings.Index(evt.Subject, ":"), strings.LastIndex(evt.Subject, ":")
+	if first < 0 || first == last {
+		return
+	}
+	targetKind, target, effectID := evt.Subject[:first], evt.Subject[first+1:last], evt.Subject[last+1:]
+	switch targetKind {
+	case "player":
+		s := findSessionByCharacterName(target)
+		if s == nil || s.Character == nil || s.World == nil {
+			return
+		}
+		if w := worldSimFor(s.World.ID); w != nil {
+			w.do(func(w *worldSim, outbox *mobOutbox) {
+				effectsMu.Lock()
+				kept, pulses, expired := takeExpiredEffect(s.Character.Effects, effectID, simTickClock())
+				s.Character.Effects = kept
+				effectsMu.Unlock()
+				endSessionEffectsLocked(w, s, pulses, expired, outbox)
+			})
+		}
+	case "mob":
+		if w := worldSimFor(evt.WorldID); w != nil {
+			w.do(func(w *worldSim, outbox *mobOutbox) {
+				if mob, ok := w.mobs[target]; ok {
+					kept, pulses, expired := takeExpiredEffect(mob.effects, effectID, simTickClock())
+					mob.effects = kept
+					endMobEffectsLocked(mob, pulses, expired, outbox)
+				}
+			})
+		}
+	}
+}
+
+// applySessionEffect puts effectID on s's character and tells them with
+// EFFECT_APPLIED. A zero duration uses the effect's own. Stuns also block
+// the session through stunSession.
+func applySessionEffect(s *ClientSession, effectID, source string, magnitude int, duration time.Duration, now time.Time, outbox *mobOutbox) {
+	def, ok := effectCatalog[effectID]
+	if !ok || s.Character == nil {
+		return
+	}
+	if duration <= 0 {
+		duration = time.Duration(def.DurationMS) * time.Millisecond
+	}
+	effectsMu.Lock()
+	var e ActiveEffect
+	s.Character.Effects, e = addEffect(s.Character.Effects, def, source, magnitude, duration, now)
+	effectsMu.Unlock()
+	scheduleEffectExpiry(0, "player", s.Character.Name, e, now)
+	if def.Kind == EffectStun {
+		stunSession(s, e.ExpiresAt)
+	}
+	outbox.send(s, ServerMessage{Command: RespEffectApplied, Payload: effectPayload("player", s.Character.Name, e, now)})
+}
+
+// applyMobEffectLocked puts effectID on mob and tells players who can see
+// it. The caller holds the world lock.
+func applyMobEffectLocked(mob *MobEntity, effectID, source string, magnitude int, now time.Time, outbox *mobOutbox) {
+	def, ok := effectCatalog[effectID]
+	if !ok {
+		return
+	}
+	var e ActiveEffect
+	mob.effects, e = addEffect(mob.effects, def, source, magnitude, time.Duration(def.DurationMS)*time.Millisecond, now)
+	scheduleEffectExpiry(mob.WorldID, "mob", mob.ID, e, now)
+	outbox.event(mob, RespEffectApplied, effectPayload("mob", mob.ID, e, now), nil)
+}
+
+// applySkillEffect applies the effect tied to skillID, if any, either to
+// the user or to their target (a mob, or a session for PvP).
+func applySkillEffect(user *ClientSession, skillID string, mob *MobEntity, victim *ClientSession, now time.Time, outbox *mobOutbox) {
+	effectID, ok := skillEffects[skillID]
+	if !ok {
+		return
+	}
+	def := effectCatalog[effectID]
+	magnitude := def.Magnitude * maxInt(user.Character.Skills[skillID], 1)
+	switch {
+	case def.OnSelf:
+		applySessionEffect(user, effectID, user.Character.Name, magnitude, 0, now, outbox)
+	case mob != nil:
+		applyMobEffectLocked(mob, effectID, user.Character.Name, magnitude, now, outbox)
+	case victim != nil:
+		applySessionEffect(victim, effectID, user.Character.Name, magnitude, 0, now, outbox)
+	}
+}
+
+// tickSessionEffectsLocked runs s's heals and damage over time and ends
+// depleted shields, telling s about each. The caller holds the world lock.
+func tickSessionEffectsLocked(w *worldSim, s *ClientSession, outbox *mobOutbox) {
+	c := s.Character
+	if c == nil {
+		return
+	}
+	effectsMu.Lock()
+	if len(c.Effects) == 0 {
+		effectsMu.Unlock()
+		return
+	}
+	kept, pulses, depleted := tickEffects(c.Effects, w.now)
+	c.Effects = kept
+	effectsMu.Unlock()
+	endSessionEffectsLocked(w, s, pulses, depleted, outbox)
+}
+
+// endSessionEffectsLocked posts pulses to s's action path and tells s about
+// the effects that ended, dropping their expiry events. A damage over time
+// death drops s from w's threat tables.
+func endSessionEffectsLocked(w *worldSim, s *ClientSession, pulses []effectPulse, ended []ActiveEffect, outbox *mobOutbox) {
+	c := s.Character
+	if len(pulses) > 0 {
+		worldID := w.ID
+		outbox.post(s, func() {
+			for _, p := range pulses {
+				switch p.kind {
+				case EffectHealOverTime:
+					c.HP = minInt(c.MaxHP, c.HP+p.amount)
+				case EffectDamageOverTime:
+					if _, died := damageCharacter(c, s.Position, p.amount); died {
+						clearPlayerThreat(worldID, c.Name)
+						sendMessage(s.Conn, ServerMessage{Command: RespPlayerDied, Payload: map[string]interface{}{"target": p.effect.Source, "effect_id": p.effect.ID, "xp_debt": c.XPDebt, "corpse": c.Corpse, "recovery": "Use RECOVER_CORPSE"}})
+					}
+				}
+			}
+		})
+	}
+	for _, e := range ended {
+		worldScheduler.Cancel(effectExpireKey(0, "player", c.Name, e.ID))
+		outbox.send(s, ServerMessage{Command: RespEffectExpired, Payload: expiredPayload("player", c.Name, e)})
+	}
+}
+
+// tickMobEffectsLocked runs mob's periodic effects and ends its depleted
+// shields.
+func tickMobEffectsLocked(mob *MobEntity, now time.Time, outbox *mobOutbox) {
+	if len(mob.effects) == 0 {
+		return
+	}
+	kept, pulses, depleted := tickEffects(mob.effects, now)
+	mob.effects = kept
+	endMobEffectsLocked(mob, pulses, depleted, outbox)
+}
+
+// endMobEffectsLocked applies pulses to mob and announces the effects that
+// ended. Damage over time adds threat for its source but never lands the
+// killing blow, which stays with a player's attack.
+func endMobEffectsLocked(mob *MobEntity, pulses []effectPulse, ended []ActiveEffect, outbox *mobOutbox) {
+	for _, p := range pulses {
+		switch p.kind {
+		case EffectHealOverTime:
+			mob.HP = minInt(mob.MaxHP, mob.HP+p.amount)
+		case EffectDamageOverTime:
+			dealt := minInt(mitigateDamage(p.amount, mob.Defense), mob.HP-1)
+			if dealt <= 0 {
+				continue
+			}
+			mob.HP -= dealt
+			addThreatLocked(mob, p.effect.Source, dealt)
+			outbox.event(mob, RespMobDamaged, map[string]interface{}{
+				"mob_id":    mob.ID,
+				"hp":        mob.HP,
+				"max_hp":    mob.MaxHP,
+				"damage":    dealt,
+				"attacker":  p.effect.Source,
+				"effect_id": p.effect.ID,
+			}, nil)
+		}
+	}
+	for _, e := range ended {
+		worldScheduler.Cancel(effectExpireKey(mob.WorldID, "mob", mob.ID, e.ID))
+		outbox.event(mob, RespEffectExpired, expiredPayload("mob", mob.ID, e), nil)
+	}
+}
+
+// mobSpeed is w.perTick(perSecond) slowed by mob's effects.
+func mobSpeed(w *worldSim, mob *MobEntity, perSecond float64) float64 {
+	return w.perTick(perSecond) * slowFactor(mob.effects)
+}
+
+func mobStunned(mob *MobEntity, now time.Time) bool {
+	for _, e := range mob.effects {
+		if effectCatalog[e.ID].Kind == EffectStun && now.Before(e.ExpiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// characterEffectTotal is effectTotal over c's effects.
+func characterEffectTotal(c *Character, kind string) int {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	return effectTotal(c.Effects, kind)
+}
+
+func characterSlowFactor(c *Character) float64 {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	return slowFactor(c.Effects)
+}
+
+// slowedMoveValid checks a move against the character's slows, which
+// shorten the step isMoveValid allows.
+func slowedMoveValid(c *Character, from, to Position) bool {
+	return distance(from, to) <= maxMoveStep*characterSlowFactor(c)
+}
+
+// absorbCharacterDamage lets c's damage shields soak damage.
+func absorbCharacterDamage(c *Character, damage int) int {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	return absorbDamage(c.Effects, damage)
+}
+
+// keepPersistentEffects drops everything but unexpired long buffs from a
+// character loaded at login.
+func keepPersistentEffects(c *Character, now time.Time) {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	kept := c.Effects[:0]
+	for _, e := range c.Effects {
+		def, ok := effectCatalog[e.ID]
+		if ok && !def.Harmful && time.Duration(def.DurationMS)*time.Millisecond >= persistEffectMin && now.Before(e.ExpiresAt) {
+			kept = append(kept, e)
+		}
+	}
+	c.Effects = kept
+}
+
+func characterEffectsPayload(c *Character, now time.Time) []map[string]interface{} {
+	effectsMu.Lock()
+	defer effectsMu.Unlock()
+	out := make([]map[string]interface{}, 0, len(c.Effects))
+	for _, e := range c.Effects {
+		out = append(out, effectPayload("player", c.Name, e, now))
+	}
+	return out
+}
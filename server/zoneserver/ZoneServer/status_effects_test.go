@@ -0,0 +1,211 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectStackingAndPeriodicTicks(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	bleeding := effectCatalog["bleeding"]
+
+	var list []ActiveEffect
+	for i := 0; i < 4; i++ {
+		list, _ = addEffect(list, bleeding, "Berserker", bleeding.Magnitude, 6*time.Second, now)
+	}
+	if len(list) != 1 || list[0].Stacks != 3 {
+		t.Fatalf("expected bleeding to stack to 3, got %#v", list)
+	}
+
+	shocked := effectCatalog["shocked"]
+	list, _ = addEffect(list, shocked, "Caster", 80, 4*time.Second, now)
+	list, e := addEffect(list, shocked, "Caster", 40, 4*time.Second, now.Add(time.Second))
+	if e.Stacks != 1 || e.Magnitude != 80 || !e.ExpiresAt.Equal(now.Add(5*time.Second)) {
+		t.Fatalf("expected a refresh to keep the stronger slow and restart the timer, got %#v", e)
+	}
+	if f := slowFactor(list); f != 0.2 {
+		t.Fatalf("expected slows to cap at %d%%, got factor %v", maxSlowPct, f)
+	}
+
+	// Missed periods catch up in one tick.
+	kept, pulses, expired := tickEffects(list, now.Add(2500*time.Millisecond))
+	if len(pulses) != 1 || pulses[0].amount != 2*3*bleeding.Magnitude || len(expired) != 0 || len(kept) != 2 {
+		t.Fatalf("expected two bleeding pulses and nothing expired, got %#v %#v", pulses, expired)
+	}
+	// Running out of time is left to the scheduler, which settles what the
+	// effect still owed as it ends.
+	if _, pulses, expired = takeExpiredEffect(kept, "shocked", now.Add(4*time.Second)); len(pulses) != 0 || len(expired) != 0 {
+		t.Fatalf("expected the refreshed slow to outlive its first expiry, got %#v", expired)
+	}
+	kept, pulses, expired = takeExpiredEffect(kept, "bleeding", now.Add(10*time.Second))
+	if len(kept) != 1 || len(expired) != 1 || len(pulses) != 1 || pulses[0].amount != 4*3*bleeding.Magnitude {
+		t.Fatalf("expected the rest of the bleed as it expires, got %#v %#v", pulses, expired)
+	}
+}
+
+func TestPlayerEffectsTickShieldAndExpire(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	knight, conn := newVisibilityTestSession("Paladin", worlds[World1], Position{X: 300, Y: 0, Z: 300})
+	defer unregisterSession(knight)
+	c := knight.Character
+	c.HP, c.MaxHP = 100, 200
+
+	withSimClock(func(clock *fakeClock) {
+		withFakeScheduler(func(schedClock *fakeClock) {
+			withEmptyWorld(World1, func(w *worldSim) {
+				w.tickRate = time.Second
+				var outbox mobOutbox
+				applySessionEffect(knight, "renewal", c.Name, 8, 0, clock.Now(), &outbox)
+				applySessionEffect(knight, "guardian_ward", c.Name, 40, 0, clock.Now(), &outbox)
+				applySessionEffect(knight, "fortified", c.Name, 15, 0, clock.Now(), &outbox)
+				outbox.flush()
+				msgs := conn.DrainMessages(t)
+				if countMessages(msgs, RespEffectApplied) != 3 {
+					t.Fatalf("expected three EFFECT_APPLIED, got %#v", msgs)
+				}
+				applied := toMap(lastMessage(msgs, RespEffectApplied).Payload)
+				if applied["target_kind"] != "player" || applied["effect_id"] != "fortified" || toInt(applied, "duration_ms") != 8000 {
+					t.Fatalf("unexpected EFFECT_APPLIED payload %#v", applied)
+				}
+				if base := characterDefense(MockCharacter()); characterDefense(c) != base+15 {
+					t.Fatalf("expected fortified to add 15 defense, got %d over %d", characterDefense(c), base)
+				}
+
+				clock.Advance(2 * time.Second)
+				processServerTick()
+				if c.HP != 116 {
+					t.Fatalf("expected two renewal ticks to heal 16, got HP %d", c.HP)
+				}
+
+				// The ward soaks 40 mitigated damage before HP is touched.
+				hp := c.HP
+				if dealt, _ := damageCharacter(c, knight.Position, 30); dealt != 0 || c.HP != hp {
+					t.Fatalf("expected the ward to absorb the hit, dealt %d", dealt)
+				}
+				if dealt, _ := damageCharacter(c, knight.Position, 1000); dealt <= 0 || c.HP >= hp {
+					t.Fatalf("expected the rest to pass the depleted ward, dealt %d", dealt)
+				}
+				conn.DrainMessages(t)
+				processServerTick()
+				expired := lastMessage(conn.DrainMessages(t), RespEffectExpired)
+				if toString(toMap(expired.Payload), "effect_id") != "guardian_ward" {
+					t.Fatalf("expected the depleted ward to expire, got %#v", expired)
+				}
+
+				// Expiries are scheduler events; the ward's went with the ward.
+				keys := map[string]bool{}
+				for _, evt := range worldScheduler.Pending() {
+					keys[evt.Key] = evt.Kind == eventEffectExpire
+				}
+				if len(keys) != 2 || !keys[effectExpireKey(0, "player", c.Name, "renewal")] || !keys[effectExpireKey(0, "player", c.Name, "fortified")] {
+					t.Fatalf("expected renewal and fortified expiries on the scheduler, got %v", keys)
+				}
+
+				clock.Advance(10 * time.Second)
+				schedClock.Advance(10 * time.Second)
+				processServerTick()
+				if len(c.Effects) != 2 {
+					t.Fatalf("expected the world tick to leave expiry to the scheduler, got %#v", c.Effects)
+				}
+				runScheduledEvents()
+				if n := countMessages(conn.DrainMessages(t), RespEffectExpired); n != 2 || len(c.Effects) != 0 {
+					t.Fatalf("expected renewal and fortified to expire, got %d left %#v", n, c.Effects)
+				}
+			})
+		})
+	})
+}
+
+func TestSkillEffectsOnMobs(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	warrior, conn := newVisibilityTestSession("Berserker", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(warrior)
+	warrior.Character.Class = "Warrior"
+	warrior.Character.Skills = map[string]int{"cleave": 2}
+	warrior.Character.HP, warrior.Character.MaxHP = 100000, 100000
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			mob := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			mob.HP, mob.MaxHP = 100000, 100000
+			withWorldMob(World1, mob.ID, mob, func() {
+				w := worldSimFor(World1)
+				w.tickRate = time.Second
+				if _, ok, reason := attackMob(warrior, mob.ID, "cleave"); !ok {
+					t.Fatalf("cleave failed: %s", reason)
+				}
+				applied := toMap(lastMessage(conn.DrainMessages(t), RespEffectApplied).Payload)
+				if applied["target_kind"] != "mob" || applied["effect_id"] != "bleeding" || toInt(applied, "magnitude") != 12 {
+					t.Fatalf("expected cleave rank 2 to bleed the mob for 12, got %#v", applied)
+				}
+
+				hp, threat := mob.HP, mob.threat[warrior.Character.Name]
+				clock.Advance(time.Second)
+				processServerTick()
+				if mob.HP >= hp || mob.threat[warrior.Character.Name] <= threat {
+					t.Fatalf("expected bleeding to damage the mob and add threat, HP %d->%d", hp, mob.HP)
+				}
+
+				// Damage over time never lands the killing blow.
+				mob.HP = 1
+				clock.Advance(time.Second)
+				processServerTick()
+				if mob.HP != 1 {
+					t.Fatalf("expected bleeding to leave the mob on 1 HP, got %d", mob.HP)
+				}
+
+				// A stunned mob stands still and does not swing.
+				mob.HP = mob.MaxHP
+				w.do(func(w *worldSim, outbox *mobOutbox) {
+					applyMobEffectLocked(mob, "stunned", "test", 0, clock.Now(), outbox)
+				})
+				conn.DrainMessages(t)
+				hpBefore := warrior.Character.HP
+				clock.Advance(time.Second)
+				processServerTick()
+				if warrior.Character.HP != hpBefore || countMessages(conn.DrainMessages(t), RespMobHit) > 0 {
+					t.Fatalf("expected a stunned mob not to attack")
+				}
+
+				w.do(func(w *worldSim, outbox *mobOutbox) { startMobReturn(mob, outbox) })
+				if len(mob.effects) != 0 {
+					t.Fatalf("expected an evading mob to shed its effects, got %#v", mob.effects)
+				}
+			})
+		})
+	})
+}
+
+func TestSlowShortensMoves(t *testing.T) {
+	c := MockCharacter()
+	now := time.Now()
+	from, to := Position{}, Position{X: 8}
+	if !slowedMoveValid(c, from, to) {
+		t.Fatalf("expected an unslowed 8-unit move to be valid")
+	}
+	c.Effects, _ = addEffect(nil, effectCatalog["shocked"], "Caster", 40, 4*time.Second, now)
+	if slowedMoveValid(c, from, to) || !slowedMoveValid(c, from, Position{X: 6}) {
+		t.Fatalf("expected a 40%% slow to cap moves at 6 units")
+	}
+}
+
+func TestOnlyLongBuffsSurviveLogin(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := MockCharacter()
+	c.Effects, _ = addEffect(c.Effects, effectCatalog["rowan_blessing"], "Elder Rowan", 10, 30*time.Minute, now)
+	c.Effects, _ = addEffect(c.Effects, effectCatalog["fortified"], c.Name, 15, 8*time.Second, now)
+	c.Effects, _ = addEffect(c.Effects, effectCatalog["bleeding"], "Berserker", 6, 6*time.Second, now)
+
+	keepPersistentEffects(c, now.Add(time.Second))
+	if len(c.Effects) != 1 || c.Effects[0].ID != "rowan_blessing" {
+		t.Fatalf("expected only the blessing to survive logout, got %#v", c.Effects)
+	}
+	keepPersistentEffects(c, now.Add(time.Hour))
+	if len(c.Effects) != 0 {
+		t.Fatalf("expected an expired blessing to be dropped, got %#v", c.Effects)
+	}
+}
@@ -24,22 +24,6 @@ func isStorageRestrictedItem(item Item) bool {
 	}
 }
 
-func hasNearbyStorageNPC(session *ClientSession) bool {
-	if session == nil || session.World == nil {
-		return false
-	}
-	npcs := worldNPCs[session.World.ID]
-	for _, npc := range npcs {
-		if !strings.Contains(strings.ToLower(npc.Name), "storage") {
-			continue
-		}
-		if isVisible(session.Position, npc.Position) {
-			return true
-		}
-	}
-	return false
-}
-
 func storageViewPayload(c *Character) map[string]interface{} {
 	return map[string]interface{}{
 		"capacity":    storageStackLimit,
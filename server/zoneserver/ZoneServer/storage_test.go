@@ -2,19 +2,19 @@ package main
 
 import "testing"
 
-func TestHasNearbyStorageNPC(t *testing.T) {
+func TestNearbyStorageNPC(t *testing.T) {
 	worlds = DefaultWorlds()
 	session := &ClientSession{
 		Character: MockCharacter(),
 		World:     worlds[World1],
 		Position:  DefaultSpawnPosition(World1),
 	}
-	if !hasNearbyStorageNPC(session) {
+	if nearbyNPCWithService(session, NPCServiceStorage) == nil {
 		t.Fatalf("expected storage NPC nearby at default world1 spawn")
 	}
 
 	session.Position = Position{X: 200, Y: 0, Z: 200}
-	if hasNearbyStorageNPC(session) {
+	if nearbyNPCWithService(session, NPCServiceStorage) != nil {
 		t.Fatalf("expected no nearby storage NPC when far from world1 storage keeper")
 	}
 }
@@ -40,8 +40,8 @@ func TestStorageViewRequiresNearbyNPC(t *testing.T) {
 		t.Fatalf("expected handled=true modified=false, got handled=%v modified=%v", handled, modified)
 	}
 	msgs := conn.DrainMessages(t)
-	if len(msgs) != 1 || msgs[0].Command != RespStorageRejected || msgs[0].Payload != "STORAGE_NPC_REQUIRED" {
-		t.Fatalf("expected STORAGE_REJECTED/STORAGE_NPC_REQUIRED, got %#v", msgs)
+	if len(msgs) != 1 || msgs[0].Command != RespStorageRejected || msgs[0].Payload != "NPC_REQUIRED" {
+		t.Fatalf("expected STORAGE_REJECTED/NPC_REQUIRED, got %#v", msgs)
 	}
 
 	session.Position = DefaultSpawnPosition(World1)
@@ -0,0 +1,191 @@
+package main
+
+import "time"
+
+// Support skill kinds.
+const (
+	SupportHeal      = "heal"      // restores HP
+	SupportShield    = "shield"    // puts the skill's damage shield on the target
+	SupportResurrect = "resurrect" // raises a party member at their corpse
+)
+
+// supportHealScale turns a heal skill's bonus into HP restored.
+const supportHealScale = 3
+
+// castSupport uses a support skill on targetName, or on the caster when
+// targetName is empty. Instant skills land at once; cast-time skills are
+// queued on the world sim and answered when they land.
+func castSupport(s *ClientSession, skillID, targetName string) (map[string]interface{}, bool, string) {
+	if sessionStunned(s) {
+		return nil, false, "STUNNED"
+	}
+	def, ok := learnedSkill(s, skillID)
+	if !ok {
+		return nil, false, "SKILL_NOT_LEARNED"
+	}
+	if def.Support == "" {
+		return nil, false, "NOT_A_SUPPORT_SKILL"
+	}
+	initWorldEntities()
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return nil, false, "WORLD_UNAVAILABLE"
+	}
+
+	var result map[string]interface{}
+	reason := "OK"
+	ok = false
+	w.doFor(s, func(w *worldSim, outbox *mobOutbox) {
+		var target *ClientSession
+		if target, reason = supportTarget(s, def, targetName); target == nil {
+			return
+		}
+		if sessionCasting(s) {
+			reason = "ALREADY_CASTING"
+			return
+		}
+		now := simTickClock()
+		if ok, reason = useSkill(s, def, now); !ok {
+			return
+		}
+		if def.CastMS > 0 {
+			queueSkillCastLocked(w, s, &skillCast{skillID: def.ID, ally: target.Character.Name, landsAt: now.Add(time.Duration(def.CastMS) * time.Millisecond)})
+			result = map[string]interface{}{
+				"skill_id": def.ID,
+				"support":  def.Support,
+				"target":   target.Character.Name,
+				"status":   "CASTING",
+				"cast_ms":  def.CastMS,
+			}
+		} else {
+			result = landSupportLocked(w, outbox, s, def, target)
+		}
+		result["resource"] = skillResourcePayload(s)
+	})
+	return result, ok, reason
+}
+
+// supportTarget resolves who a support skill lands on and checks that they
+// are friendly and in range: party members, mutual friends or the caster.
+// Resurrection needs a dead party member within range of their corpse.
+func supportTarget(s *ClientSession, def SkillDefinition, targetName string) (*ClientSession, string) {
+	target := s
+	if targetName != "" && targetName != s.Character.Name {
+		target = findSessionByCharacterName(targetName)
+		if target == nil || !target.Authenticated || target.Character == nil {
+			return nil, "TARGET_OFFLINE"
+		}
+		if target.World == nil || target.World.ID != s.World.ID {
+			return nil, "TARGET_OTHER_WORLD"
+		}
+		party := arePartyMates(s.Character.Name, target.Character.Name)
+		if def.Support == SupportResurrect && !party {
+			return nil, "NOT_IN_PARTY"
+		}
+		friends := s.Character.Friends[target.Character.Name] && target.Character.Friends[s.Character.Name]
+		if !party && !friends {
+			return nil, "NOT_FRIENDLY"
+		}
+	}
+
+	at := target.Position
+	switch def.Support {
+	case SupportResurrect:
+		if target == s {
+			return nil, "INVALID_TARGET"
+		}
+		if target.Character.Corpse == nil {
+			return nil, "TARGET_NOT_DEAD"
+		}
+		at = *target.Character.Corpse
+	case SupportHeal:
+		if target.Character.HP >= target.Character.MaxHP {
+			return nil, "TARGET_AT_FULL_HP"
+		}
+	}
+	if distance(s.Position, at) > def.Range {
+		return nil, "TARGET_OUT_OF_RANGE"
+	}
+	return target, "OK"
+}
+
+// landSupportLocked applies def from s to target and returns the
+// SUPPORT_RESULT payload. Heals earn threat on the mobs fighting the
+// target, as the caster's own heals do. HP and XP changes are posted to the
+// target's action path. The caller holds the world lock.
+func landSupportLocked(w *worldSim, outbox *mobOutbox, s *ClientSession, def SkillDefinition, target *ClientSession) map[string]interface{} {
+	c := target.Character
+	bonus := skillBonus(s.Character, def.ID)
+	result := map[string]interface{}{
+		"skill_id":  def.ID,
+		"support":   def.Support,
+		"target":    c.Name,
+		"target_hp": c.HP,
+	}
+	switch def.Support {
+	case SupportHeal:
+		healed := minInt(bonus*supportHealScale, maxInt(c.MaxHP-c.HP, 0))
+		recordHealingThreatLocked(w, s.Character.Name, c.Name, healed)
+		result["healed"] = healed
+		result["target_hp"] = c.HP + healed
+		from := s.Character.Name
+		outbox.post(target, func() {
+			c.HP = minInt(c.HP+healed, c.MaxHP)
+			sendMessage(target.Conn, ServerMessage{Command: RespHealed, Payload: map[string]interface{}{
+				"from":     from,
+				"skill_id": def.ID,
+				"amount":   healed,
+				"hp":       c.HP,
+				"max_hp":   c.MaxHP,
+			}})
+		})
+	case SupportShield:
+		effectID := skillEffects[def.ID]
+		magnitude := effectCatalog[effectID].Magnitude * maxInt(s.Character.Skills[def.ID], 1)
+		applySessionEffect(target, effectID, s.Character.Name, magnitude, 0, simTickClock(), outbox)
+		result["effect_id"] = effectID
+		result["absorb"] = magnitude
+	case SupportResurrect:
+		restored := c.XPDebt * minInt(bonus, 100) / 100
+		result["xp_restored"] = restored
+		result["target_hp"] = c.MaxHP
+		from := s.Character.Name
+		outbox.post(target, func() {
+			c.XPDebt = maxInt(c.XPDebt-restored, 0)
+			c.Corpse = nil
+			c.HP = c.MaxHP
+			sendMessage(target.Conn, ServerMessage{Command: RespResurrected, Payload: map[string]interface{}{
+				"from":        from,
+				"xp_restored": restored,
+				"xp_debt":     c.XPDebt,
+				"hp":          c.HP,
+			}})
+		})
+	}
+	if target != s {
+		from := s.Character.Name
+		outbox.post(target, func() { queuePersistCharacter(c, "support from "+from) })
+	}
+	return result
+}
+
+// landSupportCastLocked lands a support cast from resolveSkillCastsLocked,
+// rechecking the target since it may have moved, died or recovered.
+func landSupportCastLocked(w *worldSim, outbox *mobOutbox, s *ClientSession, cast *skillCast) {
+	def, ok := learnedSkill(s, cast.skillID)
+	if !ok {
+		outbox.send(s, ServerMessage{Command: RespSupportRejected, Payload: "SKILL_NOT_LEARNED"})
+		return
+	}
+	target, reason := supportTarget(s, def, cast.ally)
+	if target == nil {
+		outbox.send(s, ServerMessage{Command: RespSupportRejected, Payload: reason})
+		return
+	}
+	result := landSupportLocked(w, outbox, s, def, target)
+	result["resource"] = skillResourcePayload(s)
+	outbox.post(s, func() {
+		sendMessage(s.Conn, ServerMessage{Command: RespSupportResult, Payload: result})
+		queuePersistCharacter(s.Character, "a cast")
+	})
+}
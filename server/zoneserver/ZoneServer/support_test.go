@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCastSupportHealsShieldsAndResurrects(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	healer, healerConn := newVisibilityTestSession("Medic", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(healer)
+	healer.Character.Class = "Healing Knight"
+	healer.Character.Skills = map[string]int{"holy_slash": 1, "renewal_burst": 2, "guardian_shield": 1, "divine_return": 1}
+	ally, allyConn := newVisibilityTestSession("Vanguard", worlds[World1], Position{X: 110, Y: 0, Z: 100})
+	defer unregisterSession(ally)
+	friend, friendConn := newVisibilityTestSession("Pal", worlds[World1], Position{X: 100, Y: 0, Z: 110})
+	defer unregisterSession(friend)
+	stranger, _ := newVisibilityTestSession("Passerby", worlds[World1], Position{X: 105, Y: 0, Z: 105})
+	defer unregisterSession(stranger)
+
+	if _, ok, reason := partyInvite("Medic", "Vanguard"); !ok {
+		t.Fatalf("party invite failed: %s", reason)
+	}
+	if _, ok, reason := partyAccept("Vanguard", "Medic"); !ok {
+		t.Fatalf("party accept failed: %s", reason)
+	}
+	healer.Character.Friends["Pal"] = true
+	friend.Character.Friends["Medic"] = true
+
+	withSimClock(func(clock *fakeClock) {
+		mob := aiTestMob(Position{X: 400, Y: 0, Z: 400})
+		mob.threat = map[string]int{"Vanguard": 10}
+		withWorldMob(World1, mob.ID, mob, func() {
+			w := worldSimFor(World1)
+			w.tickRate = 100 * time.Millisecond
+
+			cases := []struct {
+				skill, target, want string
+			}{
+				{"holy_slash", "Vanguard", "NOT_A_SUPPORT_SKILL"},
+				{"divine_return", "Passerby", "NOT_IN_PARTY"},
+				{"guardian_shield", "Passerby", "NOT_FRIENDLY"},
+				{"renewal_burst", "Vanguard", "TARGET_AT_FULL_HP"},
+				{"divine_return", "Vanguard", "TARGET_NOT_DEAD"},
+				{"guardian_shield", "Nobody", "TARGET_OFFLINE"},
+			}
+			for _, tc := range cases {
+				if _, ok, reason := castSupport(healer, tc.skill, tc.target); ok || reason != tc.want {
+					t.Fatalf("%s on %s: expected %s, got ok=%v reason=%s", tc.skill, tc.target, tc.want, ok, reason)
+				}
+			}
+			stranger.Character.Skills = map[string]int{"renewal_burst": 1}
+			if _, ok, reason := castSupport(stranger, "renewal_burst", "Medic"); ok || reason != "SKILL_NOT_LEARNED" {
+				t.Fatalf("expected a non-Healing Knight to lack the skill, got ok=%v reason=%s", ok, reason)
+			}
+
+			// An instant shield on a mutual friend.
+			result, ok, reason := castSupport(healer, "guardian_shield", "Pal")
+			if !ok || result["effect_id"] != "guardian_ward" || result["absorb"] != 40 {
+				t.Fatalf("expected guardian_shield to ward Pal, got ok=%v reason=%s %#v", ok, reason, result)
+			}
+			applied := toMap(lastMessage(friendConn.DrainMessages(t), RespEffectApplied).Payload)
+			if applied["effect_id"] != "guardian_ward" || applied["source"] != "Medic" {
+				t.Fatalf("expected Pal to be told about the ward, got %#v", applied)
+			}
+
+			// A cast-time heal on a party member, credited as threat.
+			ally.Character.HP = 20
+			result, ok, reason = castSupport(healer, "renewal_burst", "Vanguard")
+			if !ok || result["status"] != "CASTING" {
+				t.Fatalf("expected renewal_burst to start casting, got ok=%v reason=%s %#v", ok, reason, result)
+			}
+			healerConn.DrainMessages(t)
+			clock.Advance(time.Second)
+			w.do(func(w *worldSim, outbox *mobOutbox) {
+				applyMobEffectLocked(mob, "stunned", "test", 0, clock.Now(), outbox)
+			})
+			clock.Advance(500 * time.Millisecond)
+			processServerTick()
+			landed := toMap(lastMessage(healerConn.DrainMessages(t), RespSupportResult).Payload)
+			if toInt(landed, "healed") != 42 || ally.Character.HP != 62 {
+				t.Fatalf("expected a 42 HP heal, got %#v (HP %d)", landed, ally.Character.HP)
+			}
+			healed := toMap(lastMessage(allyConn.DrainMessages(t), RespHealed).Payload)
+			if healed["from"] != "Medic" || toInt(healed, "amount") != 42 {
+				t.Fatalf("expected HEALED for the ally, got %#v", healed)
+			}
+			if mob.threat["Medic"] != 21 {
+				t.Fatalf("expected the heal to add threat, got %#v", mob.threat)
+			}
+
+			// Resurrection works at the corpse, not where the ally stands now.
+			ally.Character.Corpse = &Position{X: 130, Y: 0, Z: 100}
+			ally.Character.XPDebt = 100
+			if _, ok, reason := castSupport(healer, "divine_return", "Vanguard"); ok || reason != "TARGET_OUT_OF_RANGE" {
+				t.Fatalf("expected the corpse to be out of range, got ok=%v reason=%s", ok, reason)
+			}
+			healer.Position = Position{X: 125, Y: 0, Z: 100}
+			skillStateOf(healer).spent = 0
+			if _, ok, reason := castSupport(healer, "divine_return", "Vanguard"); !ok {
+				t.Fatalf("divine_return failed: %s", reason)
+			}
+			clock.Advance(3 * time.Second)
+			processServerTick()
+			if ally.Character.Corpse != nil || ally.Character.XPDebt != 75 || ally.Character.HP != ally.Character.MaxHP {
+				t.Fatalf("expected the ally raised with 25%% of the debt restored, got corpse=%v debt=%d", ally.Character.Corpse, ally.Character.XPDebt)
+			}
+			raised := lastMessage(allyConn.DrainMessages(t), RespResurrected)
+			if toInt(toMap(raised.Payload), "xp_restored") != 25 {
+				t.Fatalf("expected RESURRECTED, got %#v", raised)
+			}
+		})
+	})
+}
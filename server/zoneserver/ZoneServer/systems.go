@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"time"
 )
 
 type Element string
@@ -46,6 +47,9 @@ type Item struct {
 	GearLevel int     `json:"gear_level"`
 	MinSTR    int     `json:"min_str"`
 	MinDEX    int     `json:"min_dex"`
+	// AttackSpeedMS overrides the class swing interval for weapons; see
+	// deriveStats.
+	AttackSpeedMS int `json:"attack_speed_ms,omitempty"`
 }
 
 type PetState struct {
@@ -93,6 +97,21 @@ type SkillDefinition struct {
 	MaxRank     int    `json:"max_rank"`
 	BaseBonus   int    `json:"base_bonus"`
 	Description string `json:"description"`
+	// CooldownMS and Cost (in the class resource) apply to each use;
+	// skills with a CastMS land that long after they are started.
+	CooldownMS int `json:"cooldown_ms"`
+	Cost       int `json:"cost"`
+	CastMS     int `json:"cast_ms"`
+	// Shape is one of the SkillShape constants (empty is single target);
+	// area shapes hit up to MaxTargets mobs within Radius. See skill_areas.go.
+	Shape      string  `json:"shape,omitempty"`
+	Radius     float64 `json:"radius,omitempty"`
+	Angle      float64 `json:"angle,omitempty"` // cone width in degrees
+	Range      float64 `json:"range,omitempty"` // how far away a ground or support target may be
+	MaxTargets int     `json:"max_targets,omitempty"`
+	// Support skills are cast on friendly players with CAST_SUPPORT; see
+	// support.go.
+	Support string `json:"support,omitempty"`
 }
 
 type NPCEntity struct {
@@ -100,6 +119,7 @@ type NPCEntity struct {
 	Name     string   `json:"name"`
 	WorldID  WorldID  `json:"world_id"`
 	Position Position `json:"position"`
+	Services []string `json:"services"` // see npc_services.go
 }
 
 type MobEntity struct {
@@ -111,6 +131,47 @@ type MobEntity struct {
 	MaxHP      int      `json:"max_hp"`
 	Position   Position `json:"position"`
 	RespawnSec int      `json:"respawn_sec"`
+	Template   string   `json:"template"`
+	LootTable  string   `json:"-"`
+	AIState    string   `json:"ai_state"`
+	Spawn      Position `json:"spawn"`
+	// Boss carries phase info for world bosses in LIST_ENTITIES.
+	Boss *BossStatus `json:"boss,omitempty"`
+
+	// Combat profile copied from the template; zero values fall back to
+	// the defaults in mob_abilities.go.
+	AttackIntervalMS int             `json:"-"`
+	DamageMin        int             `json:"-"`
+	DamageMax        int             `json:"-"`
+	Abilities        []MobAbility    `json:"-"`
+	Defense          int             `json:"defense"`
+	Element          Element         `json:"element"`
+	Resistances      map[Element]int `json:"resistances,omitempty"`
+	// Training dummies never move, fight back, die or reward; see training.go.
+	Training bool `json:"training,omitempty"`
+
+	targetName string
+	patrolTo   Position
+	threat     map[string]int // character name -> accumulated threat
+	boss       *bossState     // non-nil for world bosses; see world_boss.go
+	effects    []ActiveEffect // see status_effects.go
+
+	// nextAttackAt and abilityReadyAt are wall-clock cooldowns; see
+	// mobAttackSession.
+	nextAttackAt   time.Time
+	abilityReadyAt map[string]time.Time
+
+	// path is the cached route toward the cell pathGoal; see moveMobTo.
+	path     []Position
+	pathGoal gridCell
+	// giveUpName/giveUpCell remember a chase abandoned for lack of a path so
+	// the mob does not re-aggro while that player stays put.
+	giveUpName string
+	giveUpCell gridCell
+
+	// lastSentPos is the position carried by the most recent MOB_MOVED or
+	// MOB_SPAWNED broadcast, used to skip unchanged mobs.
+	lastSentPos Position
 }
 
 var quests = map[string]Quest{
@@ -152,45 +213,44 @@ var quests = map[string]Quest{
 var (
 	worldUnlockHistory = map[WorldID]string{}
 	historyMu          sync.RWMutex
-	mobMu              sync.RWMutex
-	worldMobs          map[WorldID]map[string]*MobEntity
 )
 
 var skillCatalog = map[string]map[string]SkillDefinition{
 	"Archer": {
-		"precise_shot": {ID: "precise_shot", Name: "Precise Shot", MaxRank: 5, BaseBonus: 7, Description: "Single-target precision boost"},
-		"evasion_step": {ID: "evasion_step", Name: "Evasion Step", MaxRank: 3, BaseBonus: 3, Description: "Reduces death chance in risky fights"},
-		"burst_arrow":  {ID: "burst_arrow", Name: "Burst Arrow", MaxRank: 5, BaseBonus: 10, Description: "High burst skill"},
+		"precise_shot": {ID: "precise_shot", Name: "Precise Shot", MaxRank: 5, BaseBonus: 7, Description: "Single-target precision boost", CooldownMS: 3000, Cost: 15},
+		"evasion_step": {ID: "evasion_step", Name: "Evasion Step", MaxRank: 3, BaseBonus: 3, Description: "Raises evasion, dodging attacks and death in risky fights", CooldownMS: 10000, Cost: 20},
+		"burst_arrow":  {ID: "burst_arrow", Name: "Burst Arrow", MaxRank: 5, BaseBonus: 10, Description: "High burst skill", CooldownMS: 6000, Cost: 30, CastMS: 800},
 	},
 	"Warrior": {
-		"cleave":      {ID: "cleave", Name: "Cleave", MaxRank: 5, BaseBonus: 9, Description: "Heavy melee sweep"},
-		"iron_wall":   {ID: "iron_wall", Name: "Iron Wall", MaxRank: 3, BaseBonus: 3, Description: "Defensive stance"},
-		"battle_rush": {ID: "battle_rush", Name: "Battle Rush", MaxRank: 5, BaseBonus: 8, Description: "Momentum attack"},
+		"cleave":      {ID: "cleave", Name: "Cleave", MaxRank: 5, BaseBonus: 9, Description: "Heavy melee sweep", CooldownMS: 4000, Cost: 20, Shape: SkillShapeCone, Radius: 8, Angle: 120, MaxTargets: 4},
+		"iron_wall":   {ID: "iron_wall", Name: "Iron Wall", MaxRank: 3, BaseBonus: 3, Description: "Defensive stance", CooldownMS: 12000, Cost: 25},
+		"battle_rush": {ID: "battle_rush", Name: "Battle Rush", MaxRank: 5, BaseBonus: 8, Description: "Momentum attack", CooldownMS: 6000, Cost: 30, Shape: SkillShapeCircle, Radius: 6, MaxTargets: 3},
 	},
 	"Mage": {
-		"arc_bolt":       {ID: "arc_bolt", Name: "Arc Bolt", MaxRank: 5, BaseBonus: 10, Description: "Elemental bolt"},
-		"mana_barrier":   {ID: "mana_barrier", Name: "Mana Barrier", MaxRank: 3, BaseBonus: 3, Description: "Protective shield"},
-		"cataclysm_nova": {ID: "cataclysm_nova", Name: "Cataclysm Nova", MaxRank: 5, BaseBonus: 12, Description: "Explosive spell"},
+		"arc_bolt":       {ID: "arc_bolt", Name: "Arc Bolt", MaxRank: 5, BaseBonus: 10, Description: "Elemental bolt", CooldownMS: 2000, Cost: 15, CastMS: 1000},
+		"mana_barrier":   {ID: "mana_barrier", Name: "Mana Barrier", MaxRank: 3, BaseBonus: 3, Description: "Protective shield", CooldownMS: 15000, Cost: 30},
+		"cataclysm_nova": {ID: "cataclysm_nova", Name: "Cataclysm Nova", MaxRank: 5, BaseBonus: 12, Description: "Explosive spell", CooldownMS: 12000, Cost: 50, CastMS: 2000, Shape: SkillShapeGround, Radius: 10, Range: 30, MaxTargets: 6},
 	},
 	"Healing Knight": {
-		"holy_slash":      {ID: "holy_slash", Name: "Holy Slash", MaxRank: 5, BaseBonus: 8, Description: "Hybrid strike"},
-		"guardian_shield": {ID: "guardian_shield", Name: "Guardian Shield", MaxRank: 3, BaseBonus: 4, Description: "Shielded protection"},
-		"renewal_burst":   {ID: "renewal_burst", Name: "Renewal Burst", MaxRank: 5, BaseBonus: 7, Description: "Healing pulse"},
+		"holy_slash":      {ID: "holy_slash", Name: "Holy Slash", MaxRank: 5, BaseBonus: 8, Description: "Hybrid strike", CooldownMS: 3000, Cost: 15},
+		"guardian_shield": {ID: "guardian_shield", Name: "Guardian Shield", MaxRank: 3, BaseBonus: 4, Description: "Shielded protection", CooldownMS: 12000, Cost: 25, Support: SupportShield, Range: 30},
+		"renewal_burst":   {ID: "renewal_burst", Name: "Renewal Burst", MaxRank: 5, BaseBonus: 7, Description: "Healing pulse", CooldownMS: 8000, Cost: 35, CastMS: 1500, Support: SupportHeal, Range: 30},
+		"divine_return":   {ID: "divine_return", Name: "Divine Return", MaxRank: 3, BaseBonus: 25, Description: "Raises a fallen party member", CooldownMS: 60000, Cost: 60, CastMS: 3000, Support: SupportResurrect, Range: 10},
 	},
 }
 
 var worldNPCs = map[WorldID][]NPCEntity{
 	World1: {
-		{ID: "npc_elder_rowan", Name: "Elder Rowan", WorldID: World1, Position: Position{X: -5, Y: 0, Z: -5}},
-		{ID: "npc_gear_smith", Name: "Gear Smith Halan", WorldID: World1, Position: Position{X: 10, Y: 0, Z: -10}},
-		{ID: "npc_storage_keeper", Name: "Storage Keeper Lysa", WorldID: World1, Position: Position{X: -10, Y: 0, Z: 5}},
-		{ID: "npc_teleporter", Name: "Planar Teleporter", WorldID: World1, Position: Position{X: 0, Y: 0, Z: 15}},
+		{ID: "npc_elder_rowan", Name: "Elder Rowan", WorldID: World1, Position: Position{X: -5, Y: 0, Z: -5}, Services: []string{NPCServiceQuestGiver, NPCServiceTrainer}},
+		{ID: "npc_gear_smith", Name: "Gear Smith Halan", WorldID: World1, Position: Position{X: 10, Y: 0, Z: -10}, Services: []string{NPCServiceSmith, NPCServiceVendor}},
+		{ID: "npc_storage_keeper", Name: "Storage Keeper Lysa", WorldID: World1, Position: Position{X: -10, Y: 0, Z: 5}, Services: []string{NPCServiceStorage}},
+		{ID: "npc_teleporter", Name: "Planar Teleporter", WorldID: World1, Position: Position{X: 0, Y: 0, Z: 15}, Services: []string{NPCServiceTeleporter}},
 	},
 	World2: {
-		{ID: "npc_shattered_keeper", Name: "Shattered Keeper", WorldID: World2, Position: Position{X: 0, Y: 0, Z: -10}},
-		{ID: "npc_teleporter_w2", Name: "Planar Teleporter", WorldID: World2, Position: Position{X: 0, Y: 0, Z: 15}},
+		{ID: "npc_shattered_keeper", Name: "Shattered Keeper", WorldID: World2, Position: Position{X: 0, Y: 0, Z: -10}, Services: []string{NPCServiceStorage, NPCServiceSmith, NPCServiceTrainer, NPCServiceQuestGiver}},
+		{ID: "npc_teleporter_w2", Name: "Planar Teleporter", WorldID: World2, Position: Position{X: 0, Y: 0, Z: 15}, Services: []string{NPCServiceTeleporter}},
 	},
 	World3: {
-		{ID: "npc_myth_warden", Name: "Myth Warden", WorldID: World3, Position: Position{X: 0, Y: 0, Z: 0}},
+		{ID: "npc_myth_warden", Name: "Myth Warden", WorldID: World3, Position: Position{X: 0, Y: 0, Z: 0}, Services: []string{NPCServiceStorage, NPCServiceSmith, NPCServiceTrainer, NPCServiceTeleporter, NPCServiceQuestGiver}},
 	},
 }
@@ -0,0 +1,158 @@
+package main
+
+import "sort"
+
+const (
+	// threatSwitchPct is how far a challenger must out-threat the current
+	// target before the mob turns: 110 means it needs more than 110%.
+	threatSwitchPct = 110
+	// proximityThreat is the token threat a player earns by being the one a
+	// mob noticed first, so any real damage elsewhere can pull it away.
+	proximityThreat = 1
+	// healThreatDivisor scales healing into threat on every engaged mob.
+	healThreatDivisor = 2
+)
+
+// tauntSkills force the mob onto the caster and lift the caster's threat just
+// past the switch threshold, so the taunter holds aggro until out-damaged.
+var tauntSkills = map[string]bool{
+	"iron_wall":       true,
+	"guardian_shield": true,
+}
+
+// healingSkills restore the caster's HP by their skill bonus when used.
+var healingSkills = map[string]bool{
+	"renewal_burst": true,
+}
+
+// addThreatLocked credits name with amount threat on mob. Caller holds the
+// world lock.
+func addThreatLocked(mob *MobEntity, name string, amount int) {
+	if amount <= 0 || name == "" {
+		return
+	}
+	if mob.threat == nil {
+		mob.threat = map[string]int{}
+	}
+	mob.threat[name] += amount
+}
+
+// tauntMobLocked makes name the mob's target with enough threat to keep it
+// past the hysteresis of whoever was on top. Caller holds the world lock.
+func tauntMobLocked(mob *MobEntity, name string) {
+	top := 0
+	for other, value := range mob.threat {
+		if other != name && value > top {
+			top = value
+		}
+	}
+	if need := top*threatSwitchPct/100 + 1; mob.threat[name] < need {
+		addThreatLocked(mob, name, need-mob.threat[name])
+	}
+	mob.targetName = name
+}
+
+// resetMobThreatLocked forgets every threat entry, e.g. when the mob dies or
+// leashes home. Caller holds the world lock.
+func resetMobThreatLocked(mob *MobEntity) {
+	mob.threat = nil
+	mob.targetName = ""
+}
+
+func clearThreatLocked(mob *MobEntity, name string) {
+	delete(mob.threat, name)
+	if mob.targetName == name {
+		mob.targetName = ""
+	}
+}
+
+// clearPlayerThreatLocked drops name from every mob in w, used when the
+// player dies. Caller holds the world lock.
+func clearPlayerThreatLocked(w *worldSim, name string) {
+	for _, mob := range w.mobs {
+		clearThreatLocked(mob, name)
+	}
+}
+
+// clearPlayerThreat drops name from every mob in worldID once the player has
+// left that world or disconnected.
+func clearPlayerThreat(worldID WorldID, name string) {
+	w := worldSimFor(worldID)
+	if w == nil || name == "" {
+		return
+	}
+	w.do(func(w *worldSim, _ *mobOutbox) {
+		clearPlayerThreatLocked(w, name)
+	})
+}
+
+// threatRankLocked returns name's 1-based position on mob's threat table, or 0
+// when name has no threat. Ties share a rank. Caller holds the world lock.
+func threatRankLocked(mob *MobEntity, name string) int {
+	own, ok := mob.threat[name]
+	if !ok {
+		return 0
+	}
+	rank := 1
+	for other, value := range mob.threat {
+		if other != name && value > own {
+			rank++
+		}
+	}
+	return rank
+}
+
+// recordHealingThreatLocked credits a heal of amount HP on target to healer
+// on every live mob in w whose threat table holds target, so healing someone
+// draws only the mobs fighting them. Caller holds the world lock.
+func recordHealingThreatLocked(w *worldSim, healer, target string, amount int) {
+	threat := maxInt(amount/healThreatDivisor, 1)
+	for _, mob := range w.mobs {
+		if mob.HP <= 0 {
+			continue
+		}
+		if _, engaged := mob.threat[target]; !engaged {
+			continue
+		}
+		addThreatLocked(mob, healer, threat)
+	}
+}
+
+// mobThreatTarget picks who mob should fight this tick. Threat held by players
+// who are no longer targetable (dead, gone or out of the world) is cleared
+// first. The current target is kept unless another candidate has more than
+// threatSwitchPct of its threat.
+func mobThreatTarget(mob *MobEntity, candidates []*ClientSession) *ClientSession {
+	if len(mob.threat) == 0 {
+		mob.targetName = ""
+		return nil
+	}
+	byName := make(map[string]*ClientSession, len(candidates))
+	for _, s := range candidates {
+		byName[s.Character.Name] = s
+	}
+	names := make([]string, 0, len(mob.threat))
+	for name := range mob.threat {
+		if byName[name] == nil {
+			clearThreatLocked(mob, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	best := names[0]
+	for _, name := range names[1:] {
+		if mob.threat[name] > mob.threat[best] {
+			best = name
+		}
+	}
+	current := mob.targetName
+	if byName[current] == nil || mob.threat[best]*100 > mob.threat[current]*threatSwitchPct {
+		mob.targetName = best
+	}
+	return byName[mob.targetName]
+}
@@ -0,0 +1,153 @@
+package main
+
+import "testing"
+
+func TestTauntPullsMobOffMageUntilOutDamaged(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	home := Position{X: 100, Y: 0, Z: 100}
+	mage, mageConn := newVisibilityTestSession("Glass", worlds[World1], Position{X: 110, Y: 0, Z: 100})
+	defer unregisterSession(mage)
+	warrior, warriorConn := newVisibilityTestSession("Bulwark", worlds[World1], Position{X: 90, Y: 0, Z: 100})
+	defer unregisterSession(warrior)
+	warrior.Character.Class = "Warrior"
+	warrior.Character.Skills = map[string]int{"iron_wall": 1}
+
+	withFixedRandIntn(0, func() {
+		withFixedRandFloat64(0.99, func() {
+			mob := aiTestMob(home)
+			mob.HP, mob.MaxHP = 5000, 5000
+			withWorldMob(World1, "mob_wolf_01", mob, func() {
+				result, ok, _ := attackMob(mage, "mob_wolf_01", "")
+				if !ok || result["threat_rank"] != 1 {
+					t.Fatalf("expected the first attacker to top the threat table, got %#v", result)
+				}
+
+				result, ok, _ = attackMob(warrior, "mob_wolf_01", "iron_wall")
+				if !ok || result["threat_rank"] != 1 || mob.targetName != "Bulwark" {
+					t.Fatalf("expected iron_wall to taunt, got rank=%v target=%q", result["threat_rank"], mob.targetName)
+				}
+				mageConn.DrainMessages(t)
+				warriorConn.DrainMessages(t)
+
+				processServerTick()
+				if !hasMessage(warriorConn.DrainMessages(t), RespMobHit, nil) || hasMessage(mageConn.DrainMessages(t), RespMobHit, nil) {
+					t.Fatalf("expected the taunted mob to hit the warrior only")
+				}
+
+				result, _, _ = attackMob(mage, "mob_wolf_01", "")
+				if result["threat_rank"] != 1 {
+					t.Fatalf("expected the mage to overtake after a second hit, got %#v", result)
+				}
+				processServerTick()
+				if mob.targetName != "Glass" {
+					t.Fatalf("expected the mob to turn on the mage, got %q", mob.targetName)
+				}
+			})
+		})
+	})
+}
+
+func TestThreatTargetHysteresisAndCleanup(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	home := Position{X: 100, Y: 0, Z: 100}
+	tank, _ := newVisibilityTestSession("Tank", worlds[World1], Position{X: 95, Y: 0, Z: 100})
+	defer unregisterSession(tank)
+	dps, _ := newVisibilityTestSession("Dps", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandFloat64(0.99, func() {
+			withWorldMob(World1, "mob_wolf_01", aiTestMob(home), func() {
+				mob := worldMobsForTests(World1)["mob_wolf_01"]
+				mob.threat = map[string]int{"Tank": 100, "Dps": 110}
+				mob.targetName = "Tank"
+
+				processServerTick()
+				if mob.targetName != "Tank" {
+					t.Fatalf("expected the mob to stay on its target within 110%%, got %q", mob.targetName)
+				}
+				mob.threat["Dps"] = 111
+				processServerTick()
+				if mob.targetName != "Dps" {
+					t.Fatalf("expected the mob to switch past 110%%, got %q", mob.targetName)
+				}
+
+				unregisterSession(dps)
+				if _, ok := mob.threat["Dps"]; ok || mob.targetName == "Dps" {
+					t.Fatalf("expected threat cleared when the target leaves, got %#v target=%q", mob.threat, mob.targetName)
+				}
+
+				tank.Character.HP = 1
+				clock.Advance(defaultMobAttackInterval)
+				processServerTick()
+				if len(mob.threat) != 0 || mob.targetName != "" {
+					t.Fatalf("expected threat cleared when the target dies, got %#v target=%q", mob.threat, mob.targetName)
+				}
+			})
+		})
+	})
+}
+
+func TestHealingDrawsOnlyTheMobsFightingTheTarget(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	medic, _ := newVisibilityTestSession("Mender", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(medic)
+	medic.Character.Class = "Healing Knight"
+	medic.Character.Skills = map[string]int{"renewal_burst": 2}
+	def, _ := learnedSkill(medic, "renewal_burst")
+	tank, _ := newVisibilityTestSession("Shieldwall", worlds[World1], Position{X: 105, Y: 0, Z: 100})
+	defer unregisterSession(tank)
+	idler, _ := newVisibilityTestSession("Idler", worlds[World1], Position{X: 95, Y: 0, Z: 100})
+	defer unregisterSession(idler)
+
+	withFixedRandIntn(0, func() {
+		withFixedRandFloat64(0.99, func() {
+			biter := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+			biter.threat = map[string]int{"Shieldwall": 50}
+			withWorldMob(World1, biter.ID, biter, func() {
+				brute := aiTestMob(Position{X: 100, Y: 0, Z: 100})
+				brute.ID = "mob_wolf_02"
+				brute.HP, brute.MaxHP = 5000, 5000
+				brute.threat = map[string]int{"Mender": 1}
+				w := worldSimFor(World1)
+				w.mobs[brute.ID] = brute
+
+				// Healing an ally draws the mobs fighting that ally.
+				tank.Character.HP = tank.Character.MaxHP - 40
+				w.do(func(w *worldSim, outbox *mobOutbox) {
+					landSupportLocked(w, outbox, medic, def, tank)
+				})
+				if tank.Character.HP != tank.Character.MaxHP || biter.threat["Mender"] != 20 || brute.threat["Mender"] != 1 {
+					t.Fatalf("expected half the 40 HP heal as threat on the tank's mob only, got biter=%#v brute=%#v", biter.threat, brute.threat)
+				}
+
+				idler.Character.HP = idler.Character.MaxHP - 40
+				w.do(func(w *worldSim, outbox *mobOutbox) {
+					landSupportLocked(w, outbox, medic, def, idler)
+				})
+				if biter.threat["Mender"] != 20 || brute.threat["Mender"] != 1 {
+					t.Fatalf("expected healing someone no mob is fighting to add no threat, got biter=%#v brute=%#v", biter.threat, brute.threat)
+				}
+
+				// A self heal draws every mob fighting the caster.
+				medic.Character.HP = medic.Character.MaxHP - 40
+				var result map[string]interface{}
+				w.do(func(w *worldSim, outbox *mobOutbox) {
+					result, _, _ = attackMobInWorld(w, outbox, medic, brute.ID, "renewal_burst")
+				})
+				healed, damage := toInt(result, "healed"), toInt(result, "damage")
+				if healed <= 0 {
+					t.Fatalf("expected renewal_burst to heal the caster, got %#v", result)
+				}
+				if biter.threat["Mender"] != 20+healed/2 || brute.threat["Mender"] != 1+damage+healed/2 {
+					t.Fatalf("expected the %d HP self heal as threat on both mobs, got biter=%#v brute=%#v", healed, biter.threat, brute.threat)
+				}
+			})
+		})
+	})
+}
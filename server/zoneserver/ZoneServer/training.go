@@ -0,0 +1,30 @@
+package main
+
+// attackTrainingDummy is the ATTACK command: a swing, with an optional
+// skill, at a server-owned training dummy. It resolves like ATTACK_MOB but
+// the dummy never falls, fights back or rewards XP or loot, so ATTACK can no
+// longer be farmed against targets the client makes up.
+func attackTrainingDummy(s *ClientSession, mobID, skillID string) (map[string]interface{}, bool, string) {
+	initWorldEntities()
+	w := worldSimFor(s.World.ID)
+	if w == nil {
+		return nil, false, "NOT_A_TRAINING_TARGET"
+	}
+	training := false
+	w.do(func(w *worldSim, _ *mobOutbox) {
+		mob, ok := w.mobs[mobID]
+		training = ok && mob.Training
+	})
+	if !training {
+		return nil, false, "NOT_A_TRAINING_TARGET"
+	}
+	if def, skilled := learnedSkill(s, skillID); skilled {
+		if skillShape(def) != SkillShapeSingle {
+			return nil, false, "AREA_SKILL_NOT_ALLOWED"
+		}
+		if def.CastMS > 0 {
+			return nil, false, "SKILL_NOT_INSTANT"
+		}
+	}
+	return attackMob(s, mobID, skillID)
+}
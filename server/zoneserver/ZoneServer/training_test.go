@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func trainingTestDummy(pos Position) *MobEntity {
+	return &MobEntity{
+		ID: "mob_training_dummy_01", Name: "Training Dummy", WorldID: World1, Level: 40, HP: 5000, MaxHP: 5000,
+		Position: pos, Spawn: pos, RespawnSec: 1, Template: "training_dummy", AIState: MobStateIdle, Training: true,
+	}
+}
+
+func TestDefaultSpawnsPlaceTrainingDummies(t *testing.T) {
+	data, err := loadSpawnData("")
+	if err != nil {
+		t.Fatalf("embedded spawn data failed to load: %v", err)
+	}
+	dummy := spawnWorldMobs(data)[World1]["mob_training_dummy_01"]
+	if dummy == nil || !dummy.Training || dummy.LootTable != "" {
+		t.Fatalf("expected a training dummy in world 1, got %#v", dummy)
+	}
+	if region := regionAt(World1, dummy.Position); region.Kind != RegionSafe {
+		t.Fatalf("expected the training yard inside town, got %s", region.ID)
+	}
+}
+
+func TestAttackOnlyHitsTrainingDummiesWithoutRewards(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	session, conn := newVisibilityTestSession("Trainee", worlds[World1], Position{X: 15, Y: 0, Z: 15})
+	defer unregisterSession(session)
+	c := session.Character
+	xp, level := c.XP, c.Level
+	boundName := c.Name
+	attack := func(payload map[string]interface{}) ServerMessage {
+		handleClientCommand(conn, session, map[*ClientSession]bool{}, "training-peer", &boundName, ReqAttack, payload)
+		msgs := conn.DrainMessages(t)
+		if len(msgs) == 0 {
+			t.Fatalf("expected a reply to ATTACK %#v", payload)
+		}
+		return msgs[len(msgs)-1]
+	}
+
+	withSimClock(func(clock *fakeClock) {
+		withFixedRandIntn(0, func() {
+			dummy := trainingTestDummy(Position{X: 16, Y: 0, Z: 15})
+			withWorldMob(World1, dummy.ID, dummy, func() {
+				cases := []struct {
+					payload map[string]interface{}
+					want    string
+				}{
+					{map[string]interface{}{"target": "rift_wolf", "target_level": 135}, "LEGACY_ATTACK_RETIRED"},
+					{map[string]interface{}{"target": "rift_wolf"}, "NOT_A_TRAINING_TARGET"},
+					{map[string]interface{}{"target": dummy.ID, "skill_id": "burst_arrow"}, "SKILL_NOT_INSTANT"},
+				}
+				c.Skills["burst_arrow"] = 1
+				for _, tc := range cases {
+					msg := attack(tc.payload)
+					if msg.Command != RespCombatRejected || msg.Payload != tc.want {
+						t.Fatalf("ATTACK %#v: expected %s, got %#v", tc.payload, tc.want, msg)
+					}
+				}
+
+				msg := attack(map[string]interface{}{"target": dummy.ID})
+				result := toMap(msg.Payload)
+				if msg.Command != RespCombatResult || result["training"] != true || toInt(result, "damage") <= 0 || toInt(result, "xp_gain") != 0 {
+					t.Fatalf("expected a practice hit, got %#v", msg)
+				}
+
+				// A killing blow leaves the dummy standing and still pays nothing.
+				dummy.HP = 1
+				msg = attack(map[string]interface{}{"target": dummy.ID})
+				if result := toMap(msg.Payload); result["defeated"] != false || dummy.HP != dummy.MaxHP {
+					t.Fatalf("expected the dummy to stay up, got %#v (HP %d)", result, dummy.HP)
+				}
+				if c.XP != xp || c.Level != level || len(dummy.threat) != 0 {
+					t.Fatalf("expected no XP or threat from practice, XP %d->%d threat %#v", xp, c.XP, dummy.threat)
+				}
+
+				// Dummies stand still and never swing back.
+				clock.Advance(5 * time.Second)
+				processServerTick()
+				if dummy.Position != dummy.Spawn || countMessages(conn.DrainMessages(t), RespMobHit) > 0 {
+					t.Fatalf("expected the dummy to stay passive")
+				}
+			})
+		})
+	})
+}
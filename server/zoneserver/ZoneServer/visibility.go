@@ -15,3 +15,83 @@ func isVisible(a, b Position) bool {
 	return distance(a, b) <= VisibilityRadius
 }
 
+// changeSessionWorld is the only supported way to move a session between
+// worlds. Every old-world viewer gets PLAYER_LEFT (and the session learns they
+// are gone), the session is re-homed in the world index that backs say-chat and
+// party proximity, ackCommand is sent with the new world, spawn and ackExtra
+// fields, and initial visibility is replayed at the destination.
+func changeSessionWorld(session *ClientSession, visible map[*ClientSession]bool, target *World, spawn Position, ackCommand string, ackExtra map[string]interface{}) {
+	oldWorld := session.World
+	leaveWorldViewers(session, visible)
+	moveSessionToWorld(session, target)
+	session.Character.WorldID = target.PersistentID()
+	session.Position = spawn
+	ack := map[string]interface{}{
+		"world": target.Name,
+		"spawn": session.Position,
+	}
+	for k, v := range ackExtra {
+		ack[k] = v
+	}
+	sendMessage(session.Conn, ServerMessage{Command: ackCommand, Payload: ack})
+	syncSessionRegion(session)
+	syncInitialVisibility(session, visible)
+	if oldWorld != nil && oldWorld.ID != target.ID {
+		clearPlayerThreat(oldWorld.ID, session.Character.Name)
+		releaseDungeonIfEmpty(oldWorld.ID)
+	}
+}
+
+// leaveOnDisconnect tells everyone who could see a disconnecting session
+// that it left and drops it from its world's threat tables, serialising with
+// any work still posted to its action path.
+func leaveOnDisconnect(session *ClientSession, visible map[*ClientSession]bool) {
+	if !session.Authenticated || session.Character == nil {
+		return
+	}
+	session.actionMu.Lock()
+	defer session.actionMu.Unlock()
+	leaveWorldViewers(session, visible)
+	if session.World != nil {
+		clearPlayerThreat(session.World.ID, session.Character.Name)
+	}
+}
+
+// forceSessionWorld moves a session out of world from on behalf of the server,
+// e.g. when a dungeon instance expires, serialising with the session's read
+// loop. It reports false if the session had already left from.
+func forceSessionWorld(session *ClientSession, from WorldID, target *World, spawn Position, ackCommand string, ackExtra map[string]interface{}) bool {
+	session.actionMu.Lock()
+	defer session.actionMu.Unlock()
+	if session.World == nil || session.World.ID != from {
+		return false
+	}
+	if session.visible == nil {
+		session.visible = make(map[*ClientSession]bool)
+	}
+	changeSessionWorld(session, session.visible, target, spawn, ackCommand, ackExtra)
+	return true
+}
+
+// leaveWorldViewers tells everyone who can see the session that it left, and
+// the session that they are gone. Visibility sets are per connection and not
+// symmetric: a player who arrived in range later holds the session in their
+// set but not the other way round, so every in-range session of the old
+// world is told as well as the session's own set.
+func leaveWorldViewers(session *ClientSession, visible map[*ClientSession]bool) {
+	left := func(other *ClientSession) {
+		sendMessage(other.Conn, ServerMessage{Command: RespPlayerLeft, Payload: session.Character.Name})
+		sendMessage(session.Conn, ServerMessage{Command: RespPlayerLeft, Payload: other.Character.Name})
+		delete(visible, other)
+	}
+	if session.World != nil {
+		forEachSessionInWorld(session.World.ID, func(other *ClientSession) {
+			if other != session && (visible[other] || isVisible(other.Position, session.Position)) {
+				left(other)
+			}
+		})
+	}
+	for other := range visible {
+		left(other)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import "testing"
+
+func newVisibilityTestSession(name string, world *World, pos Position) (*ClientSession, *captureConn) {
+	conn := &captureConn{}
+	c := MockCharacter()
+	c.Name = name
+	ensureCharacterDefaults(c)
+	s := &ClientSession{
+		Conn:          conn,
+		Character:     c,
+		World:         world,
+		Position:      pos,
+		Active:        true,
+		Authenticated: true,
+	}
+	registerSession(s)
+	bindSessionCharacterName(s, name)
+	return s, conn
+}
+
+func hasMessage(msgs []ServerMessage, command string, payload interface{}) bool {
+	for _, msg := range msgs {
+		if msg.Command == command && (payload == nil || msg.Payload == payload) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEnterWorldRetiresOldViewersAndMovesMembership(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	defer func() { worlds = DefaultWorlds() }()
+
+	traveler, travelerConn := newVisibilityTestSession("Traveler", worlds[World1], DefaultSpawnPosition(World1))
+	watcher, watcherConn := newVisibilityTestSession("Watcher", worlds[World1], DefaultSpawnPosition(World1))
+	local, localConn := newVisibilityTestSession("Local", worlds[World2], DefaultSpawnPosition(World2))
+	defer unregisterSession(traveler)
+	defer unregisterSession(watcher)
+	defer unregisterSession(local)
+
+	travelerVisible := map[*ClientSession]bool{}
+	syncInitialVisibility(traveler, travelerVisible)
+	if !travelerVisible[watcher] {
+		t.Fatalf("expected watcher visible before the transition")
+	}
+	travelerConn.DrainMessages(t)
+	watcherConn.DrainMessages(t)
+
+	traveler.Character.Level = 60
+	traveler.Character.UnlockedWorlds[World2] = true
+	bound := "Traveler"
+	handled, modified := handleClientCommand(travelerConn, traveler, travelerVisible, "visibility-peer", &bound, ReqEnterWorld, map[string]interface{}{"world_id": 2})
+	if !handled || !modified {
+		t.Fatalf("expected ENTER_WORLD handled+modified, got handled=%v modified=%v", handled, modified)
+	}
+
+	if !hasMessage(watcherConn.DrainMessages(t), RespPlayerLeft, "Traveler") {
+		t.Fatalf("expected old-world viewer to receive PLAYER_LEFT")
+	}
+	travelerMsgs := travelerConn.DrainMessages(t)
+	if !hasMessage(travelerMsgs, RespPlayerLeft, "Watcher") {
+		t.Fatalf("expected traveler to drop old-world viewer, got %#v", travelerMsgs)
+	}
+	if !hasMessage(travelerMsgs, RespEnterOK, nil) || !hasMessage(travelerMsgs, RespPlayerJoined, nil) {
+		t.Fatalf("expected ENTER_OK and new-world PLAYER_JOINED, got %#v", travelerMsgs)
+	}
+	if !hasMessage(localConn.DrainMessages(t), RespPlayerJoined, nil) {
+		t.Fatalf("expected new-world local to see traveler join")
+	}
+	if travelerVisible[watcher] || !travelerVisible[local] {
+		t.Fatalf("expected visibility set to track only new-world viewers, got %#v", travelerVisible)
+	}
+	if traveler.Character.WorldID != World2 || !sessionInWorld(traveler, World2) || sessionInWorld(traveler, World1) {
+		t.Fatalf("expected traveler indexed in world 2 only")
+	}
+
+	broadcastSay(watcher, "still here?")
+	if hasMessage(travelerConn.DrainMessages(t), RespChatMessage, nil) {
+		t.Fatalf("expected old-world say chat to stop reaching the traveler")
+	}
+	broadcastSay(local, "welcome")
+	if !hasMessage(travelerConn.DrainMessages(t), RespChatMessage, nil) {
+		t.Fatalf("expected new-world say chat to reach the traveler")
+	}
+}
+
+func TestEnterWorldNotifiesViewersWhoArrivedLater(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	defer func() { worlds = DefaultWorlds() }()
+
+	traveler, travelerConn := newVisibilityTestSession("EarlyBird", worlds[World1], DefaultSpawnPosition(World1))
+	defer unregisterSession(traveler)
+	travelerVisible := map[*ClientSession]bool{}
+	syncInitialVisibility(traveler, travelerVisible)
+
+	// The watcher arrives second, so only their set holds the traveler.
+	watcher, watcherConn := newVisibilityTestSession("LateComer", worlds[World1], DefaultSpawnPosition(World1))
+	defer unregisterSession(watcher)
+	watcherVisible := map[*ClientSession]bool{}
+	syncInitialVisibility(watcher, watcherVisible)
+	if !watcherVisible[traveler] || travelerVisible[watcher] {
+		t.Fatalf("expected only the watcher's set to hold the other, got traveler=%#v watcher=%#v", travelerVisible, watcherVisible)
+	}
+	travelerConn.DrainMessages(t)
+	watcherConn.DrainMessages(t)
+
+	traveler.Character.Level = 60
+	traveler.Character.UnlockedWorlds[World2] = true
+	bound := "EarlyBird"
+	if handled, _ := handleClientCommand(travelerConn, traveler, travelerVisible, "visibility-peer", &bound, ReqEnterWorld, map[string]interface{}{"world_id": 2}); !handled {
+		t.Fatalf("expected ENTER_WORLD to be handled")
+	}
+	if !hasMessage(watcherConn.DrainMessages(t), RespPlayerLeft, "EarlyBird") {
+		t.Fatalf("expected the later arrival to receive PLAYER_LEFT")
+	}
+	if !hasMessage(travelerConn.DrainMessages(t), RespPlayerLeft, "LateComer") {
+		t.Fatalf("expected the traveler to drop the later arrival")
+	}
+}
+
+func TestDisconnectNotifiesViewersWhoArrivedLater(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	leaver, _ := newVisibilityTestSession("Quitter", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(leaver)
+	leaverVisible := map[*ClientSession]bool{}
+	syncInitialVisibility(leaver, leaverVisible)
+
+	watcher, watcherConn := newVisibilityTestSession("Lingerer", worlds[World1], Position{X: 110, Y: 0, Z: 100})
+	defer unregisterSession(watcher)
+	syncInitialVisibility(watcher, map[*ClientSession]bool{})
+	watcherConn.DrainMessages(t)
+
+	withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 100, Y: 0, Z: 100}), func() {
+		mob := worldMobsForTests(World1)["mob_wolf_01"]
+		mob.threat = map[string]int{"Quitter": 10, "Lingerer": 5}
+
+		leaveOnDisconnect(leaver, leaverVisible)
+		if !hasMessage(watcherConn.DrainMessages(t), RespPlayerLeft, "Quitter") {
+			t.Fatalf("expected the later arrival to receive PLAYER_LEFT on disconnect")
+		}
+		if _, ok := mob.threat["Quitter"]; ok || mob.threat["Lingerer"] != 5 {
+			t.Fatalf("expected only the leaver's threat dropped, got %#v", mob.threat)
+		}
+	})
+}
+
+func TestPartyNearbyMembersFollowsWorldTransitions(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	leader, _ := newVisibilityTestSession("Lead", worlds[World1], DefaultSpawnPosition(World1))
+	member, memberConn := newVisibilityTestSession("Mate", worlds[World1], DefaultSpawnPosition(World1))
+	defer unregisterSession(leader)
+	defer unregisterSession(member)
+
+	if _, ok, reason := partyInvite("Lead", "Mate"); !ok {
+		t.Fatalf("party invite failed: %s", reason)
+	}
+	if _, ok, reason := partyAccept("Mate", "Lead"); !ok {
+		t.Fatalf("party accept failed: %s", reason)
+	}
+	if got := len(partyNearbyMembers(leader)); got != 1 {
+		t.Fatalf("expected one nearby party member, got %d", got)
+	}
+
+	changeSessionWorld(member, map[*ClientSession]bool{}, worlds[World2], DefaultSpawnPosition(World2), RespTeleportOK, nil)
+	memberConn.DrainMessages(t)
+	if got := len(partyNearbyMembers(leader)); got != 0 {
+		t.Fatalf("expected party proximity to drop member in another world, got %d", got)
+	}
+}
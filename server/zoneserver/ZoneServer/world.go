@@ -15,6 +15,18 @@ type World struct {
 	MaxLevel     int
 	Unlocked     bool
 	RequiresAura bool
+	// InstanceOf is the persistent world hosting this private instance, or
+	// zero for the shared worlds in DefaultWorlds.
+	InstanceOf WorldID
+}
+
+// PersistentID is the world a character should be saved in while standing in
+// w; instances resolve to their host world so reconnects never target them.
+func (w *World) PersistentID() WorldID {
+	if w.InstanceOf != 0 {
+		return w.InstanceOf
+	}
+	return w.ID
 }
 
 func DefaultWorlds() map[WorldID]*World {
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Scheduled event kinds for world bosses; Subject is the boss ID.
+const (
+	eventBossSpawn   = "boss_spawn"
+	eventBossDespawn = "boss_despawn"
+)
+
+const worldBossesFile = "world_bosses.json"
+
+// WorldBoss is a boss that spawns at Position on a cron schedule, changes
+// behaviour at HP thresholds, and shares loot by damage contribution.
+type WorldBoss struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Template string   `json:"template"` // base combat profile and abilities
+	WorldID  WorldID  `json:"world_id"`
+	Position Position `json:"position"`
+	Level    int      `json:"level"`
+	HP       int      `json:"hp"`
+
+	// Schedule is a five-field cron expression in UTC; DespawnAfterSec
+	// removes a boss nobody killed.
+	Schedule        string `json:"schedule"`
+	DespawnAfterSec int    `json:"despawn_after_sec"`
+
+	LootTable          string         `json:"loot_table"`
+	MinContributionPct int            `json:"min_contribution_pct"`
+	LootTiers          []BossLootTier `json:"loot_tiers"`
+	Phases             []BossPhase    `json:"phases"`
+
+	cron *cronSchedule
+}
+
+// BossPhase takes over once the boss drops to HPPct of its max HP. Non-zero
+// fields replace the current combat profile; Abilities, when set, replace
+// the ability list.
+type BossPhase struct {
+	HPPct            int          `json:"hp_pct"`
+	Name             string       `json:"name"`
+	AttackIntervalMS int          `json:"attack_interval_ms"`
+	DamageMin        int          `json:"damage_min"`
+	DamageMax        int          `json:"damage_max"`
+	Abilities        []MobAbility `json:"abilities"`
+}
+
+// BossLootTier grants Rolls on the boss loot table to participants whose
+// contribution share is at least MinPct. The first matching tier applies.
+type BossLootTier struct {
+	MinPct int `json:"min_pct"`
+	Rolls  int `json:"rolls"`
+}
+
+// BossStatus is the phase info LIST_ENTITIES reports for a live boss.
+type BossStatus struct {
+	BossID       string `json:"boss_id"`
+	Phase        int    `json:"phase"`
+	PhaseName    string `json:"phase_name"`
+	Phases       int    `json:"phases"`
+	NextPhasePct int    `json:"next_phase_hp_pct"`
+	Participants int    `json:"participants"`
+}
+
+// bossState is the per-spawn state of a boss mob.
+type bossState struct {
+	def    *WorldBoss
+	phase  int            // 0 before the first threshold, then 1..len(Phases)
+	damage map[string]int // character name -> damage dealt this spawn
+}
+
+func validateWorldBosses(bosses []WorldBoss, data *SpawnData) []string {
+	problems := make([]string, 0)
+	known := DefaultWorlds()
+	for i := range bosses {
+		b := &bosses[i]
+		if b.ID == "" {
+			problems = append(problems, "world boss without id")
+			continue
+		}
+		if _, dup := data.Bosses[b.ID]; dup {
+			problems = append(problems, fmt.Sprintf("duplicate world boss %q", b.ID))
+		}
+		if _, ok := data.Templates[b.Template]; !ok {
+			problems = append(problems, fmt.Sprintf("world boss %q references unknown mob template %q", b.ID, b.Template))
+		}
+		if _, ok := known[b.WorldID]; !ok {
+			problems = append(problems, fmt.Sprintf("world boss %q references unknown world %d", b.ID, b.WorldID))
+		}
+		if b.Level <= 0 || b.HP <= 0 {
+			problems = append(problems, fmt.Sprintf("world boss %q needs positive level and hp", b.ID))
+		}
+		cron, err := parseCron(b.Schedule)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("world boss %q: %v", b.ID, err))
+		}
+		b.cron = cron
+		if _, ok := lootTables[b.LootTable]; !ok {
+			problems = append(problems, fmt.Sprintf("world boss %q references unknown loot table %q", b.ID, b.LootTable))
+		}
+		if b.MinContributionPct < 0 || b.MinContributionPct > 100 {
+			problems = append(problems, fmt.Sprintf("world boss %q needs min_contribution_pct in 0..100", b.ID))
+		}
+		if len(b.LootTiers) == 0 {
+			problems = append(problems, fmt.Sprintf("world boss %q needs at least one loot tier", b.ID))
+		}
+		for j, tier := range b.LootTiers {
+			if tier.Rolls <= 0 || (j > 0 && tier.MinPct >= b.LootTiers[j-1].MinPct) {
+				problems = append(problems, fmt.Sprintf("world boss %q loot tiers need positive rolls and descending min_pct", b.ID))
+				break
+			}
+		}
+		last := 100
+		for _, p := range b.Phases {
+			if p.HPPct <= 0 || p.HPPct >= last {
+				problems = append(problems, fmt.Sprintf("world boss %q phases need descending hp_pct below 100", b.ID))
+				break
+			}
+			last = p.HPPct
+		}
+		for _, p := range b.Phases {
+			problems = append(problems, validateMobAbilities(MobTemplate{ID: b.ID + "/" + p.Name, Abilities: p.Abilities})...)
+		}
+		data.Bosses[b.ID] = b
+	}
+	return problems
+}
+
+func bossMobID(bossID string) string {
+	return "boss_" + bossID
+}
+
+func bossSpawnKey(bossID string) string {
+	return eventBossSpawn + ":" + bossID
+}
+
+func bossDespawnKey(bossID string) string {
+	return eventBossDespawn + ":" + bossID
+}
+
+// scheduleWorldBosses queues the next spawn of every boss in a world this
+// node owns.
+func scheduleWorldBosses() {
+	for _, def := range activeSpawnData().Bosses {
+		if ownsWorld(def.WorldID) {
+			scheduleNextBossSpawn(def)
+		}
+	}
+}
+
+func scheduleNextBossSpawn(def *WorldBoss) {
+	next := def.cron.Next(worldScheduler.clock.Now())
+	if next.IsZero() {
+		log.Printf("world boss %s: schedule %q never fires", def.ID, def.Schedule)
+		return
+	}
+	worldScheduler.ScheduleAt(ScheduledEvent{
+		Key:     bossSpawnKey(def.ID),
+		Kind:    eventBossSpawn,
+		WorldID: def.WorldID,
+		Subject: def.ID,
+	}, next)
+}
+
+// spawnWorldBoss is the eventBossSpawn handler. It spawns the boss unless it
+// is already up, announces it to the whole world, and queues the next spawn
+// and this spawn's despawn.
+func spawnWorldBoss(evt ScheduledEvent) {
+	def := activeSpawnData().Bosses[evt.Subject]
+	if def == nil {
+		return
+	}
+	scheduleNextBossSpawn(def)
+	w := worldSimFor(def.WorldID)
+	if w == nil {
+		return
+	}
+	spawned := false
+	w.do(func(w *worldSim, outbox *mobOutbox) {
+		if mob, ok := w.mobs[bossMobID(def.ID)]; ok && mob.HP > 0 {
+			return
+		}
+		mob := newBossMob(def)
+		w.mobs[mob.ID] = mob
+		spawned = true
+		outbox.event(mob, RespMobSpawned, mobSnapshotPayload(mob), nil)
+		outbox.announce(def.WorldID, ServerMessage{Command: RespWorldBossSpawned, Payload: map[string]interface{}{
+			"boss_id":           def.ID,
+			"mob_id":            mob.ID,
+			"name":              mob.Name,
+			"level":             mob.Level,
+			"pos":               mob.Position,
+			"despawn_after_sec": def.DespawnAfterSec,
+		}})
+	})
+	if spawned && def.DespawnAfterSec > 0 {
+		worldScheduler.Schedule(ScheduledEvent{
+			Key:     bossDespawnKey(def.ID),
+			Kind:    eventBossDespawn,
+			WorldID: def.WorldID,
+			Subject: def.ID,
+		}, time.Duration(def.DespawnAfterSec)*time.Second)
+	}
+}
+
+// despawnWorldBoss is the eventBossDespawn handler for a boss nobody killed
+// in time.
+func despawnWorldBoss(evt ScheduledEvent) {
+	w := worldSimFor(evt.WorldID)
+	if w == nil {
+		return
+	}
+	w.do(func(w *worldSim, outbox *mobOutbox) {
+		mob, ok := w.mobs[bossMobID(evt.Subject)]
+		if !ok || mob.HP <= 0 {
+			return
+		}
+		delete(w.mobs, mob.ID)
+		outbox.announce(evt.WorldID, ServerMessage{Command: RespWorldBossDespawned, Payload: map[string]interface{}{
+			"boss_id": evt.Subject,
+			"mob_id":  mob.ID,
+			"name":    mob.Name,
+		}})
+	})
+}
+
+func newBossMob(def *WorldBoss) *MobEntity {
+	tmpl := activeSpawnData().Templates[def.Template]
+	name := def.Name
+	if name == "" {
+		name = tmpl.Name
+	}
+	mob := &MobEntity{
+		ID:               bossMobID(def.ID),
+		Name:             name,
+		Template:         tmpl.ID,
+		LootTable:        def.LootTable,
+		WorldID:          def.WorldID,
+		Level:            def.Level,
+		HP:               def.HP,
+		MaxHP:            def.HP,
+		Position:         def.Position,
+		Spawn:            def.Position,
+		AIState:          MobStateIdle,
+		AttackIntervalMS: tmpl.AttackIntervalMS,
+		DamageMin:        tmpl.DamageMin,
+		DamageMax:        tmpl.DamageMax,
+		Abilities:        tmpl.Abilities,
+		Defense:          tmpl.Defense,
+		Element:          tmpl.elementOrNone(),
+		Resistances:      tmpl.Resistances,
+		boss:             &bossState{def: def, damage: map[string]int{}},
+	}
+	if grid := terrainFor(def.WorldID); grid != nil {
+		mob.Position.Y = grid.groundY(mob.Position)
+		mob.Spawn.Y = mob.Position.Y
+	}
+	mob.lastSentPos = mob.Position
+	return mob
+}
+
+// recordBossDamageLocked credits attacker with damage against a boss and
+// moves the boss into any phase whose threshold it has now crossed.
+func recordBossDamageLocked(mob *MobEntity, attacker string, damage int, outbox *mobOutbox) {
+	b := mob.boss
+	b.damage[attacker] += damage
+	if mob.HP <= 0 {
+		return
+	}
+	for b.phase < len(b.def.Phases) && mob.HP*100 <= mob.MaxHP*b.def.Phases[b.phase].HPPct {
+		p := b.def.Phases[b.phase]
+		b.phase++
+		if p.AttackIntervalMS > 0 {
+			mob.AttackIntervalMS = p.AttackIntervalMS
+		}
+		if p.DamageMin > 0 {
+			mob.DamageMin, mob.DamageMax = p.DamageMin, p.DamageMax
+		}
+		if p.Abilities != nil {
+			mob.Abilities = p.Abilities
+			mob.abilityReadyAt = nil
+		}
+		outbox.event(mob, RespWorldBossPhase, map[string]interface{}{
+			"boss_id":    b.def.ID,
+			"mob_id":     mob.ID,
+			"phase":      b.phase,
+			"phase_name": p.Name,
+			"hp":         mob.HP,
+			"max_hp":     mob.MaxHP,
+		}, nil)
+	}
+}
+
+// resetBossLocked restores a leashed boss to its opening phase and forgets
+// who fought it.
+func resetBossLocked(mob *MobEntity) {
+	b := mob.boss
+	tmpl := activeSpawnData().Templates[b.def.Template]
+	b.phase = 0
+	b.damage = map[string]int{}
+	mob.AttackIntervalMS, mob.DamageMin, mob.DamageMax = tmpl.AttackIntervalMS, tmpl.DamageMin, tmpl.DamageMax
+	mob.Abilities = tmpl.Abilities
+	mob.abilityReadyAt = nil
+}
+
+func (b *bossState) status() *BossStatus {
+	st := &BossStatus{
+		BossID:       b.def.ID,
+		Phase:        b.phase,
+		PhaseName:    "Opening",
+		Phases:       len(b.def.Phases) + 1,
+		Participants: len(b.damage),
+	}
+	if b.phase > 0 {
+		st.PhaseName = b.def.Phases[b.phase-1].Name
+	}
+	if b.phase < len(b.def.Phases) {
+		st.NextPhasePct = b.def.Phases[b.phase].HPPct
+	}
+	return st
+}
+
+// bossContribution is one participant's share of a boss kill.
+type bossContribution struct {
+	session  *ClientSession
+	name     string
+	damage   int
+	sharePct int // own damage, or the party's pooled damage, over the total
+	rank     int
+}
+
+// bossContributionsLocked ranks everyone owed a share of a boss kill. Damage
+// is pooled per party, and party members near a contributor (see
+// partyNearbyMembers) share their party's pool even if they dealt none, so
+// tanks and healers are not left out. Only players still in the world count.
+func bossContributionsLocked(mob *MobEntity) []bossContribution {
+	b := mob.boss
+	total := 0
+	pools := map[string]int{}
+	poolOf := func(name string) string {
+		if partyID, _, _, ok := partyRosterForMember(name); ok {
+			return "party:" + partyID
+		}
+		return "solo:" + name
+	}
+	for name, dmg := range b.damage {
+		total += dmg
+		pools[poolOf(name)] += dmg
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	byName := map[string]*bossContribution{}
+	add := func(s *ClientSession) {
+		if s == nil || s.Character == nil || !sessionInWorld(s, mob.WorldID) {
+			return
+		}
+		name := s.Character.Name
+		if byName[name] != nil {
+			return
+		}
+		byName[name] = &bossContribution{
+			session:  s,
+			name:     name,
+			damage:   b.damage[name],
+			sharePct: pools[poolOf(name)] * 100 / total,
+		}
+	}
+	for name := range b.damage {
+		s := findSessionByCharacterName(name)
+		add(s)
+		for _, member := range partyNearbyMembers(s) {
+			add(member)
+		}
+	}
+
+	out := make([]bossContribution, 0, len(byName))
+	for _, c := range byName {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].damage != out[j].damage {
+			return out[i].damage > out[j].damage
+		}
+		return out[i].name < out[j].name
+	})
+	for i := range out {
+		out[i].rank = i + 1
+	}
+	return out
+}
+
+// awardWorldBossLocked pays out a boss kill by contribution and announces
+// it. Each participant's loot and XP land on their own action path, after
+// which everyone but killer is sent WORLD_BOSS_LOOT; the killer's award is
+// returned for MOB_ATTACK_RESULT and filled in on theirs.
+func awardWorldBossLocked(w *worldSim, mob *MobEntity, killer *ClientSession, outbox *mobOutbox) map[string]interface{} {
+	def := mob.boss.def
+	contributions := bossContributionsLocked(mob)
+	xpGain := 35 + mob.Level*4
+
+	var killerAward map[string]interface{}
+	top := make([]map[string]interface{}, 0, 3)
+	for _, c := range contributions {
+		if len(top) < 3 {
+			top = append(top, map[string]interface{}{"name": c.name, "damage": c.damage, "share_pct": c.sharePct})
+		}
+		rolls := 0
+		if c.sharePct >= def.MinContributionPct {
+			for _, tier := range def.LootTiers {
+				if c.sharePct >= tier.MinPct {
+					rolls = tier.Rolls
+					break
+				}
+			}
+		}
+		award := map[string]interface{}{
+			"boss_id":   def.ID,
+			"mob_id":    mob.ID,
+			"damage":    c.damage,
+			"share_pct": c.sharePct,
+			"rank":      c.rank,
+			"rolls":     rolls,
+			"xp_gain":   0,
+			"drops":     []map[string]interface{}{},
+		}
+		if rolls > 0 {
+			award["xp_gain"] = xpGain
+		}
+		s, partied := c.session, len(partyNearbyMembers(c.session)) > 0
+		outbox.post(s, func() {
+			if rolls > 0 {
+				drops := make([]map[string]interface{}, 0)
+				for i := 0; i < rolls; i++ {
+					drops = append(drops, rollLootForMob(s.Character, def.LootTable)...)
+				}
+				if partied {
+					drops = applyPartyLootBonus(s.Character, drops)
+				}
+				award["leveled_up"] = gainXP(s.Character, xpGain)
+				award["drops"] = drops
+			}
+			if s == killer {
+				return // returned for MOB_ATTACK_RESULT
+			}
+			sendMessage(s.Conn, ServerMessage{Command: RespWorldBossLoot, Payload: award})
+			if rolls > 0 {
+				queuePersistCharacter(s.Character, "world boss loot")
+			}
+		})
+		if s == killer {
+			killerAward = award
+		}
+	}
+
+	outbox.announce(mob.WorldID, ServerMessage{Command: RespWorldBossDefeated, Payload: map[string]interface{}{
+		"boss_id":      def.ID,
+		"mob_id":       mob.ID,
+		"name":         mob.Name,
+		"killer":       killer.Character.Name,
+		"participants": len(contributions),
+		"top":          top,
+	}})
+	worldScheduler.Cancel(bossDespawnKey(def.ID))
+	delete(w.mobs, mob.ID)
+	return killerAward
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// withEmptyWorld gives worldID a simulation with no mobs.
+func withEmptyWorld(worldID WorldID, fn func(w *worldSim)) {
+	withWorldMob(worldID, "unused", nil, func() {
+		w := worldSimFor(worldID)
+		w.mobs = map[string]*MobEntity{}
+		fn(w)
+	})
+}
+
+func TestWorldBossSpawnsOnSchedulePhasesAndDespawns(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	def := activeSpawnData().Bosses["rift_alpha"]
+	near, nearConn := newVisibilityTestSession("Scout", worlds[World1], Position{X: 140, Y: 0, Z: 150})
+	defer unregisterSession(near)
+	far, farConn := newVisibilityTestSession("Faraway", worlds[World1], Position{X: -80, Y: 0, Z: -80})
+	defer unregisterSession(far)
+
+	withFakeScheduler(func(clock *fakeClock) { // 2026-01-01 00:00 UTC
+		withEmptyWorld(World1, func(w *worldSim) {
+			scheduleWorldBosses()
+			runScheduledEvents()
+			if _, up := w.mobs[bossMobID(def.ID)]; up {
+				t.Fatalf("expected no boss before its schedule")
+			}
+
+			clock.Advance(2 * time.Hour)
+			runScheduledEvents()
+			mob := w.mobs[bossMobID(def.ID)]
+			if mob == nil || mob.HP != def.HP || mob.Level != def.Level {
+				t.Fatalf("expected the boss to spawn at 02:00, got %#v", mob)
+			}
+			if !hasMessage(farConn.DrainMessages(t), RespWorldBossSpawned, nil) {
+				t.Fatalf("expected the spawn to be announced world-wide")
+			}
+			keys := map[string]time.Time{}
+			for _, evt := range worldScheduler.Pending() {
+				keys[evt.Key] = evt.DueAt
+			}
+			if want := clock.Now().Add(2 * time.Hour); !keys[bossSpawnKey(def.ID)].Equal(want) {
+				t.Fatalf("expected the next spawn at %v, got %v", want, keys[bossSpawnKey(def.ID)])
+			}
+			if _, ok := keys[bossDespawnKey(def.ID)]; !ok {
+				t.Fatalf("expected a despawn to be queued, got %v", keys)
+			}
+
+			mobs := listNearbyEntities(near)["mobs"].([]MobEntity)
+			if len(mobs) != 1 || mobs[0].Boss == nil || mobs[0].Boss.Phase != 0 || mobs[0].Boss.NextPhasePct != 60 {
+				t.Fatalf("expected LIST_ENTITIES to show the boss in its opening phase, got %#v", mobs)
+			}
+
+			mob.HP = mob.MaxHP*60/100 + 1
+			nearConn.DrainMessages(t)
+			if _, ok, reason := attackMob(near, mob.ID, ""); !ok {
+				t.Fatalf("attack failed: %s", reason)
+			}
+			if mob.boss.phase != 1 || mob.AttackIntervalMS != 1000 {
+				t.Fatalf("expected the boss to enter Blood Frenzy, got phase %d interval %d", mob.boss.phase, mob.AttackIntervalMS)
+			}
+			mobs = listNearbyEntities(near)["mobs"].([]MobEntity)
+			if mobs[0].Boss.PhaseName != "Blood Frenzy" || mobs[0].Boss.Participants != 1 {
+				t.Fatalf("expected phase info in LIST_ENTITIES, got %#v", mobs[0].Boss)
+			}
+
+			// Leashing resets the fight.
+			startMobReturn(mob, &mobOutbox{})
+			if mob.boss.phase != 0 || len(mob.boss.damage) != 0 || mob.AttackIntervalMS != 1500 {
+				t.Fatalf("expected a leashed boss to reset, got phase %d", mob.boss.phase)
+			}
+
+			clock.Advance(time.Duration(def.DespawnAfterSec) * time.Second)
+			runScheduledEvents()
+			if _, up := w.mobs[bossMobID(def.ID)]; up {
+				t.Fatalf("expected the unkilled boss to despawn")
+			}
+			if !hasMessage(farConn.DrainMessages(t), RespWorldBossDespawned, nil) {
+				t.Fatalf("expected the despawn to be announced")
+			}
+		})
+	})
+}
+
+func TestWorldBossLootFollowsContributionAndParties(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+
+	t.Setenv("A3_PERSISTENCE_MODE", "json")
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir temp dir failed: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldWD) }()
+	resetPersistenceRuntimeStateForTests()
+	defer resetPersistenceRuntimeStateForTests()
+
+	def := activeSpawnData().Bosses["rift_alpha"]
+	at := def.Position
+	solo, soloConn := newVisibilityTestSession("Solo", worlds[World1], at)
+	dps, dpsConn := newVisibilityTestSession("PartyDps", worlds[World1], at)
+	healer, healerConn := newVisibilityTestSession("PartyHeal", worlds[World1], at)
+	leech, leechConn := newVisibilityTestSession("Leech", worlds[World1], at)
+	for _, s := range []*ClientSession{solo, dps, healer, leech} {
+		defer unregisterSession(s)
+	}
+	if _, ok, reason := partyInvite("PartyDps", "PartyHeal"); !ok {
+		t.Fatalf("party invite failed: %s", reason)
+	}
+	if _, ok, reason := partyAccept("PartyHeal", "PartyDps"); !ok {
+		t.Fatalf("party accept failed: %s", reason)
+	}
+
+	withFakeScheduler(func(*fakeClock) {
+		withEmptyWorld(World1, func(w *worldSim) {
+			spawnWorldBoss(ScheduledEvent{Kind: eventBossSpawn, WorldID: World1, Subject: def.ID})
+			mob := w.mobs[bossMobID(def.ID)]
+			mob.boss.damage = map[string]int{"Solo": 5000, "PartyDps": 1500, "Leech": 100}
+			mob.HP = 1
+			for _, conn := range []*captureConn{soloConn, dpsConn, healerConn, leechConn} {
+				conn.DrainMessages(t)
+			}
+
+			// A participant busy with a command gets their loot once it ends,
+			// on their own action path.
+			dps.serving.Store(true)
+			dps.actionMu.Lock()
+			xp := dps.Character.XP
+			result, ok, reason := attackMob(solo, mob.ID, "")
+			if !ok || result["defeated"] != true {
+				dps.actionMu.Unlock()
+				t.Fatalf("expected the killing blow to land, got ok=%v reason=%s %#v", ok, reason, result)
+			}
+			if dps.Character.XP != xp {
+				dps.actionMu.Unlock()
+				t.Fatalf("expected no boss XP while the participant's command runs")
+			}
+			dps.actionMu.Unlock()
+			waitForPosted(t, dps)
+			if dps.Character.XP == xp {
+				t.Fatalf("expected boss XP once the participant's command ended")
+			}
+			award := result["boss"].(map[string]interface{})
+			if award["rank"] != 1 || award["rolls"] != 3 || award["xp_gain"] == 0 {
+				t.Fatalf("expected the top contributor to get the top tier, got %#v", award)
+			}
+			if _, up := w.mobs[mob.ID]; up {
+				t.Fatalf("expected the defeated boss to be removed")
+			}
+			for _, evt := range worldScheduler.Pending() {
+				if evt.Key == bossDespawnKey(def.ID) {
+					t.Fatalf("expected the despawn to be cancelled")
+				}
+			}
+
+			for name, tc := range map[string]struct {
+				conn  *captureConn
+				rolls int
+			}{
+				"PartyDps":  {dpsConn, 2},    // party pool 1500/6600+ = 22%
+				"PartyHeal": {healerConn, 2}, // no damage, shares the party pool
+				"Leech":     {leechConn, 0},  // under min_contribution_pct
+			} {
+				msgs := tc.conn.DrainMessages(t)
+				loot := toMap(lastMessage(msgs, RespWorldBossLoot).Payload)
+				if loot == nil || toInt(loot, "rolls") != tc.rolls {
+					t.Fatalf("%s: expected %d loot rolls, got %#v", name, tc.rolls, loot)
+				}
+				if !hasMessage(msgs, RespWorldBossDefeated, nil) {
+					t.Fatalf("%s: expected the kill to be announced", name)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// worldSim owns one world's mobs. Once its loop is started, player actions
+// arrive as messages on inbox and run on the loop goroutine between ticks;
+// before that (at startup and in tests) do runs them inline. Either way mu
+// serialises access to mobs, and broadcasts collected in the mobOutbox are
+// published only after mu is released.
+type worldSim struct {
+	ID   WorldID
+	mu   sync.Mutex
+	mobs map[string]*MobEntity
+
+	inbox   chan worldRequest
+	quit    chan struct{}
+	stopped chan struct{} // closed when the loop exits; nil if never started
+
+	// tickRate is how often the loop ticks; now is the sim clock reading at
+	// the start of the current tick. Mob speeds and cooldowns are scaled by
+	// these so changing tick_rate_ms does not change mob speed or DPS.
+	tickRate time.Duration
+	now      time.Time
+
+	// Path search accounting for the current tick; see moveMobTo.
+	pathBudget    int
+	pathSearches  int
+	pathsDeferred int
+
+	// casts are cast-time skills waiting to land; see startSkillCast.
+	casts map[*ClientSession]*skillCast
+	// targets are selected mob IDs and autoAttacks the running auto-attack
+	// loops; see auto_attack.go.
+	targets     map[*ClientSession]string
+	autoAttacks map[*ClientSession]*autoAttack
+
+	metricsMu sync.Mutex
+	metrics   WorldTickMetrics
+}
+
+type worldRequest struct {
+	fn     func(w *worldSim, outbox *mobOutbox)
+	outbox *mobOutbox
+	done   chan struct{}
+}
+
+// WorldTickMetrics is reported per world by /admin/worlds. A tick overruns
+// when it takes longer than the configured tick rate.
+type WorldTickMetrics struct {
+	WorldID    WorldID `json:"world_id"`
+	Mobs       int     `json:"mobs"`
+	Ticks      uint64  `json:"ticks"`
+	Overruns   uint64  `json:"overruns"`
+	LastTickMS float64 `json:"last_tick_ms"`
+	MaxTickMS  float64 `json:"max_tick_ms"`
+	AvgTickMS  float64 `json:"avg_tick_ms"`
+	InboxDepth int     `json:"inbox_depth"`
+
+	PathSearches  uint64 `json:"path_searches"`
+	PathsDeferred uint64 `json:"paths_deferred"`
+
+	total time.Duration
+}
+
+const worldInboxSize = 64
+
+var (
+	simsMu       sync.RWMutex
+	worldSims    map[WorldID]*worldSim
+	simsStarted  bool
+	simTickRate  = time.Second
+	simTickClock = time.Now
+)
+
+func newWorldSim(id WorldID, mobs map[string]*MobEntity) *worldSim {
+	if mobs == nil {
+		mobs = map[string]*MobEntity{}
+	}
+	return &worldSim{
+		ID:       id,
+		mobs:     mobs,
+		inbox:    make(chan worldRequest, worldInboxSize),
+		quit:     make(chan struct{}),
+		tickRate: simTickRate,
+		metrics:  WorldTickMetrics{WorldID: id},
+	}
+}
+
+func worldSimFor(id WorldID) *worldSim {
+	simsMu.RLock()
+	defer simsMu.RUnlock()
+	return worldSims[id]
+}
+
+func allWorldSims() []*worldSim {
+	simsMu.RLock()
+	defer simsMu.RUnlock()
+	out := make([]*worldSim, 0, len(worldSims))
+	for _, w := range worldSims {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// addWorldSim registers a simulation for id, starting its loop if the world
+// loops are already running.
+func addWorldSim(id WorldID, mobs map[string]*MobEntity) *worldSim {
+	w := newWorldSim(id, mobs)
+	simsMu.Lock()
+	defer simsMu.Unlock()
+	if worldSims == nil {
+		worldSims = map[WorldID]*worldSim{}
+	}
+	if old := worldSims[id]; old != nil {
+		old.stop()
+	}
+	worldSims[id] = w
+	if simsStarted {
+		w.start(simTickRate)
+	}
+	return w
+}
+
+func removeWorldSim(id WorldID) {
+	simsMu.Lock()
+	w := worldSims[id]
+	delete(worldSims, id)
+	simsMu.Unlock()
+	if w != nil {
+		w.stop()
+	}
+}
+
+// startWorldSims gives every world its own loop ticking at tickRate.
+func startWorldSims(tickRate time.Duration) {
+	simsMu.Lock()
+	defer simsMu.Unlock()
+	simTickRate = tickRate
+	simsStarted = true
+	for _, w := range worldSims {
+		w.start(tickRate)
+	}
+}
+
+func stopWorldSims() {
+	simsMu.Lock()
+	simsStarted = false
+	running := make([]*worldSim, 0, len(worldSims))
+	for _, w := range worldSims {
+		running = append(running, w)
+	}
+	simsMu.Unlock()
+	for _, w := range running {
+		w.stop()
+	}
+}
+
+func (w *worldSim) start(tickRate time.Duration) {
+	if w.stopped != nil {
+		return
+	}
+	w.stopped = make(chan struct{})
+	w.tickRate = tickRate
+	go w.loop(tickRate)
+}
+
+func (w *worldSim) stop() {
+	select {
+	case <-w.quit:
+	default:
+		close(w.quit)
+	}
+	if w.stopped != nil {
+		<-w.stopped
+	}
+}
+
+func (w *worldSim) loop(tickRate time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(tickRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case req := <-w.inbox:
+			w.mu.Lock()
+			req.fn(w, req.outbox)
+			w.mu.Unlock()
+			close(req.done)
+		case <-ticker.C:
+			w.tick()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// do runs fn against the world's mobs and then publishes what it queued on
+// the outbox from the caller's goroutine. It reports false if the world was
+// shut down before fn could run. Must not be called from the world's own loop.
+func (w *worldSim) do(fn func(w *worldSim, outbox *mobOutbox)) bool {
+	return w.doFor(nil, fn)
+}
+
+// doFor is do called from owner's own action path, such as their command
+// handler: character work fn posts for owner runs inline before doFor
+// returns, so the caller sees its results.
+func (w *worldSim) doFor(owner *ClientSession, fn func(w *worldSim, outbox *mobOutbox)) bool {
+	outbox := mobOutbox{owner: owner}
+	defer outbox.flush()
+
+	if w.stopped != nil {
+		req := worldRequest{fn: fn, outbox: &outbox, done: make(chan struct{})}
+		select {
+		case w.inbox <- req:
+		case <-w.stopped:
+			return false
+		}
+		select {
+		case <-req.done:
+			return true
+		case <-w.stopped:
+			return false
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(w, &outbox)
+	return true
+}
+
+// tick advances every live mob's AI once and records how long it took.
+func (w *worldSim) tick() {
+	present := make([]*ClientSession, 0)
+	candidates := make([]*ClientSession, 0)
+	forEachSessionInWorld(w.ID, func(s *ClientSession) {
+		regenSkillResource(s, w.tickRate)
+		present = append(present, s)
+		if mobCanTarget(s, w.ID) {
+			candidates = append(candidates, s)
+		}
+	})
+
+	start := simTickClock()
+	var outbox mobOutbox
+	w.mu.Lock()
+	w.now = start
+	w.pathBudget = mobPathBudgetPerTick
+	w.pathSearches, w.pathsDeferred = 0, 0
+	for _, s := range present {
+		tickSessionEffectsLocked(w, s, &outbox)
+	}
+	for _, mob := range w.mobs {
+		if mob.HP <= 0 {
+			continue // Dead mobs don't move
+		}
+		tickMobEffectsLocked(mob, w.now, &outbox)
+		if mob.Training || mobStunned(mob, w.now) {
+			continue
+		}
+		stepMobAI(w, mob, candidates, &outbox)
+		outbox.moved(mob)
+	}
+	resolveSkillCastsLocked(w, &outbox)
+	resolveAutoAttacksLocked(w, &outbox)
+	mobCount, searches, deferred := len(w.mobs), w.pathSearches, w.pathsDeferred
+	w.mu.Unlock()
+	outbox.flush()
+	w.recordTick(simTickClock().Sub(start), mobCount)
+	w.recordPathing(searches, deferred)
+}
+
+// perTick converts a rate in units per second into the amount covered by
+// one tick.
+func (w *worldSim) perTick(perSecond float64) float64 {
+	return perSecond * w.tickRate.Seconds()
+}
+
+func (w *worldSim) recordPathing(searches, deferred int) {
+	w.metricsMu.Lock()
+	defer w.metricsMu.Unlock()
+	w.metrics.PathSearches += uint64(searches)
+	w.metrics.PathsDeferred += uint64(deferred)
+}
+
+func (w *worldSim) recordTick(d time.Duration, mobCount int) {
+	w.metricsMu.Lock()
+	defer w.metricsMu.Unlock()
+	m := &w.metrics
+	m.Ticks++
+	m.total += d
+	m.Mobs = mobCount
+	m.LastTickMS = durationMS(d)
+	if ms := durationMS(d); ms > m.MaxTickMS {
+		m.MaxTickMS = ms
+	}
+	m.AvgTickMS = durationMS(m.total / time.Duration(m.Ticks))
+	if d > w.tickRate {
+		m.Overruns++
+	}
+}
+
+func (w *worldSim) tickMetrics() WorldTickMetrics {
+	w.metricsMu.Lock()
+	defer w.metricsMu.Unlock()
+	m := w.metrics
+	m.InboxDepth = len(w.inbox)
+	return m
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withSimClock drives the world simulation clock (mob cooldowns, stuns)
+// from a fake clock.
+func withSimClock(fn func(clock *fakeClock)) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	orig := simTickClock
+	simTickClock = clock.Now
+	defer func() { simTickClock = orig }()
+	fn(clock)
+}
+
+func TestWorldSimLoopRunsActionsAndTicks(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	player, playerConn := newVisibilityTestSession("Looper", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(player)
+
+	mob := aiTestMob(Position{X: 110, Y: 0, Z: 100})
+	mob.HP, mob.MaxHP = 5000, 5000
+	withWorldMob(World1, "mob_wolf_01", mob, func() {
+		sim := worldSimFor(World1)
+		sim.start(10 * time.Millisecond)
+		defer sim.stop()
+
+		result, ok, reason := attackMob(player, "mob_wolf_01", "")
+		if !ok || result["mob_hp"].(int) >= 5000 {
+			t.Fatalf("expected the attack to run on the world loop, got ok=%v reason=%s result=%#v", ok, reason, result)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for sim.tickMetrics().Ticks < 3 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if sim.tickMetrics().Ticks < 3 {
+			t.Fatalf("expected the world loop to tick on its own")
+		}
+		if entities := listNearbyEntities(player); len(entities["mobs"].([]MobEntity)) != 1 {
+			t.Fatalf("expected LIST_ENTITIES to read through the world loop, got %#v", entities)
+		}
+		if !hasMessage(playerConn.DrainMessages(t), RespMobHit, nil) {
+			t.Fatalf("expected the ticking mob to hit the player it was attacked by")
+		}
+	})
+}
+
+func TestWorldSimStoppedRejectsActions(t *testing.T) {
+	resetSocialStateForTests()
+	worlds = DefaultWorlds()
+	player, _ := newVisibilityTestSession("Late", worlds[World1], Position{X: 100, Y: 0, Z: 100})
+	defer unregisterSession(player)
+
+	withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 105, Y: 0, Z: 100}), func() {
+		sim := worldSimFor(World1)
+		sim.start(time.Hour)
+		sim.stop()
+		if ran := sim.do(func(*worldSim, *mobOutbox) {}); ran {
+			t.Fatalf("expected a stopped world to refuse work")
+		}
+		if _, ok, reason := attackMob(player, "mob_wolf_01", ""); ok || reason != "MOB_NOT_FOUND" {
+			t.Fatalf("expected MOB_NOT_FOUND from a stopped world, got ok=%v reason=%s", ok, reason)
+		}
+	})
+}
+
+func TestWorldTickMetricsAndAdminReport(t *testing.T) {
+	withWorldMob(World1, "mob_wolf_01", aiTestMob(Position{X: 100, Y: 0, Z: 100}), func() {
+		sim := worldSimFor(World1)
+		sim.recordTick(simTickRate/2, 1)
+		sim.recordTick(simTickRate*2, 1)
+
+		m := sim.tickMetrics()
+		if m.Ticks != 2 || m.Overruns != 1 || m.MaxTickMS != durationMS(simTickRate*2) || m.LastTickMS != m.MaxTickMS {
+			t.Fatalf("unexpected metrics %#v", m)
+		}
+		if m.AvgTickMS != durationMS(simTickRate*5/4) {
+			t.Fatalf("expected the average over both ticks, got %v", m.AvgTickMS)
+		}
+
+		mux := http.NewServeMux()
+		registerAdminEndpoints(mux, "s3cret")
+		req := httptest.NewRequest(http.MethodGet, "/admin/worlds", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var body struct {
+			Worlds []WorldTickMetrics `json:"worlds"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		if len(body.Worlds) != 1 || body.Worlds[0].WorldID != World1 || body.Worlds[0].Overruns != 1 {
+			t.Fatalf("unexpected world metrics %#v", body.Worlds)
+		}
+	})
+}
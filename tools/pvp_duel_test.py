@@ -87,6 +87,15 @@ def recv_until(sock, expected_commands, timeout=5.0):
     raise TimeoutError(f"timed out waiting for {expected_commands}")
 
 
+def walk(conn, start, end, step=7.0):
+    x, z = start
+    while (x, z) != end:
+        x = min(x + step, end[0]) if x < end[0] else end[0]
+        z = min(z + step, end[1]) if z < end[1] else end[1]
+        conn.send({"command": "MOVE", "payload": {"x": x, "y": 0.0, "z": z}})
+        conn.recv_until("MOVE_OK")
+
+
 def main():
     run_id = int(time.time())
     name_a = f"DuelA_{run_id}"
@@ -200,6 +209,10 @@ def main():
         b.send({"command": "GUILD_DISBAND"})
         b.recv_until("GUILD_UPDATE")
 
+        # Town is a safe zone; step both duelists out into the wilds first.
+        walk(a, (7.0, 7.0), (42.0, 42.0))
+        walk(b, (6.0, 6.0), (41.0, 41.0))
+
         # Party up first: friendly fire should be blocked.
         a.send({"command": "PARTY_INVITE", "payload": {"target": name_b}})
         b.recv_until("PARTY_INVITE")
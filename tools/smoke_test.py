@@ -212,15 +212,27 @@ def find_visible_mob(payload, mob_id):
 
 
 def defeat_mob(conn, mob_id, skill_id, prefix):
-    for _ in range(18):
-        conn.send({"command": "ATTACK_MOB", "payload": {"mob_id": mob_id, "skill_id": skill_id}})
+    # A cast-time skill answers CASTING and lands later with its own result.
+    # While it cools down (or the pool runs dry) swing without it.
+    use_skill = skill_id
+    for _ in range(30):
+        payload = {"mob_id": mob_id}
+        if use_skill:
+            payload["skill_id"] = use_skill
+        conn.send({"command": "ATTACK_MOB", "payload": payload})
         result = conn.recv_until_any(["MOB_ATTACK_RESULT", "MOB_ATTACK_REJECTED"], timeout=6.0, prefix=prefix)
+        if result.get("command") == "MOB_ATTACK_RESULT" and result.get("payload", {}).get("status") == "CASTING":
+            result = conn.recv_until_any(["MOB_ATTACK_RESULT", "MOB_ATTACK_REJECTED"], timeout=6.0, prefix=prefix)
         if result.get("command") == "MOB_ATTACK_REJECTED":
             payload = result.get("payload")
-            if payload == "MOB_ALREADY_DEFEATED":
+            if payload in ("SKILL_ON_COOLDOWN", "INSUFFICIENT_MANA"):
+                use_skill = ""
+                continue
+            if payload in ("MOB_ALREADY_DEFEATED", "CAST_INTERRUPTED"):
                 time.sleep(0.35)
                 continue
             raise RuntimeError(f"unexpected mob attack rejection: {result!r}")
+        use_skill = skill_id
 
         payload = result.get("payload", {})
         if payload.get("status") == "PLAYER_DIED":